@@ -0,0 +1,83 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type alarmOption struct {
+	threshold int
+	fn        func(count int)
+}
+
+// Call fn with the number of active entries when it reaches threshold,
+// checked each time PruneEntries or PruneEntriesBatched runs, such as by auto
+// prune. The alarm is raised once and again only after the count drops below
+// the threshold.
+func WithAlarm(threshold int, fn func(count int)) Option {
+	return Option{
+		key: "alarm",
+		value: alarmOption{
+			threshold: threshold,
+			fn:        fn,
+		},
+	}
+}
+
+// entryAlarm remembers whether it was raised, so the callback only runs when
+// the count crosses the threshold
+type entryAlarm struct {
+	threshold int
+	fn        func(count int)
+	raised    atomic.Bool
+}
+
+func newEntryAlarm(opt alarmOption) *entryAlarm {
+	return &entryAlarm{
+		threshold: opt.threshold,
+		fn:        opt.fn,
+	}
+}
+
+// checkAlarm counts the active entries of the local table and raises the
+// alarm if they crossed the threshold
+func (k *Keybase) checkAlarm(ctx context.Context) error {
+	if k.alarm == nil {
+		return nil
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	count := invalidCount
+	err := k.read(ctx, OpAlarm, nil, func(ctx context.Context) (err error) {
+		count, err = newCountEntriesQuery(k.params(QueryParams{Active: true, Timestamp: timestamp})).queryCount(ctx, k.conn)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if count < k.alarm.threshold {
+		k.alarm.raised.Store(false)
+	} else if !k.alarm.raised.Swap(true) {
+		k.alarm.fn(count)
+	}
+	return nil
+}
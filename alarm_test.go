@@ -0,0 +1,120 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithAlarm(t *testing.T) {
+	ctx := context.Background()
+	_, err := Open(ctx, WithAlarm(0, func(int) {}))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	_, err = Open(ctx, WithAlarm(1, nil))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	clock := &fixedClock{now: time.Now()}
+	raised := []int{}
+	keybase, err := Open(ctx, WithClock(clock), WithTTL(time.Minute), WithAlarm(3, func(count int) {
+		raised = append(raised, count)
+	}))
+	assert.NoError(t, err)
+	for index := range 2 {
+		assert.NoError(t, keybase.Put(ctx, "namespace", fmt.Sprint("key", index)))
+	}
+	assert.NoError(t, keybase.PruneEntries(ctx))
+	assert.Empty(t, raised)
+
+	// the alarm is raised once while the count stays above the threshold
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key2"))
+	assert.NoError(t, keybase.PruneEntries(ctx))
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key3"))
+	assert.NoError(t, keybase.PruneEntries(ctx))
+	assert.Equal(t, []int{3}, raised)
+
+	// and again after the count drops below it
+	clock.now = clock.now.Add(time.Hour)
+	assert.NoError(t, keybase.PruneEntries(ctx))
+	for index := range 3 {
+		assert.NoError(t, keybase.Put(ctx, "namespace", fmt.Sprint("key", index)))
+	}
+	assert.NoError(t, keybase.PruneEntries(ctx))
+	assert.Equal(t, []int{3, 3}, raised)
+	assert.NoError(t, keybase.Close())
+}
+
+func TestAlarmInternalCount(t *testing.T) {
+	ctx := context.Background()
+	ops := []Op{}
+	raised := []int{}
+	keybase, err := Open(ctx,
+		WithAlarm(1, func(count int) {
+			raised = append(raised, count)
+		}),
+		WithAuthorizer(func(ctx context.Context, namespace string, op Op) error {
+			if op == OpCountEntries {
+				return ErrForbidden
+			}
+			return nil
+		}),
+		WithInterceptor(func(ctx context.Context, op OpInfo, next func(ctx context.Context) error) error {
+			ops = append(ops, op.Op)
+			return next(ctx)
+		}),
+	)
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	// the alarm counts without the interceptors or the authorizer
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+	assert.NoError(t, keybase.PruneEntries(ctx))
+	assert.Equal(t, []int{1}, raised)
+	assert.Equal(t, []Op{OpPut, OpPruneEntries}, ops)
+}
+
+func TestAlarmPruneNamespace(t *testing.T) {
+	ctx := context.Background()
+	clock := &fixedClock{now: time.Now()}
+	raised := []int{}
+	keybase, err := Open(ctx, WithClock(clock), WithTTL(time.Minute), WithAlarm(2, func(count int) {
+		raised = append(raised, count)
+	}))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key1"))
+	assert.NoError(t, keybase.PruneNamespace(ctx, "namespace"))
+	assert.Equal(t, []int{2}, raised)
+
+	// pruning the namespace below the threshold clears the alarm
+	clock.now = clock.now.Add(time.Hour)
+	assert.NoError(t, keybase.PruneNamespace(ctx, "namespace"))
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key1"))
+	assert.NoError(t, keybase.PruneNamespace(ctx, "namespace"))
+	assert.Equal(t, []int{2, 2}, raised)
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Move expired entries to an archive when pruning instead of deleting them
+func WithArchiveExpired() Option {
+	return Option{
+		key: "archive",
+	}
+}
+
+// GetArchivedKeys collects the keys of a namespace that were archived when
+// pruned
+func (k *Keybase) GetArchivedKeys(ctx context.Context, namespace string) ([]string, error) {
+	var keys []string
+	err := k.read(ctx, OpGetArchivedKeys, []string{namespace}, func(ctx context.Context) (err error) {
+		keys, err = newGetArchivedKeysQuery(k.params(QueryParams{Namespace: namespace})).queryValues(ctx, k.conn)
+		if err != nil {
+			return err
+		}
+		keys, err = k.decodeAll(keys)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.GetArchivedKeys: failed to query database: %w", err)
+	}
+	return keys, nil
+}
+
+// PurgeArchive removes archived entries that were pruned longer ago than
+// olderThan, returning the number of entries removed
+func (k *Keybase) PurgeArchive(ctx context.Context, olderThan time.Duration) (int, error) {
+	timestamp := k.clock.Now().UnixMilli()
+	purged := 0
+	err := k.write(ctx, OpPurgeArchive, nil, func(ctx context.Context) error {
+		rows, err := newPurgeArchiveQuery(QueryParams{Timestamp: timestamp, Threshold: olderThan.Milliseconds()}).queryRowsAffected(ctx, k.conn)
+		purged = int(rows)
+		if err != nil {
+			return err
+		}
+		return newPruneOverflowQuery(QueryParams{}).queryExec(withOperation(ctx, OpPruneOverflow), k.conn)
+	})
+	k.record(ctx, JournalEntry{Op: OpPurgeArchive, Interval: olderThan}, err)
+	if err != nil {
+		return 0, fmt.Errorf("keybase.PurgeArchive: failed to purge archive: %w", err)
+	}
+	return purged, nil
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveExpired(t *testing.T) {
+	ctx := context.Background()
+	long := strings.Repeat("k", 64)
+	keybase, err := Open(ctx, WithTTL(time.Millisecond*20), WithArchiveExpired(), WithOverflow(32), WithEncryption(make([]byte, 32)))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "namespace", long))
+	assert.NoError(t, keybase.Put(ctx, "other", "key1"))
+	time.Sleep(time.Millisecond * 20)
+	assert.NoError(t, keybase.PruneNamespace(ctx, "namespace"))
+
+	count, err := keybase.CountEntries(ctx, false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	keys, err := keybase.GetArchivedKeys(ctx, "namespace")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"key0", long}, keys)
+	keys, err = keybase.GetArchivedKeys(ctx, "other")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	purged, err := keybase.PurgeArchive(ctx, time.Hour)
+	assert.NoError(t, err)
+	assert.Zero(t, purged)
+	purged, err = keybase.PurgeArchive(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, purged)
+	keys, err = keybase.GetArchivedKeys(ctx, "namespace")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	keybase, err = Open(ctx, WithTTL(time.Millisecond))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+	time.Sleep(time.Millisecond * 2)
+	assert.NoError(t, keybase.PruneEntries(ctx))
+	keys, err = keybase.GetArchivedKeys(ctx, "namespace")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	assert.NoError(t, keybase.Close())
+	_, err = keybase.GetArchivedKeys(ctx, "namespace")
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = keybase.PurgeArchive(ctx, 0)
+	assert.ErrorIs(t, err, ErrClosed)
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type actorKey struct{}
+
+// AuditRecord mutation recorded by WithAuditLog
+type AuditRecord struct {
+	ID        int64
+	Time      time.Time
+	Actor     string
+	Op        Op
+	Namespace string
+	Key       string
+	Field     string
+	Target    string
+}
+
+// AuditFilter selects audit records, where zero fields match every record
+type AuditFilter struct {
+	Actor     string
+	Op        Op
+	Namespace string
+	Key       string
+	Since     time.Time
+	Until     time.Time
+	// Limit maximum number of records returned, oldest first
+	Limit int
+}
+
+// Record every successful mutation in an audit table, along with the actor
+// set on its context with WithActor. Records are written once the mutation
+// has succeeded, so a failure to record does not fail the mutation.
+func WithAuditLog() Option {
+	return Option{
+		key: "audit",
+	}
+}
+
+// WithActor derives a context whose mutations are attributed to the actor in
+// the audit log
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// record journals a mutation and, if it succeeded, adds it to the audit log
+func (k *Keybase) record(ctx context.Context, entry JournalEntry, err error) {
+	k.journal.record(entry, err)
+	if err != nil || !k.audit {
+		return
+	}
+	// the mutation already happened, so it is audited even if the caller
+	// gives up on the context
+	ctx = context.WithoutCancel(ctx)
+	_ = k.write(ctx, OpAudit, nil, func(ctx context.Context) error {
+		return newAuditQuery(AuditRecord{
+			Time:      k.clock.Now(),
+			Actor:     actor(ctx),
+			Op:        entry.Op,
+			Namespace: entry.Namespace,
+			Key:       k.encode(entry.Key),
+			Field:     entry.Field,
+			Target:    entry.Target,
+		}).queryExec(ctx, k.conn)
+	})
+}
+
+// QueryAudit collects the audit records matching the filter, oldest first
+func (k *Keybase) QueryAudit(ctx context.Context, filter AuditFilter) ([]AuditRecord, error) {
+	if !k.audit {
+		return nil, fmt.Errorf("keybase.QueryAudit: %w: audit log is not enabled", ErrUnsupportedOption)
+	}
+	filter.Key = k.encode(filter.Key)
+	records := []AuditRecord{}
+	err := k.read(ctx, OpQueryAudit, nil, func(ctx context.Context) error {
+		return newQueryAuditQuery(filter).queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			record, timestamp := AuditRecord{}, int64(0)
+			err := rows.Scan(&record.ID, &timestamp, &record.Actor, &record.Op, &record.Namespace, &record.Key, &record.Field, &record.Target)
+			if err != nil {
+				return err
+			}
+			record.Time = time.UnixMilli(timestamp)
+			record.Key, err = k.decode(record.Key)
+			records = append(records, record)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.QueryAudit: failed to query database: %w", err)
+	}
+	return records, nil
+}
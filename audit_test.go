@@ -0,0 +1,99 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLog(t *testing.T) {
+	ctx := context.Background()
+	keybase, err := Open(ctx, WithAuditLog(), WithEncryption(make([]byte, 32)))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	start := time.Now().Add(-time.Millisecond)
+	alice := WithActor(ctx, "alice")
+	assert.NoError(t, keybase.Put(alice, "namespace", "key0"))
+	assert.NoError(t, keybase.Tx(WithActor(ctx, "bob"), func(tx *KeybaseTx) error {
+		return tx.Delete(ctx, "namespace", "key0")
+	}))
+	assert.NoError(t, keybase.PruneNamespace(alice, "namespace"))
+	assert.NoError(t, keybase.ClearEntries(ctx))
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Error(t, keybase.Put(cancelled, "namespace", "key1"))
+
+	records, err := keybase.QueryAudit(ctx, AuditFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, records, 4)
+	assert.Equal(t, AuditRecord{ID: records[0].ID, Time: records[0].Time, Actor: "alice", Op: OpPut, Namespace: "namespace", Key: "key0"}, records[0])
+	assert.True(t, records[0].Time.After(start))
+	assert.Equal(t, OpDeleteKey, records[1].Op)
+	assert.Equal(t, "bob", records[1].Actor)
+	assert.Equal(t, OpPruneNamespace, records[2].Op)
+	assert.Equal(t, OpClearEntries, records[3].Op)
+	assert.Empty(t, records[3].Actor)
+
+	records, err = keybase.QueryAudit(ctx, AuditFilter{Actor: "alice", Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, OpPut, records[0].Op)
+	records, err = keybase.QueryAudit(ctx, AuditFilter{Key: "key0", Op: OpDeleteKey, Namespace: "namespace"})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	records, err = keybase.QueryAudit(ctx, AuditFilter{Since: time.Now().Add(time.Minute)})
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+	records, err = keybase.QueryAudit(ctx, AuditFilter{Until: start})
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	keybase, err = Open(ctx)
+	assert.NoError(t, err)
+	defer keybase.Close()
+	_, err = keybase.QueryAudit(ctx, AuditFilter{})
+	assert.ErrorIs(t, err, ErrUnsupportedOption)
+}
+
+func TestAuditLogWriteBatching(t *testing.T) {
+	ctx := context.Background()
+	keybase, err := Open(ctx, WithAuditLog(), WithWriteBatching(time.Hour, 2))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	// grouped puts are recorded with the actor of their caller
+	done := keybase.PutAsync(WithActor(ctx, "alice"), "namespace", "key0")
+	assert.NoError(t, keybase.Put(WithActor(ctx, "bob"), "namespace", "key1"))
+	assert.NoError(t, <-done)
+	records, err := keybase.QueryAudit(ctx, AuditFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	actors := map[string]string{}
+	for _, record := range records {
+		actors[record.Key] = record.Actor
+	}
+	assert.Equal(t, map[string]string{"key0": "alice", "key1": "bob"}, actors)
+}
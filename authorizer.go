@@ -0,0 +1,64 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Authorizer decides whether the caller, usually identified through the
+// context, may run an operation on a namespace
+type Authorizer func(ctx context.Context, namespace string, op Op) error
+
+// Consult an authorizer before every operation, once for each namespace it
+// touches. Operations that are not limited to any namespace, such as
+// CountEntries, are authorized with an empty namespace. Errors returned by the
+// authorizer fail the operation with ErrForbidden.
+func WithAuthorizer(authorizer Authorizer) Option {
+	return Option{
+		key:   "authorizer",
+		value: authorizer,
+	}
+}
+
+// authorize consults the authorizer for each namespace of the operation
+func (k *Keybase) authorize(ctx context.Context, op Op, namespaces []string) error {
+	authorizer := k.config.authorizer
+	if authorizer == nil || op == OpTx {
+		// the operations of a transaction are authorized as they run
+		return nil
+	}
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+	for _, namespace := range namespaces {
+		err := authorizer(ctx, namespace, op)
+		if err != nil && !errors.Is(err, ErrForbidden) {
+			err = fmt.Errorf("%w: %w", ErrForbidden, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tenantKey struct{}
+
+func tenantAuthorizer(ctx context.Context, namespace string, op Op) error {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	if tenant == "" || !strings.HasPrefix(namespace, tenant+":") {
+		return errors.New("namespace belongs to another tenant")
+	}
+	return nil
+}
+
+func TestWithAuthorizer(t *testing.T) {
+	ctx := context.Background()
+	alice := context.WithValue(ctx, tenantKey{}, "alice")
+	bob := context.WithValue(ctx, tenantKey{}, "bob")
+	for _, opts := range [][]Option{
+		{WithAuthorizer(tenantAuthorizer)},
+		{WithAuthorizer(tenantAuthorizer), WithStorage(filepath.Join(t.TempDir(), "keybase.db")), WithWriteBatching(time.Hour, 1)},
+	} {
+		keybase, err := Open(ctx, opts...)
+		assert.NoError(t, err)
+
+		assert.NoError(t, keybase.Put(alice, "alice:sessions", "key"))
+		err = keybase.Put(bob, "alice:sessions", "key")
+		assert.ErrorIs(t, err, ErrForbidden)
+		assert.ErrorContains(t, err, "another tenant")
+		assert.ErrorIs(t, keybase.Put(ctx, "alice:sessions", "key"), ErrForbidden)
+		if keybase.group != nil {
+			assert.ErrorIs(t, <-keybase.PutAsync(bob, "alice:sessions", "key"), ErrForbidden)
+		}
+
+		count, err := keybase.CountKey(alice, "alice:sessions", "key", true)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		_, err = keybase.CountKey(bob, "alice:sessions", "key", true)
+		assert.ErrorIs(t, err, ErrForbidden)
+
+		// every namespace of the operation is authorized
+		_, err = keybase.CopyNamespace(alice, "alice:sessions", "alice:copy", false)
+		assert.NoError(t, err)
+		_, err = keybase.CopyNamespace(alice, "alice:sessions", "bob:copy", false)
+		assert.ErrorIs(t, err, ErrForbidden)
+		_, err = keybase.MatchKeyAcross(alice, []string{"alice:sessions", "bob:sessions"}, "*", true, false)
+		assert.ErrorIs(t, err, ErrForbidden)
+
+		// operations within a transaction are authorized as they run
+		err = keybase.Tx(alice, func(tx *KeybaseTx) error {
+			_, err := tx.Match(alice, "alice:sessions", "*", true, true)
+			if err != nil {
+				return err
+			}
+			return tx.Put(alice, "alice:sessions", "tx")
+		})
+		assert.NoError(t, err)
+		for _, fn := range []func(tx *KeybaseTx) error{
+			func(tx *KeybaseTx) error { return tx.Put(alice, "bob:sessions", "key") },
+			func(tx *KeybaseTx) error { return tx.Delete(alice, "bob:sessions", "key") },
+			func(tx *KeybaseTx) error {
+				_, err := tx.Match(alice, "bob:sessions", "*", true, true)
+				return err
+			},
+		} {
+			assert.ErrorIs(t, keybase.Tx(alice, fn), ErrForbidden)
+		}
+
+		// operations over every namespace are authorized with an empty one
+		_, err = keybase.CountEntries(alice, true, false)
+		assert.ErrorIs(t, err, ErrForbidden)
+		assert.NoError(t, keybase.Close())
+	}
+}
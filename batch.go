@@ -0,0 +1,137 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+type batchKey struct{}
+
+type batchedPut struct {
+	// ctx of the caller, if the put outlives it, so it is recorded with the
+	// caller's actor
+	ctx       context.Context
+	namespace string
+	key       string
+	now       time.Time
+	until     time.Time
+}
+
+// writeBatch puts buffered for a context derived with WithBatch
+type writeBatch struct {
+	mu      *sync.Mutex
+	keybase *Keybase
+	puts    []batchedPut
+}
+
+// WithBatch derives a context whose Puts on this keybase are buffered instead
+// of written, until flush commits them together in a single transaction. If
+// any Put fails, none of them are written. Puts made after a flush are
+// buffered again for the next one.
+func (k *Keybase) WithBatch(ctx context.Context) (context.Context, func() error) {
+	b := &writeBatch{
+		mu:      new(sync.Mutex),
+		keybase: k,
+	}
+	return context.WithValue(ctx, batchKey{}, b), func() error {
+		return b.flush(ctx)
+	}
+}
+
+// batch finds the batch of this keybase in the context, if any
+func (k *Keybase) batch(ctx context.Context) *writeBatch {
+	b, ok := ctx.Value(batchKey{}).(*writeBatch)
+	if !ok || b.keybase != k {
+		return nil
+	}
+	return b
+}
+
+func (b *writeBatch) add(namespace, key string, now, until time.Time) error {
+	if b.keybase.closed.Load() {
+		return ErrClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.puts = append(b.puts, batchedPut{namespace: namespace, key: key, now: now, until: until})
+	return nil
+}
+
+func (b *writeBatch) flush(ctx context.Context) error {
+	b.mu.Lock()
+	puts := b.puts
+	b.puts = nil
+	b.mu.Unlock()
+	if len(puts) == 0 {
+		return nil
+	}
+	err := b.keybase.commitPuts(ctx, OpFlushBatch, puts)
+	if err != nil {
+		return fmt.Errorf("keybase.WithBatch: failed to commit batch: %w", err)
+	}
+	return nil
+}
+
+// commitPuts writes the puts in a single transaction, so that none of them are
+// written if any fails
+func (k *Keybase) commitPuts(ctx context.Context, op Op, puts []batchedPut) error {
+	namespaces := []string{}
+	for _, put := range puts {
+		if !slices.Contains(namespaces, put.namespace) {
+			namespaces = append(namespaces, put.namespace)
+		}
+	}
+	err := k.write(ctx, op, namespaces, func(ctx context.Context) error {
+		return k.transaction(ctx, func(db querier) error {
+			for _, put := range puts {
+				expiration := k.expiration(put.now)
+				if !put.until.IsZero() {
+					expiration = put.until.UnixMilli()
+				}
+				params := k.params(QueryParams{Namespace: put.namespace, Key: put.key, Expiration: expiration, Timestamp: put.now.UnixMilli()})
+				err := k.insertWith(withOperation(ctx, OpPut), db, put.key, func(db querier) error {
+					err := newPutQuery(params).queryExec(withOperation(ctx, OpPut), db)
+					if err != nil {
+						return err
+					}
+					return k.versioned(ctx, db, params)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	for _, put := range puts {
+		recordCtx := ctx
+		if put.ctx != nil {
+			recordCtx = put.ctx
+		}
+		k.record(recordCtx, putEntry(put.namespace, put.key, put.now, put.until), err)
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithBatch(t *testing.T) {
+	keybase, err := Open(context.Background(), WithMaxEntries(3, RejectWrites))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	count := func() int {
+		count, err := keybase.CountEntries(context.Background(), true, false)
+		assert.NoError(t, err)
+		return count
+	}
+
+	ctx, flush := keybase.WithBatch(context.Background())
+	assert.NoError(t, flush())
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key1"))
+	assert.Zero(t, count())
+	assert.NoError(t, flush())
+	assert.Equal(t, 2, count())
+
+	other, err := Open(context.Background())
+	assert.NoError(t, err)
+	defer other.Close()
+	assert.NoError(t, other.Put(ctx, "namespace", "key0"))
+	otherCount, err := other.CountEntries(context.Background(), true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, otherCount)
+
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key2"))
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key3"))
+	assert.ErrorIs(t, flush(), ErrQuotaExceeded)
+	assert.Equal(t, 2, count())
+
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key2"))
+	assert.NoError(t, keybase.Close())
+	assert.ErrorIs(t, flush(), ErrClosed)
+	assert.ErrorIs(t, keybase.Put(ctx, "namespace", "key3"), ErrClosed)
+}
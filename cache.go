@@ -0,0 +1,174 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+)
+
+// CacheStats hit and miss counts for the read cache enabled by WithCache
+type CacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Size      int
+}
+
+type cacheKey struct {
+	op        Op
+	namespace string
+	key       string
+	active    bool
+	unique    bool
+	order     Order
+	exclude   string
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	value   any
+	expires int64
+}
+
+// readCache least recently used cache of query results. Every write bumps
+// the generation, so results computed before a write are never stored.
+type readCache struct {
+	mu         *sync.Mutex
+	size       int
+	generation uint64
+	entries    map[cacheKey]*list.Element
+	order      *list.List
+	stats      CacheStats
+}
+
+// Cache up to size CountKey and GetKeys results in memory, invalidated by
+// every write
+func WithCache(size int) Option {
+	return Option{
+		key:   "cache",
+		value: size,
+	}
+}
+
+func newReadCache(size int) *readCache {
+	return &readCache{
+		mu:      new(sync.Mutex),
+		size:    size,
+		entries: map[cacheKey]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (c *readCache) get(key cacheKey, timestamp int64) (any, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if ok && timestamp < element.Value.(*cacheEntry).expires {
+		c.stats.Hits++
+		c.order.MoveToFront(element)
+		return element.Value.(*cacheEntry).value, c.generation, true
+	}
+	if ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+	c.stats.Misses++
+	return nil, c.generation, false
+}
+
+func (c *readCache) put(generation uint64, key cacheKey, value any, expires int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+	if element, ok := c.entries[key]; ok {
+		element.Value = &cacheEntry{key: key, value: value, expires: expires}
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.stats.Evictions++
+	}
+}
+
+func (c *readCache) invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.entries = map[cacheKey]*list.Element{}
+	c.order.Init()
+}
+
+func (c *readCache) snapshot() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
+
+// cached serves a read from the cache, running query on a miss. Inactive
+// results stay valid until the next write, while active results also expire
+// when the earliest active entry they counted does.
+func (k *Keybase) cached(ctx context.Context, key cacheKey, timestamp int64, query func() (any, error)) (any, error) {
+	if k.cache == nil {
+		return query()
+	}
+	value, generation, ok := k.cache.get(key, timestamp)
+	if ok {
+		return value, nil
+	}
+	value, err := query()
+	if err != nil {
+		return nil, err
+	}
+	expires := int64(math.MaxInt64)
+	if key.active {
+		next, err := newNextExpirationQuery(k.params(QueryParams{Namespace: key.namespace, Key: key.key, Timestamp: timestamp})).queryNullInt(withOperation(ctx, OpNextExpiration), k.conn)
+		if err != nil {
+			return value, nil
+		}
+		if next.Valid {
+			expires = next.Int64
+		}
+	}
+	k.cache.put(generation, key, value, expires)
+	return value, nil
+}
+
+// CacheStats reports the hits and misses of the read cache
+func (k *Keybase) CacheStats() CacheStats {
+	return k.cache.snapshot()
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadCache(t *testing.T) {
+	cache := newReadCache(2)
+	key0 := cacheKey{op: OpCountKey, key: "key0"}
+	key1 := cacheKey{op: OpCountKey, key: "key1"}
+	key2 := cacheKey{op: OpCountKey, key: "key2"}
+
+	_, generation, ok := cache.get(key0, 0)
+	assert.False(t, ok)
+	cache.put(generation, key0, 0, math.MaxInt64)
+	cache.put(generation, key1, 1, 10)
+	value, _, ok := cache.get(key0, 0)
+	assert.True(t, ok)
+	assert.Equal(t, 0, value)
+
+	_, _, ok = cache.get(key1, 10)
+	assert.False(t, ok)
+
+	cache.put(generation, key1, 1, math.MaxInt64)
+	cache.put(generation, key2, 2, math.MaxInt64)
+	_, _, ok = cache.get(key0, 0)
+	assert.False(t, ok)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 3, Evictions: 1, Size: 2}, cache.snapshot())
+
+	cache.invalidate()
+	cache.put(generation, key0, 0, math.MaxInt64)
+	assert.Zero(t, cache.snapshot().Size)
+
+	var disabled *readCache
+	disabled.invalidate()
+	assert.Equal(t, CacheStats{}, disabled.snapshot())
+}
+
+func TestCache(t *testing.T) {
+	keybase, err := Open(context.Background(), WithCache(16), WithTTL(time.Millisecond*50))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "key"))
+	for i := 0; i < 2; i++ {
+		count, err := keybase.CountKey(context.Background(), "namespace", "key", true)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		keys, err := keybase.GetKeys(context.Background(), "namespace", true, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key"}, keys)
+	}
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 2, Size: 2}, keybase.CacheStats())
+
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "key"))
+	count, err := keybase.CountKey(context.Background(), "namespace", "key", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	time.Sleep(time.Millisecond * 60)
+	count, err = keybase.CountKey(context.Background(), "namespace", "key", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	count, err = keybase.CountKey(context.Background(), "namespace", "key", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, int64(2), keybase.CacheStats().Hits)
+
+	uncached, err := Open(context.Background())
+	assert.NoError(t, err)
+	defer uncached.Close()
+	_, err = uncached.CountKey(context.Background(), "namespace", "key", true)
+	assert.NoError(t, err)
+	assert.Equal(t, CacheStats{}, uncached.CacheStats())
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// changeWatcher polls the data version of a dedicated connection, which
+// changes whenever another connection, in this or another process, commits
+type changeWatcher struct {
+	mu        *sync.Mutex
+	feature   *Feature
+	conn      *sql.Conn
+	version   int
+	callbacks []func()
+}
+
+// Poll the database for changes committed by other processes sharing the
+// storage, notifying the callbacks registered with OnChange. Polling requires
+// persistent storage.
+func WithChangePolling(interval time.Duration) Option {
+	return Option{
+		key:   "changepolling",
+		value: interval,
+	}
+}
+
+func newChangeWatcher(interval time.Duration, poll func(ctx context.Context) error) *changeWatcher {
+	return &changeWatcher{
+		mu:      new(sync.Mutex),
+		feature: newFeature(interval, poll),
+	}
+}
+
+// OnChange registers a callback that is notified when change polling finds
+// that the database was changed since the previous poll, including by other
+// processes
+func (k *Keybase) OnChange(fn func()) {
+	k.changes.mu.Lock()
+	defer k.changes.mu.Unlock()
+	k.changes.callbacks = append(k.changes.callbacks, fn)
+}
+
+// ChangePolling handle for the change polling feature, which can be started
+// even if it was not enabled with WithChangePolling
+func (k *Keybase) ChangePolling() *Feature {
+	return k.changes.feature
+}
+
+func (k *Keybase) pollChanges(ctx context.Context) error {
+	if isMemory(k.config.storage) {
+		return fmt.Errorf("keybase.ChangePolling: %w: change polling requires persistent storage", ErrUnsupportedOption)
+	}
+	w := k.changes
+	var callbacks []func()
+	err := k.read(ctx, OpPollChanges, nil, func(ctx context.Context) error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		first := w.conn == nil
+		if first {
+			conn, err := k.db.Conn(ctx)
+			if err != nil {
+				return err
+			}
+			w.conn = conn
+		}
+		version, err := newDataVersionQuery().queryCount(withOperation(ctx, OpDataVersion), &instrumentedDB{querier: w.conn, stats: k.stats, table: k.conn.table})
+		if err != nil {
+			return err
+		}
+		if !first && version != w.version {
+			callbacks = slices.Clone(w.callbacks)
+		}
+		w.version = version
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("keybase.ChangePolling: failed to query data version: %w", err)
+	}
+	for _, fn := range callbacks {
+		fn()
+	}
+	return nil
+}
+
+// close returns the dedicated connection to the pool
+func (w *changeWatcher) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+}
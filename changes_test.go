@@ -0,0 +1,64 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangePolling(t *testing.T) {
+	ctx := context.Background()
+	storage := filepath.Join(t.TempDir(), "keybase.db")
+	watcher, err := Open(ctx, WithStorage(storage), WithChangePolling(time.Millisecond))
+	assert.NoError(t, err)
+	defer watcher.Close()
+	changes := atomic.Int64{}
+	watcher.OnChange(func() {
+		changes.Add(1)
+	})
+	assert.Eventually(t, func() bool {
+		return watcher.ChangePolling().Status().Runs > 0
+	}, time.Second, time.Millisecond)
+
+	// a second keybase stands in for another process sharing the file
+	other, err := Open(ctx, WithStorage(storage))
+	assert.NoError(t, err)
+	defer other.Close()
+	assert.NoError(t, other.Put(ctx, "namespace", "key"))
+	assert.Eventually(t, func() bool {
+		return changes.Load() == 1
+	}, time.Second, time.Millisecond)
+	runs := watcher.ChangePolling().Status().Runs
+	assert.Eventually(t, func() bool {
+		return watcher.ChangePolling().Status().Runs > runs+2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int64(1), changes.Load())
+	assert.NoError(t, watcher.ChangePolling().Status().LastError)
+
+	_, err = Open(ctx, WithChangePolling(time.Second))
+	assert.ErrorIs(t, err, ErrUnsupportedOption)
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package chaoskit wraps a keybase to inject delays, transient errors and busy
+// conditions, chosen deterministically from a seed, so that retry and
+// degradation handling can be tested reproducibly.
+package chaoskit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/maxtek6/keybase-go"
+)
+
+var (
+	// ErrTransient injected error that a retry is expected to recover from
+	ErrTransient = errors.New("chaoskit: injected transient error")
+	// ErrBusy injected error mimicking SQLite giving up on a locked database
+	ErrBusy = errors.New("chaoskit: injected busy database")
+)
+
+// Config probabilities, between 0 and 1, of each fault being injected into a
+// call. Calls made in the same order with the same seed see the same faults.
+type Config struct {
+	Seed      int64
+	DelayRate float64
+	// MaxDelay upper bound of an injected delay
+	MaxDelay  time.Duration
+	ErrorRate float64
+	BusyRate  float64
+	// BusyWait time a busy call blocks before failing, as it would while
+	// SQLite waits out its busy timeout
+	BusyWait time.Duration
+}
+
+// Stats number of calls made through the wrapper and the faults injected
+type Stats struct {
+	Calls  int64
+	Delays int64
+	Errors int64
+	Busy   int64
+}
+
+// Keybase wraps a keybase, injecting faults into its core operations. Other
+// methods are passed through unchanged.
+type Keybase struct {
+	*keybase.Keybase
+	mu     *sync.Mutex
+	config Config
+	random *rand.Rand
+	stats  Stats
+}
+
+type fault struct {
+	delay time.Duration
+	err   error
+}
+
+// Wrap wraps a keybase with the fault injection described by config
+func Wrap(kb *keybase.Keybase, config Config) *Keybase {
+	return &Keybase{
+		Keybase: kb,
+		mu:      new(sync.Mutex),
+		config:  config,
+		random:  rand.New(rand.NewSource(config.Seed)),
+	}
+}
+
+// Stats reports the calls and faults injected so far
+func (c *Keybase) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// next draws the fault for the next call. Every roll is drawn on every call,
+// so the sequence only depends on the seed and the number of calls.
+func (c *Keybase) next() fault {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delayRoll, delay := c.random.Float64(), c.random.Float64()
+	busyRoll, errorRoll := c.random.Float64(), c.random.Float64()
+	c.stats.Calls++
+	f := fault{}
+	if delayRoll < c.config.DelayRate {
+		c.stats.Delays++
+		f.delay = time.Duration(delay * float64(c.config.MaxDelay))
+	}
+	switch {
+	case busyRoll < c.config.BusyRate:
+		c.stats.Busy++
+		f.delay += c.config.BusyWait
+		f.err = ErrBusy
+	case errorRoll < c.config.ErrorRate:
+		c.stats.Errors++
+		f.err = ErrTransient
+	}
+	return f
+}
+
+func (c *Keybase) inject(ctx context.Context, op string) error {
+	f := c.next()
+	if f.delay > 0 {
+		timer := time.NewTimer(f.delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("chaoskit.%s: %w", op, ctx.Err())
+		case <-timer.C:
+		}
+	}
+	if f.err != nil {
+		return fmt.Errorf("chaoskit.%s: %w", op, f.err)
+	}
+	return nil
+}
+
+// Put inserts new value, unless a fault is injected
+func (c *Keybase) Put(ctx context.Context, namespace, key string, opts ...keybase.PutOption) error {
+	if err := c.inject(ctx, "Put"); err != nil {
+		return err
+	}
+	return c.Keybase.Put(ctx, namespace, key, opts...)
+}
+
+// PutIfAbsent inserts new value only if the key has no active entries, unless
+// a fault is injected
+func (c *Keybase) PutIfAbsent(ctx context.Context, namespace, key string) (bool, error) {
+	if err := c.inject(ctx, "PutIfAbsent"); err != nil {
+		return false, err
+	}
+	return c.Keybase.PutIfAbsent(ctx, namespace, key)
+}
+
+// Seen reports whether the key has active entries, inserting one if not,
+// unless a fault is injected
+func (c *Keybase) Seen(ctx context.Context, namespace, key string) (bool, error) {
+	if err := c.inject(ctx, "Seen"); err != nil {
+		return false, err
+	}
+	return c.Keybase.Seen(ctx, namespace, key)
+}
+
+// MatchKey searches the namespace for keys matching the pattern, unless a
+// fault is injected
+func (c *Keybase) MatchKey(ctx context.Context, namespace, pattern string, active, unique bool, opts ...keybase.QueryOption) ([]string, error) {
+	if err := c.inject(ctx, "MatchKey"); err != nil {
+		return nil, err
+	}
+	return c.Keybase.MatchKey(ctx, namespace, pattern, active, unique, opts...)
+}
+
+// MatchKeyAcross searches the namespaces for keys matching the pattern, unless
+// a fault is injected
+func (c *Keybase) MatchKeyAcross(ctx context.Context, namespaces []string, pattern string, active, unique bool) ([]keybase.NamespacedKey, error) {
+	if err := c.inject(ctx, "MatchKeyAcross"); err != nil {
+		return nil, err
+	}
+	return c.Keybase.MatchKeyAcross(ctx, namespaces, pattern, active, unique)
+}
+
+// CountMatch counts the keys matching the pattern, unless a fault is injected
+func (c *Keybase) CountMatch(ctx context.Context, namespace, pattern string, active, unique bool) (int, error) {
+	if err := c.inject(ctx, "CountMatch"); err != nil {
+		return 0, err
+	}
+	return c.Keybase.CountMatch(ctx, namespace, pattern, active, unique)
+}
+
+// CountKey counts the entries of a key, unless a fault is injected
+func (c *Keybase) CountKey(ctx context.Context, namespace, key string, active bool) (int, error) {
+	if err := c.inject(ctx, "CountKey"); err != nil {
+		return 0, err
+	}
+	return c.Keybase.CountKey(ctx, namespace, key, active)
+}
+
+// GetKeys collects the keys of a namespace, unless a fault is injected
+func (c *Keybase) GetKeys(ctx context.Context, namespace string, active, unique bool, opts ...keybase.QueryOption) ([]string, error) {
+	if err := c.inject(ctx, "GetKeys"); err != nil {
+		return nil, err
+	}
+	return c.Keybase.GetKeys(ctx, namespace, active, unique, opts...)
+}
+
+// CountKeys counts the keys of a namespace, unless a fault is injected
+func (c *Keybase) CountKeys(ctx context.Context, namespace string, active, unique bool) (int, error) {
+	if err := c.inject(ctx, "CountKeys"); err != nil {
+		return 0, err
+	}
+	return c.Keybase.CountKeys(ctx, namespace, active, unique)
+}
+
+// GetNamespaces collects the namespaces, unless a fault is injected
+func (c *Keybase) GetNamespaces(ctx context.Context, active bool) ([]string, error) {
+	if err := c.inject(ctx, "GetNamespaces"); err != nil {
+		return nil, err
+	}
+	return c.Keybase.GetNamespaces(ctx, active)
+}
+
+// CountEntries counts all entries, unless a fault is injected
+func (c *Keybase) CountEntries(ctx context.Context, active, unique bool) (int, error) {
+	if err := c.inject(ctx, "CountEntries"); err != nil {
+		return 0, err
+	}
+	return c.Keybase.CountEntries(ctx, active, unique)
+}
+
+// PruneEntries removes stale entries, unless a fault is injected
+func (c *Keybase) PruneEntries(ctx context.Context) error {
+	if err := c.inject(ctx, "PruneEntries"); err != nil {
+		return err
+	}
+	return c.Keybase.PruneEntries(ctx)
+}
+
+// Increment adds delta to a counter, unless a fault is injected
+func (c *Keybase) Increment(ctx context.Context, namespace, key string, delta int64) (int64, error) {
+	if err := c.inject(ctx, "Increment"); err != nil {
+		return 0, err
+	}
+	return c.Keybase.Increment(ctx, namespace, key, delta)
+}
+
+// GetCounter gets the value of a counter, unless a fault is injected
+func (c *Keybase) GetCounter(ctx context.Context, namespace, key string) (int64, error) {
+	if err := c.inject(ctx, "GetCounter"); err != nil {
+		return 0, err
+	}
+	return c.Keybase.GetCounter(ctx, namespace, key)
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package chaoskit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maxtek6/keybase-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func open(t *testing.T) *keybase.Keybase {
+	kb, err := keybase.Open(context.Background())
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = kb.Close() })
+	return kb
+}
+
+func outcomes(c *Keybase, calls int) []error {
+	errs := make([]error, calls)
+	for index := range errs {
+		errs[index] = c.Put(context.Background(), "namespace", fmt.Sprint(index))
+	}
+	return errs
+}
+
+func TestDeterministic(t *testing.T) {
+	config := Config{Seed: 42, ErrorRate: 0.3, BusyRate: 0.1}
+	first := outcomes(Wrap(open(t), config), 50)
+	second := outcomes(Wrap(open(t), config), 50)
+	for index := range first {
+		assert.Equal(t, errors.Is(first[index], ErrTransient), errors.Is(second[index], ErrTransient))
+		assert.Equal(t, errors.Is(first[index], ErrBusy), errors.Is(second[index], ErrBusy))
+	}
+
+	c := Wrap(open(t), config)
+	outcomes(c, 50)
+	stats := c.Stats()
+	assert.Equal(t, int64(50), stats.Calls)
+	assert.NotZero(t, stats.Errors)
+	assert.NotZero(t, stats.Busy)
+	count, err := c.Keybase.CountEntries(context.Background(), true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, int(stats.Calls-stats.Errors-stats.Busy), count)
+}
+
+func TestFaults(t *testing.T) {
+	ctx := context.Background()
+	c := Wrap(open(t), Config{})
+	assert.NoError(t, c.Put(ctx, "namespace", "key"))
+	inserted, err := c.PutIfAbsent(ctx, "namespace", "other")
+	assert.NoError(t, err)
+	assert.True(t, inserted)
+	_, err = c.Increment(ctx, "namespace", "counter", 2)
+	assert.NoError(t, err)
+	counter, err := c.GetCounter(ctx, "namespace", "counter")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), counter)
+	keys, err := c.MatchKey(ctx, "namespace", "k*", true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key"}, keys)
+	assert.Zero(t, c.Stats().Errors+c.Stats().Busy+c.Stats().Delays)
+
+	c = Wrap(open(t), Config{ErrorRate: 1})
+	_, err = c.CountKey(ctx, "namespace", "key", true)
+	assert.ErrorIs(t, err, ErrTransient)
+	_, err = c.GetKeys(ctx, "namespace", true, true)
+	assert.ErrorIs(t, err, ErrTransient)
+	_, err = c.CountKeys(ctx, "namespace", true, true)
+	assert.ErrorIs(t, err, ErrTransient)
+	_, err = c.GetNamespaces(ctx, true)
+	assert.ErrorIs(t, err, ErrTransient)
+	assert.ErrorIs(t, c.PruneEntries(ctx), ErrTransient)
+
+	c = Wrap(open(t), Config{BusyRate: 1, BusyWait: time.Millisecond * 20})
+	start := time.Now()
+	_, err = c.CountEntries(ctx, true, false)
+	assert.ErrorIs(t, err, ErrBusy)
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*20)
+
+	c = Wrap(open(t), Config{DelayRate: 1, MaxDelay: time.Hour})
+	timeout, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancel()
+	assert.ErrorIs(t, c.Put(timeout, "namespace", "key"), context.DeadlineExceeded)
+}
+
+// TestRetries shows concurrent writers recovering from injected faults
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+	c := Wrap(open(t), Config{Seed: 7, DelayRate: 0.5, MaxDelay: time.Millisecond, ErrorRate: 0.2, BusyRate: 0.1})
+	group := sync.WaitGroup{}
+	for writer := 0; writer < 8; writer++ {
+		group.Add(1)
+		go func(writer int) {
+			defer group.Done()
+			for index := 0; index < 10; index++ {
+				for attempt := 0; ; attempt++ {
+					err := c.Put(ctx, "namespace", fmt.Sprintf("%d-%d", writer, index))
+					if err == nil || attempt == 100 {
+						assert.NoError(t, err)
+						break
+					}
+				}
+			}
+		}(writer)
+	}
+	group.Wait()
+	count, err := c.Keybase.CountEntries(ctx, true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 80, count)
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/crc32"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// corruptChecksum matches rows whose stored checksum no longer matches their
+// contents. Rows written before checksums were enabled have no checksum.
+const corruptChecksum = "checksum IS NOT NULL AND checksum IS NOT keybase_checksum(namespace, key, expiration)"
+
+// QuarantinedEntry entry removed from the keybase because its checksum did
+// not match its contents
+type QuarantinedEntry struct {
+	Namespace  string
+	Key        string
+	Expiration time.Time
+	Detected   time.Time
+}
+
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("keybase_checksum", 3, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		return checksumOf(args[0], args[1], args[2]), nil
+	})
+}
+
+// Store a checksum with every entry and skip entries that fail verification
+// on read, so corruption of the storage file is detected
+func WithChecksums() Option {
+	return Option{
+		key:   "checksums",
+		value: true,
+	}
+}
+
+func checksum(namespace, key string, expiration int64) int64 {
+	return checksumOf(namespace, key, expiration)
+}
+
+func checksumOf(namespace, key, expiration any) int64 {
+	return int64(crc32.ChecksumIEEE([]byte(fmt.Sprintf("%v\x00%v\x00%v", namespace, key, expiration))))
+}
+
+// migrateChecksums adds the checksum column to keybases created without it
+func migrateChecksums(ctx context.Context, db querier) error {
+	count, err := newChecksumColumnQuery().queryCount(withOperation(ctx, OpChecksumColumn), db)
+	if err != nil || count > 0 {
+		return err
+	}
+	return newAddChecksumColumnQuery().queryExec(withOperation(ctx, OpAddChecksumColumn), db)
+}
+
+// VerifyChecksums moves every entry whose checksum does not match its contents
+// to the quarantine, returning the number of entries moved
+func (k *Keybase) VerifyChecksums(ctx context.Context) (int, error) {
+	if !k.checksums {
+		return 0, fmt.Errorf("keybase.VerifyChecksums: %w: checksums are not enabled", ErrUnsupportedOption)
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	quarantined := 0
+	err := k.write(ctx, OpQuarantineEntries, nil, func(ctx context.Context) error {
+		rows, err := newQuarantineEntriesQuery(k.params(QueryParams{Timestamp: timestamp})).queryRowsAffected(ctx, k.conn)
+		quarantined = int(rows)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("keybase.VerifyChecksums: failed to quarantine entries: %w", err)
+	}
+	return quarantined, nil
+}
+
+// Quarantine lists the entries removed by VerifyChecksums, oldest first
+func (k *Keybase) Quarantine(ctx context.Context) ([]QuarantinedEntry, error) {
+	entries := []QuarantinedEntry{}
+	err := k.read(ctx, OpGetQuarantine, nil, func(ctx context.Context) error {
+		return newGetQuarantineQuery().queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			entry := QuarantinedEntry{}
+			var expiration, detected int64
+			err := rows.Scan(&entry.Namespace, &entry.Key, &expiration, &detected)
+			if err != nil {
+				return err
+			}
+			// corruption may have damaged the ciphertext, so keep it if it
+			// cannot be decrypted
+			if key, err := k.decode(entry.Key); err == nil {
+				entry.Key = key
+			}
+			entry.Expiration = time.UnixMilli(expiration)
+			entry.Detected = time.UnixMilli(detected)
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.Quarantine: failed to query database: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksum(t *testing.T) {
+	assert.Equal(t, checksum("namespace", "key", 5), checksum("namespace", "key", 5))
+	assert.NotEqual(t, checksum("namespace", "key", 5), checksum("namespace", "key", 6))
+
+	keybase, err := Open(context.Background())
+	assert.NoError(t, err)
+	defer keybase.Close()
+	var value int64
+	assert.NoError(t, keybase.db.QueryRow("SELECT keybase_checksum(?, ?, ?)", "namespace", "key", int64(5)).Scan(&value))
+	assert.Equal(t, checksum("namespace", "key", 5), value)
+
+	_, err = keybase.VerifyChecksums(context.Background())
+	assert.ErrorIs(t, err, ErrUnsupportedOption)
+}
+
+func TestChecksums(t *testing.T) {
+	storageDirectory, _ := os.MkdirTemp(os.TempDir(), "keybase-*")
+	defer os.RemoveAll(storageDirectory)
+	storagePath := path.Join(storageDirectory, "keybase.db")
+
+	keybase, err := Open(context.Background(), WithStorage(storagePath), WithTTL(time.Minute))
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "legacy"))
+	assert.NoError(t, keybase.Close())
+
+	keybase, err = Open(context.Background(), WithStorage(storagePath), WithTTL(time.Minute), WithChecksums())
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "key0"))
+	inserted, err := keybase.PutIfAbsent(context.Background(), "namespace", "key1")
+	assert.NoError(t, err)
+	assert.True(t, inserted)
+	_, err = keybase.db.Exec("UPDATE keybase SET expiration = expiration + 1 WHERE key = 'key0'")
+	assert.NoError(t, err)
+
+	keys, err := keybase.GetKeys(context.Background(), "namespace", true, true)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"legacy", "key1"}, keys)
+	_, err = keybase.GetExpiration(context.Background(), "namespace", "key0")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	quarantined, err := keybase.VerifyChecksums(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, quarantined)
+	quarantined, err = keybase.VerifyChecksums(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, quarantined)
+	entries, err := keybase.Quarantine(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "key0", entries[0].Key)
+	assert.Equal(t, "namespace", entries[0].Namespace)
+	assert.NoError(t, keybase.Close())
+
+	keybase, err = Open(context.Background(), WithStorage(storagePath), WithChecksums())
+	assert.NoError(t, err)
+	count, err := keybase.CountEntries(context.Background(), false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.NoError(t, keybase.Close())
+
+	_, err = keybase.Quarantine(context.Background())
+	assert.ErrorIs(t, err, ErrClosed)
+}
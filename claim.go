@@ -0,0 +1,151 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Claim exclusive use of a key, held as its only active entry until it
+// expires or is released. Only writes that respect active entries, such as
+// PutIfAbsent and other claims, are kept out.
+type Claim struct {
+	keybase    *Keybase
+	namespace  string
+	key        string
+	row        int64
+	inserted   int64
+	expiration time.Time
+}
+
+// Claim inserts an entry for a key that has no active entries, failing with
+// ErrClaimHeld otherwise, so that only one caller at a time holds the key
+func (k *Keybase) Claim(ctx context.Context, namespace, key string, ttl time.Duration) (*Claim, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("keybase.Claim: %w: ttl must be positive", ErrInvalidArgument)
+	}
+	now := k.clock.Now()
+	claim := &Claim{
+		keybase:    k,
+		namespace:  namespace,
+		key:        key,
+		inserted:   now.UnixMilli(),
+		expiration: now.Add(ttl),
+	}
+	claimed := false
+	err := k.write(ctx, OpClaim, []string{namespace}, func(ctx context.Context) error {
+		params := k.params(QueryParams{
+			Namespace:  namespace,
+			Key:        key,
+			Expiration: claim.expiration.UnixMilli(),
+			Timestamp:  claim.inserted,
+		})
+		return k.insert(ctx, key, func(db querier) error {
+			row, err := newClaimQuery(params).queryNullInt(ctx, db)
+			claim.row, claimed = row.Int64, row.Valid
+			if err != nil || !claimed {
+				return err
+			}
+			return k.versioned(ctx, db, params)
+		})
+	})
+	if err == nil && !claimed {
+		err = ErrClaimHeld
+	}
+	k.record(ctx, JournalEntry{Op: OpClaim, Namespace: namespace, Key: key, TTL: ttl, Handle: claim.handle(claimed)}, err)
+	if errors.Is(err, ErrClaimHeld) {
+		return nil, fmt.Errorf("keybase.Claim: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keybase.Claim: failed to claim key: %w", err)
+	}
+	return claim, nil
+}
+
+// handle identifies the claim in the journal, by its row and the time it was
+// inserted, since rows of released claims are reused
+func (c *Claim) handle(claimed bool) string {
+	if !claimed {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", c.row, c.inserted)
+}
+
+// Expiration gets the time at which the claim expires
+func (c *Claim) Expiration() time.Time {
+	return c.expiration
+}
+
+// Extend keeps the claim until ttl from now, failing with ErrClaimLost if the
+// claim already expired or was released
+func (c *Claim) Extend(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("keybase.Claim.Extend: %w: ttl must be positive", ErrInvalidArgument)
+	}
+	now := c.keybase.clock.Now()
+	expiration := now.Add(ttl)
+	extended := false
+	err := c.keybase.write(ctx, OpExtendClaim, []string{c.namespace}, func(ctx context.Context) error {
+		rows, err := newExtendClaimQuery(c.keybase.params(QueryParams{
+			Namespace:  c.namespace,
+			Key:        c.key,
+			Row:        c.row,
+			Inserted:   c.inserted,
+			Expiration: expiration.UnixMilli(),
+			Timestamp:  now.UnixMilli(),
+		})).queryRowsAffected(ctx, c.keybase.conn)
+		extended = rows > 0
+		return err
+	})
+	if err == nil && !extended {
+		err = ErrClaimLost
+	}
+	c.keybase.record(ctx, JournalEntry{Op: OpExtendClaim, Namespace: c.namespace, Key: c.key, TTL: ttl, Handle: c.handle(true)}, err)
+	if errors.Is(err, ErrClaimLost) {
+		return fmt.Errorf("keybase.Claim.Extend: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("keybase.Claim.Extend: failed to extend claim: %w", err)
+	}
+	c.expiration = expiration
+	return nil
+}
+
+// Release removes the claimed entry so the key can be claimed immediately.
+// Releasing a claim that was lost has no effect.
+func (c *Claim) Release(ctx context.Context) error {
+	err := c.keybase.write(ctx, OpReleaseClaim, []string{c.namespace}, func(ctx context.Context) error {
+		return newReleaseClaimQuery(c.keybase.params(QueryParams{
+			Namespace: c.namespace,
+			Key:       c.key,
+			Row:       c.row,
+			Inserted:  c.inserted,
+		})).queryExec(ctx, c.keybase.conn)
+	})
+	c.keybase.record(ctx, JournalEntry{Op: OpReleaseClaim, Namespace: c.namespace, Key: c.key, Handle: c.handle(true)}, err)
+	if err != nil {
+		return fmt.Errorf("keybase.Claim.Release: failed to release claim: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaim(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{nil, {WithChecksums()}, {WithEncryption(make([]byte, 32))}} {
+		clock := &fixedClock{now: time.UnixMilli(1700000000000)}
+		keybase, err := Open(ctx, append(opts, WithClock(clock))...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		_, err = keybase.Claim(ctx, "namespace", "key", 0)
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+
+		claim, err := keybase.Claim(ctx, "namespace", "key", time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, clock.now.Add(time.Minute), claim.Expiration())
+		_, err = keybase.Claim(ctx, "namespace", "key", time.Minute)
+		assert.ErrorIs(t, err, ErrClaimHeld)
+		other, err := keybase.Claim(ctx, "othernamespace", "key", time.Minute)
+		assert.NoError(t, err)
+		assert.NoError(t, other.Release(ctx))
+
+		clock.now = clock.now.Add(30 * time.Second)
+		assert.ErrorIs(t, claim.Extend(ctx, 0), ErrInvalidArgument)
+		assert.NoError(t, claim.Extend(ctx, time.Minute))
+		clock.now = clock.now.Add(45 * time.Second)
+		_, err = keybase.Claim(ctx, "namespace", "key", time.Minute)
+		assert.ErrorIs(t, err, ErrClaimHeld)
+		assert.NoError(t, claim.Release(ctx))
+		assert.ErrorIs(t, claim.Extend(ctx, time.Minute), ErrClaimLost)
+
+		claim, err = keybase.Claim(ctx, "namespace", "key", time.Second)
+		assert.NoError(t, err)
+		clock.now = clock.now.Add(time.Second)
+		assert.ErrorIs(t, claim.Extend(ctx, time.Minute), ErrClaimLost)
+		assert.NoError(t, keybase.PruneEntries(ctx))
+		clock.now = clock.now.Add(time.Millisecond)
+		next, err := keybase.Claim(ctx, "namespace", "key", time.Minute)
+		assert.NoError(t, err)
+		assert.NoError(t, claim.Release(ctx))
+		assert.ErrorIs(t, claim.Extend(ctx, time.Minute), ErrClaimLost)
+		count, err := keybase.CountKey(ctx, "namespace", "key", true)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.NoError(t, next.Release(ctx))
+
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err = keybase.Claim(canceled, "namespace", "key", time.Minute)
+		assert.Error(t, err)
+		assert.Error(t, next.Extend(canceled, time.Minute))
+		assert.Error(t, next.Release(canceled))
+	}
+}
+
+func TestClaimConcurrent(t *testing.T) {
+	ctx := context.Background()
+	keybase, err := Open(ctx)
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	claimed := atomic.Int32{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := keybase.Claim(ctx, "namespace", "key", time.Minute)
+			if err == nil {
+				claimed.Add(1)
+			} else {
+				assert.ErrorIs(t, err, ErrClaimHeld)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), claimed.Load())
+}
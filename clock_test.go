@@ -0,0 +1,62 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestWithClock(t *testing.T) {
+	ctx := context.Background()
+	clock := &fixedClock{now: time.Now().Add(-time.Hour).Truncate(time.Millisecond)}
+	keybase, err := Open(ctx, WithClock(clock), WithTTL(time.Minute))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+	expiration, err := keybase.GetExpiration(ctx, "namespace", "key0")
+	assert.NoError(t, err)
+	assert.True(t, clock.now.Add(time.Minute).Equal(expiration))
+	ttl, err := keybase.GetTTL(ctx, "namespace", "key0")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	clock.now = clock.now.Add(time.Minute)
+	count, err := keybase.CountKey(ctx, "namespace", "key0", true)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+	assert.NoError(t, keybase.PruneEntries(ctx))
+	count, err = keybase.CountKey(ctx, "namespace", "key0", false)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
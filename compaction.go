@@ -0,0 +1,75 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CompactionPolicy selects which entry is kept when collapsing duplicates
+type CompactionPolicy int
+
+const (
+	// KeepLatest keeps the entry with the latest expiration
+	KeepLatest CompactionPolicy = iota
+	// KeepEarliest keeps the entry with the earliest expiration
+	KeepEarliest
+)
+
+type compactionOption struct {
+	interval time.Duration
+	policy   CompactionPolicy
+}
+
+// Periodically collapse duplicate entries in the background
+func WithDuplicateCompaction(interval time.Duration, policy CompactionPolicy) Option {
+	return Option{
+		key: "compaction",
+		value: compactionOption{
+			interval: interval,
+			policy:   policy,
+		},
+	}
+}
+
+// CompactDuplicates collapses the entries of each key into a single entry
+// chosen by the policy, returning the number of entries removed
+func (k *Keybase) CompactDuplicates(ctx context.Context, policy CompactionPolicy) (int, error) {
+	removed := 0
+	err := k.write(ctx, OpCompactDuplicates, nil, func(ctx context.Context) error {
+		rows, err := newCompactDuplicatesQuery(k.params(QueryParams{Policy: policy})).queryRowsAffected(ctx, k.conn)
+		removed = int(rows)
+		return err
+	})
+	k.record(ctx, JournalEntry{Op: OpCompactDuplicates, Policy: policy}, err)
+	if err != nil {
+		return 0, fmt.Errorf("keybase.CompactDuplicates: failed to remove duplicates: %w", err)
+	}
+	return removed, nil
+}
+
+// DuplicateCompaction handle for the background compaction feature, which can
+// be started even if it was not enabled with WithDuplicateCompaction
+func (k *Keybase) DuplicateCompaction() *Feature {
+	return k.compact
+}
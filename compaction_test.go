@@ -0,0 +1,84 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompactDuplicates(t *testing.T) {
+	keybase, err := Open(context.Background(), WithTTL(time.Minute))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	put := func(namespace, key string, ttl time.Duration) {
+		assert.NoError(t, keybase.Reconfigure(context.Background(), WithTTL(ttl)))
+		assert.NoError(t, keybase.Put(context.Background(), namespace, key))
+	}
+	put("namespace", "key0", time.Minute)
+	put("namespace", "key0", time.Hour)
+	put("namespace", "key0", time.Hour)
+	put("namespace", "key1", time.Millisecond)
+	put("namespace", "key1", time.Minute)
+	put("othernamespace", "key0", time.Minute)
+
+	removed, err := keybase.CompactDuplicates(context.Background(), KeepLatest)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, removed)
+	count, err := keybase.CountEntries(context.Background(), false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	ttl, err := keybase.GetTTL(context.Background(), "namespace", "key0")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Minute)
+
+	put("namespace", "key0", time.Millisecond)
+	time.Sleep(time.Millisecond * 5)
+	removed, err = keybase.CompactDuplicates(context.Background(), KeepEarliest)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	_, err = keybase.GetTTL(context.Background(), "namespace", "key0")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(0))
+	defer cancel()
+	_, err = keybase.CompactDuplicates(ctx, KeepLatest)
+	assert.Error(t, err)
+}
+
+func TestDuplicateCompaction(t *testing.T) {
+	keybase, err := Open(context.Background(), WithDuplicateCompaction(time.Millisecond*5, KeepLatest))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.True(t, keybase.DuplicateCompaction().Status().Running)
+
+	for index := 0; index < 3; index++ {
+		assert.NoError(t, keybase.Put(context.Background(), "namespace", "key"))
+	}
+	assert.Eventually(t, func() bool {
+		count, err := keybase.CountEntries(context.Background(), false, false)
+		return err == nil && count == 1
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type fileOptions struct {
+	Storage       string            `yaml:"storage"`
+	TTL           string            `yaml:"ttl"`
+	PruneInterval string            `yaml:"prune_interval"`
+	Pragmas       map[string]string `yaml:"pragmas"`
+}
+
+// OptionsFromFile loads options from a YAML or JSON configuration file
+func OptionsFromFile(path string) ([]Option, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("keybase.OptionsFromFile: failed to read file: %w", err)
+	}
+	config := fileOptions{}
+	err = yaml.Unmarshal(data, &config)
+	if err != nil {
+		return nil, fmt.Errorf("keybase.OptionsFromFile: failed to parse file: %w", err)
+	}
+	opts, err := config.options()
+	if err != nil {
+		return nil, fmt.Errorf("keybase.OptionsFromFile: %w", err)
+	}
+	return opts, nil
+}
+
+// OptionsFromEnv loads options from environment variables named with the
+// given prefix, such as PREFIX_STORAGE, PREFIX_TTL and PREFIX_PRUNE_INTERVAL
+func OptionsFromEnv(prefix string) ([]Option, error) {
+	name := func(variable string) string {
+		if prefix == "" {
+			return variable
+		}
+		return prefix + "_" + variable
+	}
+	config := fileOptions{
+		Storage:       os.Getenv(name("STORAGE")),
+		TTL:           os.Getenv(name("TTL")),
+		PruneInterval: os.Getenv(name("PRUNE_INTERVAL")),
+	}
+	opts, err := config.options()
+	if err != nil {
+		return nil, fmt.Errorf("keybase.OptionsFromEnv: %w", err)
+	}
+	return opts, nil
+}
+
+func (config fileOptions) options() ([]Option, error) {
+	opts := []Option{}
+	if config.Storage != "" {
+		opts = append(opts, WithStorage(config.Storage))
+	}
+	if config.TTL != "" {
+		ttl, err := time.ParseDuration(config.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ttl: %w", err)
+		}
+		opts = append(opts, WithTTL(ttl))
+	}
+	if config.PruneInterval != "" {
+		interval, err := time.ParseDuration(config.PruneInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prune interval: %w", err)
+		}
+		opts = append(opts, WithAutoPrune(interval))
+	}
+	if len(config.Pragmas) > 0 {
+		opts = append(opts, WithPragmas(config.Pragmas))
+	}
+	return opts, nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsFromFile(t *testing.T) {
+	directory := t.TempDir()
+	write := func(name, contents string) string {
+		path := filepath.Join(directory, name)
+		assert.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+		return path
+	}
+
+	_, err := OptionsFromFile(filepath.Join(directory, "missing.yaml"))
+	assert.Error(t, err)
+
+	_, err = OptionsFromFile(write("invalid.yaml", "storage: [\n"))
+	assert.Error(t, err)
+
+	_, err = OptionsFromFile(write("ttl.yaml", "ttl: forever\n"))
+	assert.Error(t, err)
+
+	opts, err := OptionsFromFile(write("keybase.yaml", "storage: /tmp/keybase.db\nttl: 1m\nprune_interval: 30s\n"))
+	assert.NoError(t, err)
+	config := parseOptions(opts...)
+	assert.Equal(t, "/tmp/keybase.db", config.storage)
+	assert.Equal(t, time.Minute, config.ttl)
+	assert.True(t, config.autoPrune)
+	assert.Equal(t, time.Second*30, config.pruneInterval)
+
+	opts, err = OptionsFromFile(write("pragmas.yaml", "pragmas:\n  journal_mode: WAL\n  cache_size: \"-2000\"\n"))
+	assert.NoError(t, err)
+	config = parseOptions(opts...)
+	assert.Equal(t, map[string]string{"journal_mode": "WAL", "cache_size": "-2000"}, config.pragmas)
+
+	opts, err = OptionsFromFile(write("keybase.json", `{"ttl": "5s"}`))
+	assert.NoError(t, err)
+	config = parseOptions(opts...)
+	assert.Equal(t, defaultStorage, config.storage)
+	assert.Equal(t, time.Second*5, config.ttl)
+	assert.False(t, config.autoPrune)
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv("KEYBASE_STORAGE", "/tmp/keybase.db")
+	t.Setenv("KEYBASE_TTL", "1m")
+	opts, err := OptionsFromEnv("KEYBASE")
+	assert.NoError(t, err)
+	config := parseOptions(opts...)
+	assert.Equal(t, "/tmp/keybase.db", config.storage)
+	assert.Equal(t, time.Minute, config.ttl)
+	assert.False(t, config.autoPrune)
+
+	t.Setenv("PRUNE_INTERVAL", "never")
+	_, err = OptionsFromEnv("")
+	assert.Error(t, err)
+}
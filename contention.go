@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ContentionReport breakdown of the time write operations spent waiting to
+// run versus executing SQL during a sample window
+type ContentionReport struct {
+	Sample     time.Duration
+	Serialized bool
+	Writes     int64
+	// LockWait time spent waiting on the serialization lock
+	LockWait time.Duration
+	// PoolWait time spent by all operations waiting for a free connection
+	PoolWait time.Duration
+	// Executing time spent running the operations once admitted, including
+	// any time SQLite spent waiting on its own locks
+	Executing time.Duration
+}
+
+// WaitRatio fraction of the write path spent waiting rather than executing
+func (r ContentionReport) WaitRatio() float64 {
+	total := r.LockWait + r.PoolWait + r.Executing
+	if total == 0 {
+		return 0
+	}
+	return float64(r.LockWait+r.PoolWait) / float64(total)
+}
+
+type contentionStats struct {
+	writes    int64
+	lockWait  time.Duration
+	executing time.Duration
+}
+
+func (s *queryStats) recordWrite(wait, executing time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes.writes++
+	s.writes.lockWait += wait
+	s.writes.executing += executing
+}
+
+func (s *queryStats) contention() contentionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writes
+}
+
+// ContentionReport samples the write path for the given duration and reports
+// how much of it was spent waiting, to help decide whether serialization
+// is worth enabling
+func (k *Keybase) ContentionReport(ctx context.Context, sample time.Duration) (ContentionReport, error) {
+	if k.closed.Load() {
+		return ContentionReport{}, fmt.Errorf("keybase.ContentionReport: %w", ErrClosed)
+	}
+	before, pool := k.stats.contention(), k.db.Stats()
+	start := time.Now()
+	timer := time.NewTimer(sample)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ContentionReport{}, fmt.Errorf("keybase.ContentionReport: %w", ctx.Err())
+	case <-timer.C:
+	}
+	after := k.stats.contention()
+	return ContentionReport{
+		Sample:     time.Since(start),
+		Serialized: k.serialize,
+		Writes:     after.writes - before.writes,
+		LockWait:   after.lockWait - before.lockWait,
+		PoolWait:   k.db.Stats().WaitDuration - pool.WaitDuration,
+		Executing:  after.executing - before.executing,
+	}, nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitRatio(t *testing.T) {
+	assert.Zero(t, ContentionReport{}.WaitRatio())
+	report := ContentionReport{LockWait: time.Second, PoolWait: time.Second, Executing: time.Second * 2}
+	assert.Equal(t, 0.5, report.WaitRatio())
+}
+
+func TestContentionReport(t *testing.T) {
+	keybase, err := Open(context.Background(), WithSerialization())
+	assert.NoError(t, err)
+
+	wg := sync.WaitGroup{}
+	stop := make(chan struct{})
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					assert.NoError(t, keybase.Put(context.Background(), "namespace", "key"))
+				}
+			}
+		}()
+	}
+	report, err := keybase.ContentionReport(context.Background(), time.Millisecond*50)
+	close(stop)
+	wg.Wait()
+	assert.NoError(t, err)
+	assert.True(t, report.Serialized)
+	assert.GreaterOrEqual(t, report.Sample, time.Millisecond*50)
+	assert.Positive(t, report.Writes)
+	assert.Positive(t, report.LockWait)
+	assert.Positive(t, report.Executing)
+	assert.Greater(t, report.WaitRatio(), 0.0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = keybase.ContentionReport(ctx, time.Minute)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.NoError(t, keybase.Close())
+	_, err = keybase.ContentionReport(context.Background(), time.Millisecond)
+	assert.ErrorIs(t, err, ErrClosed)
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+)
+
+// Increment atomically adds delta to a counter and returns the new value. A
+// counter expires one TTL after it is created, after which it restarts from delta.
+func (k *Keybase) Increment(ctx context.Context, namespace, key string, delta int64) (int64, error) {
+	now := k.clock.Now()
+	var value int64
+	err := k.write(ctx, OpIncrement, []string{namespace}, func(ctx context.Context) error {
+		result, err := newIncrementQuery(k.params(QueryParams{
+			Namespace:  namespace,
+			Key:        key,
+			Delta:      delta,
+			Expiration: k.expiration(now),
+			Timestamp:  now.UnixMilli(),
+		})).queryNullInt(ctx, k.conn)
+		value = result.Int64
+		return err
+	})
+	k.record(ctx, JournalEntry{Op: OpIncrement, Namespace: namespace, Key: key, Delta: delta}, err)
+	if err != nil {
+		return 0, fmt.Errorf("keybase.Increment: failed to update counter: %w", err)
+	}
+	return value, nil
+}
+
+// GetCounter gets the current value of a counter, which is zero if the counter
+// does not exist or has expired
+func (k *Keybase) GetCounter(ctx context.Context, namespace, key string) (int64, error) {
+	timestamp := k.clock.Now().UnixMilli()
+	var value int64
+	err := k.read(ctx, OpGetCounter, []string{namespace}, func(ctx context.Context) error {
+		result, err := newGetCounterQuery(k.params(QueryParams{Namespace: namespace, Key: key, Timestamp: timestamp})).queryNullInt(ctx, k.conn)
+		value = result.Int64
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("keybase.GetCounter: failed to query database: %w", err)
+	}
+	return value, nil
+}
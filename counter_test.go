@@ -0,0 +1,69 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounter(t *testing.T) {
+	keybase, err := Open(context.Background(), WithTTL(time.Millisecond*50))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	value, err := keybase.GetCounter(context.Background(), "namespace", "counter")
+	assert.NoError(t, err)
+	assert.Zero(t, value)
+
+	value, err = keybase.Increment(context.Background(), "namespace", "counter", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), value)
+	value, err = keybase.Increment(context.Background(), "namespace", "counter", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), value)
+	value, err = keybase.Increment(context.Background(), "othernamespace", "counter", -1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), value)
+
+	value, err = keybase.GetCounter(context.Background(), "namespace", "counter")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), value)
+
+	time.Sleep(time.Millisecond * 50)
+
+	value, err = keybase.GetCounter(context.Background(), "namespace", "counter")
+	assert.NoError(t, err)
+	assert.Zero(t, value)
+	value, err = keybase.Increment(context.Background(), "namespace", "counter", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), value)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(0))
+	defer cancel()
+	_, err = keybase.Increment(ctx, "namespace", "counter", 1)
+	assert.Error(t, err)
+	_, err = keybase.GetCounter(ctx, "namespace", "counter")
+	assert.Error(t, err)
+}
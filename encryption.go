@@ -0,0 +1,140 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// keyCipher encrypts identifiers deterministically with AES-GCM, deriving the
+// nonce from the plaintext so equal keys encrypt to equal ciphertexts and
+// can still be compared, counted and indexed by SQLite
+type keyCipher struct {
+	aead     cipher.AEAD
+	nonceKey []byte
+}
+
+// Encrypt keys and field values with AES-GCM before they are written to
+// storage. The key must be 16, 24 or 32 bytes long. Encryption is
+// deterministic, which reveals whether two stored keys are equal, and
+// pattern matching falls back to filtering decrypted keys in Go.
+func WithEncryption(key []byte) Option {
+	return Option{
+		key:   "encryption",
+		value: key,
+	}
+}
+
+func newKeyCipher(key []byte) (*keyCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArgument, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonceKey := sha256.Sum256(append([]byte("keybase nonce "), key...))
+	return &keyCipher{aead: aead, nonceKey: nonceKey[:]}, nil
+}
+
+func (c *keyCipher) encrypt(plaintext string) string {
+	mac := hmac.New(sha256.New, c.nonceKey)
+	_, _ = mac.Write([]byte(plaintext))
+	nonce := mac.Sum(nil)[:c.aead.NonceSize()]
+	return base64.RawURLEncoding.EncodeToString(c.aead.Seal(nonce, nonce, []byte(plaintext), nil))
+}
+
+func (c *keyCipher) decrypt(ciphertext string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(ciphertext)
+	if err != nil || len(data) < c.aead.NonceSize() {
+		return "", fmt.Errorf("%w: malformed ciphertext", ErrDecryption)
+	}
+	nonce, sealed := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDecryption, err)
+	}
+	return string(plaintext), nil
+}
+
+// encode encrypts a stored identifier when encryption is enabled. Empty
+// strings are left as is, since they select every key in some queries.
+func (k *Keybase) encode(value string) string {
+	if k.cipher == nil || value == "" {
+		return value
+	}
+	return k.cipher.encrypt(value)
+}
+
+func (k *Keybase) decode(value string) (string, error) {
+	if k.cipher == nil || value == "" {
+		return value, nil
+	}
+	return k.cipher.decrypt(value)
+}
+
+func (k *Keybase) decodeAll(values []string) ([]string, error) {
+	if k.cipher == nil {
+		return values, nil
+	}
+	decoded := make([]string, 0, len(values))
+	for _, value := range values {
+		plaintext, err := k.decode(value)
+		if err != nil {
+			return nil, err
+		}
+		decoded = append(decoded, plaintext)
+	}
+	return decoded, nil
+}
+
+// likePattern compiles a glob pattern to a regular expression with the same
+// semantics as the LIKE clause built by globToLike, for matching in Go
+func likePattern(pattern string) *regexp.Regexp {
+	expression := strings.Builder{}
+	expression.WriteString("(?is)^")
+	escaped := false
+	for _, r := range globToLike(pattern) {
+		switch {
+		case escaped:
+			expression.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == likeEscape:
+			escaped = true
+		case r == '%':
+			expression.WriteString(".*")
+		case r == '_':
+			expression.WriteString(".")
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expression.WriteString("$")
+	return regexp.MustCompile(expression.String())
+}
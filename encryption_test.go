@@ -0,0 +1,135 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyCipher(t *testing.T) {
+	_, err := newKeyCipher([]byte("short"))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	keyCipher, err := newKeyCipher([]byte(strings.Repeat("k", 32)))
+	assert.NoError(t, err)
+	ciphertext := keyCipher.encrypt("key")
+	assert.NotContains(t, ciphertext, "key")
+	assert.Equal(t, ciphertext, keyCipher.encrypt("key"))
+	assert.NotEqual(t, ciphertext, keyCipher.encrypt("otherkey"))
+	plaintext, err := keyCipher.decrypt(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "key", plaintext)
+
+	_, err = keyCipher.decrypt("!")
+	assert.ErrorIs(t, err, ErrDecryption)
+	otherCipher, _ := newKeyCipher([]byte(strings.Repeat("o", 32)))
+	_, err = otherCipher.decrypt(ciphertext)
+	assert.ErrorIs(t, err, ErrDecryption)
+}
+
+func TestLikePattern(t *testing.T) {
+	assert.True(t, likePattern("key*").MatchString("Key0"))
+	assert.True(t, likePattern("k?y").MatchString("key"))
+	assert.False(t, likePattern("k?y").MatchString("keey"))
+	assert.True(t, likePattern("a.b*").MatchString("a.bc"))
+	assert.False(t, likePattern("a.b*").MatchString("axbc"))
+}
+
+func TestEncryption(t *testing.T) {
+	storageDirectory, _ := os.MkdirTemp(os.TempDir(), "keybase-*")
+	defer os.RemoveAll(storageDirectory)
+	storagePath := path.Join(storageDirectory, "keybase.db")
+	secret := []byte(strings.Repeat("s", 32))
+
+	_, err := Open(context.Background(), WithEncryption([]byte("short")))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	keybase, err := Open(context.Background(), WithStorage(storagePath), WithEncryption(secret), WithOverflow(32), WithTTL(time.Millisecond*50))
+	assert.NoError(t, err)
+	expired := []string{}
+	mu := sync.Mutex{}
+	keybase.OnExpire(func(namespace, key string) {
+		mu.Lock()
+		defer mu.Unlock()
+		expired = append(expired, key)
+	})
+	long := strings.Repeat("secret", 10)
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "secret0"))
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "secret0"))
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "other"))
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", long))
+	assert.NoError(t, keybase.PutField(context.Background(), "namespace", "secret0", "field", "hidden"))
+
+	var leaked int
+	assert.NoError(t, keybase.db.QueryRow(`SELECT (SELECT COUNT(*) FROM keybase WHERE key LIKE '%secret%')
+		+ (SELECT COUNT(*) FROM keybase_overflow WHERE value LIKE '%secret%')
+		+ (SELECT COUNT(*) FROM keybase_fields WHERE key LIKE '%secret%' OR value LIKE '%hidden%')`).Scan(&leaked))
+	assert.Zero(t, leaked)
+
+	keys, err := keybase.GetKeys(context.Background(), "namespace", true, true)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"secret0", "other", long}, keys)
+	keys, err = keybase.MatchKey(context.Background(), "namespace", "SECRET*", true, false)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"secret0", "secret0", long}, keys)
+	across, err := keybase.MatchKeyAcross(context.Background(), nil, "secret0", true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []NamespacedKey{{"namespace", "secret0"}}, across)
+	count, err := keybase.CountMatch(context.Background(), "namespace", "SECRET*", true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	count, err = keybase.CountKey(context.Background(), "namespace", "secret0", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	value, err := keybase.GetField(context.Background(), "namespace", "secret0", "field")
+	assert.NoError(t, err)
+	assert.Equal(t, "hidden", value)
+	fields, err := keybase.GetFields(context.Background(), "namespace", "secret0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"field": "hidden"}, fields)
+	assert.NoError(t, keybase.Close())
+
+	keybase, err = Open(context.Background(), WithStorage(storagePath), WithEncryption([]byte(strings.Repeat("o", 32))), WithOverflow(32), WithTTL(time.Millisecond*50))
+	assert.NoError(t, err)
+	_, err = keybase.GetKeys(context.Background(), "namespace", false, false)
+	assert.ErrorIs(t, err, ErrDecryption)
+	assert.NoError(t, keybase.Close())
+
+	keybase, err = Open(context.Background(), WithStorage(storagePath), WithEncryption(secret), WithOverflow(32))
+	assert.NoError(t, err)
+	keybase.OnExpire(func(namespace, key string) {
+		mu.Lock()
+		defer mu.Unlock()
+		expired = append(expired, key)
+	})
+	time.Sleep(time.Millisecond * 60)
+	assert.NoError(t, keybase.PruneEntries(context.Background()))
+	assert.NoError(t, keybase.Close())
+	assert.ElementsMatch(t, []string{"secret0", "secret0", "other", long}, expired)
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Entry a single entry of a key, with its expiration
+type Entry struct {
+	Namespace  string
+	Key        string
+	Expiration time.Time
+}
+
+// EntryOption filters the entries collected by GetEntries
+type EntryOption struct {
+	key   string
+	value any
+}
+
+type entryFilter struct {
+	active  bool
+	pattern string
+	limit   int
+}
+
+// Only collect entries that have not expired
+func ActiveEntries() EntryOption {
+	return EntryOption{
+		key: "active",
+	}
+}
+
+// Only collect entries with a key matching the pattern
+func MatchingEntries(pattern string) EntryOption {
+	return EntryOption{
+		key:   "pattern",
+		value: pattern,
+	}
+}
+
+// Collect at most limit entries, soonest to expire first
+func LimitEntries(limit int) EntryOption {
+	return EntryOption{
+		key:   "limit",
+		value: limit,
+	}
+}
+
+func parseEntryOptions(opts ...EntryOption) entryFilter {
+	filter := entryFilter{}
+	for _, opt := range opts {
+		switch opt.key {
+		case "active":
+			filter.active = true
+		case "pattern":
+			filter.pattern = opt.value.(string)
+		case "limit":
+			filter.limit = opt.value.(int)
+		}
+	}
+	return filter
+}
+
+// GetEntries collects the entries of a namespace along with their expiration,
+// ordered by expiration
+func (k *Keybase) GetEntries(ctx context.Context, namespace string, opts ...EntryOption) ([]Entry, error) {
+	filter := parseEntryOptions(opts...)
+	if filter.limit < 0 {
+		return nil, fmt.Errorf("keybase.GetEntries: %w: negative limit", ErrInvalidArgument)
+	}
+	params := QueryParams{Namespace: namespace, Pattern: filter.pattern, Active: filter.active, Limit: filter.limit, Timestamp: k.clock.Now().UnixMilli()}
+	if k.cipher != nil {
+		// encrypted keys cannot be matched by SQLite, so the pattern and
+		// limit are applied here
+		params.Pattern, params.Limit = "", 0
+	}
+	entries := []Entry{}
+	err := k.read(ctx, OpGetEntries, []string{namespace}, func(ctx context.Context) error {
+		matcher := likePattern(filter.pattern)
+		return newGetEntriesQuery(k.params(params)).queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			entry := Entry{Namespace: namespace}
+			var expiration int64
+			err := rows.Scan(&entry.Key, &expiration)
+			if err != nil {
+				return err
+			}
+			entry.Key, err = k.decode(entry.Key)
+			if err != nil {
+				return err
+			}
+			if k.cipher != nil {
+				if filter.pattern != "" && !matcher.MatchString(entry.Key) {
+					return nil
+				}
+				if filter.limit > 0 && len(entries) == filter.limit {
+					return nil
+				}
+			}
+			entry.Expiration = time.UnixMilli(expiration)
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.GetEntries: failed to query database: %w", err)
+	}
+	return entries, nil
+}
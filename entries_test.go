@@ -0,0 +1,67 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEntries(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{{}, {WithOverflow(8)}, {WithEncryption(make([]byte, 32))}} {
+		keybase, err := Open(ctx, opts...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+		now := time.Now().Truncate(time.Millisecond)
+		assert.NoError(t, keybase.PutUntil(ctx, "namespace", "user:0", now.Add(time.Hour)))
+		assert.NoError(t, keybase.PutUntil(ctx, "namespace", "user:1", now.Add(time.Minute)))
+		assert.NoError(t, keybase.PutUntil(ctx, "namespace", "session:overflowing", now.Add(-time.Minute)))
+		assert.NoError(t, keybase.PutUntil(ctx, "other", "user:2", now.Add(time.Hour)))
+
+		entries, err := keybase.GetEntries(ctx, "namespace")
+		assert.NoError(t, err)
+		assert.Equal(t, []Entry{
+			{Namespace: "namespace", Key: "session:overflowing", Expiration: now.Add(-time.Minute)},
+			{Namespace: "namespace", Key: "user:1", Expiration: now.Add(time.Minute)},
+			{Namespace: "namespace", Key: "user:0", Expiration: now.Add(time.Hour)},
+		}, entries)
+
+		entries, err = keybase.GetEntries(ctx, "namespace", ActiveEntries(), LimitEntries(1))
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "user:1", entries[0].Key)
+
+		entries, err = keybase.GetEntries(ctx, "namespace", MatchingEntries("*:0"))
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "user:0", entries[0].Key)
+
+		entries, err = keybase.GetEntries(ctx, "missing")
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+		_, err = keybase.GetEntries(ctx, "namespace", LimitEntries(-1))
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+	}
+}
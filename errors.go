@@ -0,0 +1,72 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import "errors"
+
+var (
+	// ErrClosed returned when using a keybase after it has been closed
+	ErrClosed = errors.New("keybase: keybase is closed")
+	// ErrInvalidStorage returned when the storage option cannot be opened
+	ErrInvalidStorage = errors.New("keybase: invalid storage")
+	// ErrNotFound returned when a requested entry does not exist
+	ErrNotFound = errors.New("keybase: not found")
+	// ErrReadOnly returned when writing to a read-only keybase
+	ErrReadOnly = errors.New("keybase: keybase is read-only")
+	// ErrLeaseHeld returned when acquiring a lease that is held by another owner
+	ErrLeaseHeld = errors.New("keybase: lease is held")
+	// ErrLeaseLost returned when renewing a lease that expired or was released
+	ErrLeaseLost = errors.New("keybase: lease is lost")
+	// ErrClaimHeld returned when claiming a key that has active entries
+	ErrClaimHeld = errors.New("keybase: claim is held")
+	// ErrClaimLost returned when extending a claim that expired or was released
+	ErrClaimLost = errors.New("keybase: claim is lost")
+	// ErrInvalidPattern returned when a pattern is empty or malformed
+	ErrInvalidPattern = errors.New("keybase: invalid pattern")
+	// ErrInvalidArgument returned when an argument is outside of its valid range
+	ErrInvalidArgument = errors.New("keybase: invalid argument")
+	// ErrUnsupportedOption returned when an option cannot be applied
+	ErrUnsupportedOption = errors.New("keybase: unsupported option")
+	// ErrImmutable returned when putting a key that already exists in an
+	// immutable namespace
+	ErrImmutable = errors.New("keybase: namespace is immutable")
+	// ErrStaleFence returned when writing with a fencing token that is not
+	// the latest of its namespace
+	ErrStaleFence = errors.New("keybase: stale fencing token")
+	// ErrForbidden returned when the authorizer denies an operation
+	ErrForbidden = errors.New("keybase: forbidden")
+	// ErrIntegrity returned when the storage fails its integrity check
+	ErrIntegrity = errors.New("keybase: integrity check failed")
+	// ErrQuotaExceeded returned when a write would exceed the entry quota
+	ErrQuotaExceeded = errors.New("keybase: quota exceeded")
+	// ErrDecryption returned when stored data cannot be decrypted with the
+	// configured encryption key
+	ErrDecryption = errors.New("keybase: failed to decrypt")
+	// ErrOverloaded returned when a read is shed while the latency objective
+	// set with WithSLO is breached
+	ErrOverloaded = errors.New("keybase: overloaded")
+	// ErrMigrationRequired returned when opening storage with an older schema
+	// while migrations are disabled
+	ErrMigrationRequired = errors.New("keybase: schema migration required")
+	// ErrSchemaVersion returned when opening storage with a schema newer than
+	// this version of the library supports
+	ErrSchemaVersion = errors.New("keybase: unsupported schema version")
+)
@@ -0,0 +1,130 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	expireWorkers   int = 4
+	expireQueueSize int = 1024
+)
+
+type expiredEntry struct {
+	namespace string
+	key       string
+}
+
+// expireDispatcher delivers pruned entries to the expiration callbacks
+// using a bounded pool of workers. The callbacks are loaded without taking mu,
+// so workers keep draining the queue while dispatch holds it.
+type expireDispatcher struct {
+	mu        *sync.RWMutex
+	callbacks atomic.Pointer[[]func(namespace, key string)]
+	queue     chan expiredEntry
+	workers   *sync.WaitGroup
+	closed    bool
+}
+
+func newExpireDispatcher() *expireDispatcher {
+	return &expireDispatcher{
+		mu:      new(sync.RWMutex),
+		workers: new(sync.WaitGroup),
+	}
+}
+
+// OnExpire registers a callback that is notified asynchronously of each entry
+// removed by PruneEntries or the auto-prune feature
+func (k *Keybase) OnExpire(fn func(namespace, key string)) {
+	k.expire.register(fn)
+}
+
+func (d *expireDispatcher) register(fn func(namespace, key string)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	callbacks := append(d.loadCallbacks(), fn)
+	d.callbacks.Store(&callbacks)
+	if d.queue == nil {
+		d.queue = make(chan expiredEntry, expireQueueSize)
+		for worker := 0; worker < expireWorkers; worker++ {
+			d.workers.Add(1)
+			go d.work()
+		}
+	}
+}
+
+func (d *expireDispatcher) loadCallbacks() []func(namespace, key string) {
+	callbacks := d.callbacks.Load()
+	if callbacks == nil {
+		return nil
+	}
+	return slices.Clip(*callbacks)
+}
+
+func (d *expireDispatcher) active() bool {
+	return len(d.loadCallbacks()) > 0
+}
+
+func (d *expireDispatcher) dispatch(ctx context.Context, entries []expiredEntry) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed || d.queue == nil {
+		return
+	}
+	for _, entry := range entries {
+		select {
+		case d.queue <- entry:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *expireDispatcher) work() {
+	defer d.workers.Done()
+	for entry := range d.queue {
+		for _, callback := range d.loadCallbacks() {
+			callback(entry.namespace, entry.key)
+		}
+	}
+}
+
+// close delivers the queued entries and stops the workers
+func (d *expireDispatcher) close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	if d.queue != nil {
+		close(d.queue)
+	}
+	d.mu.Unlock()
+	d.workers.Wait()
+}
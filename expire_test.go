@@ -0,0 +1,114 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnExpire(t *testing.T) {
+	keybase, err := Open(context.Background(), WithTTL(time.Millisecond*10))
+	assert.NoError(t, err)
+
+	mu := new(sync.Mutex)
+	expired := map[string]int{}
+	keybase.OnExpire(func(namespace, key string) {
+		mu.Lock()
+		defer mu.Unlock()
+		expired[namespace+"/"+key]++
+	})
+
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "key0"))
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "key0"))
+	assert.NoError(t, keybase.Put(context.Background(), "othernamespace", "key1"))
+	time.Sleep(time.Millisecond * 10)
+	assert.NoError(t, keybase.Reconfigure(context.Background(), WithTTL(time.Minute)))
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "key2"))
+	assert.NoError(t, keybase.PruneEntries(context.Background()))
+
+	assert.NoError(t, keybase.Close())
+	assert.Equal(t, map[string]int{"namespace/key0": 2, "othernamespace/key1": 1}, expired)
+
+	keybase.OnExpire(func(namespace, key string) {})
+	assert.Len(t, keybase.expire.loadCallbacks(), 1)
+}
+
+func TestExpireDispatcher(t *testing.T) {
+	dispatcher := newExpireDispatcher()
+	assert.False(t, dispatcher.active())
+	dispatcher.dispatch(context.Background(), []expiredEntry{{namespace: "namespace", key: "key"}})
+
+	block := make(chan struct{})
+	dispatcher.register(func(namespace, key string) {
+		<-block
+	})
+	entries := make([]expiredEntry, expireQueueSize+expireWorkers+1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	dispatcher.dispatch(ctx, entries)
+	assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	close(block)
+	dispatcher.close()
+	dispatcher.close()
+}
+
+// TestExpireDispatcherClose closes the dispatcher while dispatch is blocked on
+// a full queue, which must not keep the workers from draining it
+func TestExpireDispatcherClose(t *testing.T) {
+	dispatcher := newExpireDispatcher()
+	block := make(chan struct{})
+	delivered := atomic.Int64{}
+	dispatcher.register(func(namespace, key string) {
+		<-block
+		delivered.Add(1)
+	})
+	// more entries than the workers take before they would wait on the lock
+	entries := make([]expiredEntry, expireQueueSize*2)
+	dispatched := make(chan struct{})
+	go func() {
+		defer close(dispatched)
+		dispatcher.dispatch(context.Background(), entries)
+	}()
+	assert.Eventually(t, func() bool {
+		return len(dispatcher.queue) == expireQueueSize
+	}, time.Second, time.Millisecond)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		dispatcher.close()
+	}()
+	// let close wait on the lock held by dispatch
+	time.Sleep(time.Millisecond * 10)
+	close(block)
+	select {
+	case <-closed:
+	case <-time.After(time.Second * 5):
+		t.Fatal("close did not return")
+	}
+	<-dispatched
+	assert.Equal(t, int64(len(entries)), delivered.Load())
+}
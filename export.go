@@ -0,0 +1,182 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	exportTimeFormat = "20060102T150405.000Z"
+	// exportRetention number of export files kept for each namespace
+	exportRetention = 5
+)
+
+// ExportRecord active key written to an export file, with the time its last
+// entry expires
+type ExportRecord struct {
+	Namespace  string    `json:"namespace"`
+	Key        string    `json:"key"`
+	Expiration time.Time `json:"expiration"`
+}
+
+type exportOption struct {
+	pattern  string
+	interval time.Duration
+	dir      string
+	codec    Codec
+}
+
+// Periodically export the active keys of each namespace matching the pattern
+// to its own file in dir, encoded with the codec, keeping the most recent
+// files of each namespace
+func WithScheduledExport(namespacePattern string, interval time.Duration, dir string, codec Codec) Option {
+	return Option{
+		key: "export",
+		value: exportOption{
+			pattern:  namespacePattern,
+			interval: interval,
+			dir:      dir,
+			codec:    codec,
+		},
+	}
+}
+
+// ExportNamespaces writes the active keys of each namespace matching the
+// pattern to a new file in dir, encoded with the codec or JSON if it is nil,
+// returning the paths of the files written. Older files of each namespace
+// beyond the most recent few are removed.
+func (k *Keybase) ExportNamespaces(ctx context.Context, pattern, dir string, codec Codec) ([]string, error) {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	now := k.clock.Now()
+	exports := map[string][]ExportRecord{}
+	err := k.read(ctx, OpExportNamespaces, nil, func(ctx context.Context) error {
+		params := k.params(QueryParams{Pattern: pattern, Active: true, Timestamp: now.UnixMilli()})
+		return newExportNamespacesQuery(params).queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			record, expiration := ExportRecord{}, int64(0)
+			err := rows.Scan(&record.Namespace, &record.Key, &expiration)
+			if err != nil {
+				return err
+			}
+			record.Key, err = k.decode(record.Key)
+			record.Expiration = time.UnixMilli(expiration)
+			exports[record.Namespace] = append(exports[record.Namespace], record)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.ExportNamespaces: failed to query database: %w", err)
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("keybase.ExportNamespaces: failed to create directory: %w", err)
+	}
+	files := []string{}
+	for namespace, records := range exports {
+		file, err := writeExport(dir, namespace, now, records, codec)
+		if err != nil {
+			return files, fmt.Errorf("keybase.ExportNamespaces: failed to export %q: %w", namespace, err)
+		}
+		files = append(files, file)
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+// ScheduledExport handle for the background export feature, which does
+// nothing unless it was configured with WithScheduledExport
+func (k *Keybase) ScheduledExport() *Feature {
+	return k.export
+}
+
+// writeExport writes the file through a temporary file, so a reader polling
+// the directory never sees a partial export, then rotates older files out
+func writeExport(dir, namespace string, now time.Time, records []ExportRecord, codec Codec) (string, error) {
+	data, err := codec.Encode(records)
+	if err != nil {
+		return "", err
+	}
+	prefix, extension := url.PathEscape(namespace)+"-", exportExtension(codec)
+	file := filepath.Join(dir, prefix+now.UTC().Format(exportTimeFormat)+extension)
+	temp, err := os.CreateTemp(dir, ".export-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), file)
+	}
+	if err != nil {
+		_ = os.Remove(temp.Name())
+		return "", err
+	}
+	return file, rotateExports(dir, prefix, extension)
+}
+
+func rotateExports(dir, prefix, extension string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	exports := []string{}
+	for _, entry := range entries {
+		stamp, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok {
+			continue
+		}
+		stamp, ok = strings.CutSuffix(stamp, extension)
+		if _, err := time.Parse(exportTimeFormat, stamp); ok && err == nil {
+			exports = append(exports, entry.Name())
+		}
+	}
+	slices.Sort(exports)
+	for len(exports) > exportRetention {
+		err = os.Remove(filepath.Join(dir, exports[0]))
+		if err != nil {
+			return err
+		}
+		exports = exports[1:]
+	}
+	return nil
+}
+
+func exportExtension(codec Codec) string {
+	switch codec.(type) {
+	case JSONCodec, *JSONCodec:
+		return ".json"
+	case GobCodec, *GobCodec:
+		return ".gob"
+	}
+	return ".export"
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportNamespaces(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	keybase, err := Open(ctx, WithTTL(time.Minute))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	assert.NoError(t, keybase.Put(ctx, "tenant/0", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "tenant/0", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "tenant/0", "key1"))
+	assert.NoError(t, keybase.Put(ctx, "tenant/1", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "other", "key0"))
+
+	files, err := keybase.ExportNamespaces(ctx, "tenant/*", dir, nil)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Regexp(t, `tenant%2F0-\d{8}T\d{6}\.\d{3}Z\.json$`, files[0])
+	data, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	records := []ExportRecord{}
+	assert.NoError(t, json.Unmarshal(data, &records))
+	assert.Len(t, records, 2)
+	assert.Equal(t, "tenant/0", records[0].Namespace)
+	assert.Equal(t, "key0", records[0].Key)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), records[0].Expiration, time.Second)
+
+	for i := 0; i < exportRetention+2; i++ {
+		time.Sleep(time.Millisecond * 2)
+		_, err = keybase.ExportNamespaces(ctx, "tenant/0", dir, GobCodec{})
+		assert.NoError(t, err)
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "tenant%2F0-*.gob"))
+	assert.NoError(t, err)
+	assert.Len(t, matches, exportRetention)
+	matches, err = filepath.Glob(filepath.Join(dir, "tenant%2F0-*.json"))
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+
+	assert.NoError(t, keybase.Close())
+	_, err = keybase.ExportNamespaces(ctx, "*", dir, nil)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestScheduledExport(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "exports")
+	keybase, err := Open(ctx, WithScheduledExport("*", time.Millisecond*20, dir, JSONCodec{}))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.True(t, keybase.ScheduledExport().Status().Running)
+
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+	assert.Eventually(t, func() bool {
+		matches, _ := filepath.Glob(filepath.Join(dir, "namespace-*.json"))
+		return len(matches) > 0
+	}, time.Second, time.Millisecond*10)
+
+	keybase, err = Open(ctx)
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.False(t, keybase.ScheduledExport().Status().Running)
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+var expvars = struct {
+	mu        sync.Mutex
+	published map[string]*atomic.Pointer[Keybase]
+}{
+	published: map[string]*atomic.Pointer[Keybase]{},
+}
+
+// Publish the operation counts, error counts and entry counts of the keybase
+// as an expvar map named prefix. Opening another keybase with the same prefix
+// publishes that keybase instead.
+func WithExpvar(prefix string) Option {
+	return Option{
+		key:   "expvar",
+		value: prefix,
+	}
+}
+
+// checkExpvar fails if the prefix is empty or used by a variable that was not
+// published by a keybase
+func checkExpvar(prefix string) error {
+	if prefix == "" {
+		return fmt.Errorf("%w: empty expvar prefix", ErrInvalidArgument)
+	}
+	expvars.mu.Lock()
+	defer expvars.mu.Unlock()
+	if _, ok := expvars.published[prefix]; !ok && expvar.Get(prefix) != nil {
+		return fmt.Errorf("%w: expvar %q is already published", ErrInvalidArgument, prefix)
+	}
+	return nil
+}
+
+func publishExpvar(prefix string, k *Keybase) {
+	expvars.mu.Lock()
+	defer expvars.mu.Unlock()
+	source, ok := expvars.published[prefix]
+	if !ok {
+		source = new(atomic.Pointer[Keybase])
+		expvars.published[prefix] = source
+		expvar.Publish(prefix, expvar.Func(func() any {
+			return source.Load().expvar()
+		}))
+	}
+	source.Store(k)
+}
+
+// expvar reports the counters published by WithExpvar. Entry counts are left
+// out when they cannot be queried, for instance after the keybase is closed.
+func (k *Keybase) expvar() map[string]map[string]int64 {
+	vars := map[string]map[string]int64{
+		"operations": {},
+		"errors":     {},
+	}
+	for op, stats := range k.stats.snapshot() {
+		vars["operations"][string(op)] = stats.Queries
+		vars["errors"][string(op)] = stats.Errors
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	var total, active int
+	err := k.read(context.Background(), OpExpvar, nil, func(ctx context.Context) (err error) {
+		total, active, err = k.countEntries(ctx, timestamp)
+		return err
+	})
+	if err == nil {
+		vars["entries"] = map[string]int64{"total": int64(total), "active": int64(active)}
+	}
+	return vars
+}
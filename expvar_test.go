@@ -0,0 +1,67 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpvar(t *testing.T) {
+	ctx := context.Background()
+	vars := func() map[string]map[string]int64 {
+		published := map[string]map[string]int64{}
+		assert.NoError(t, json.Unmarshal([]byte(expvar.Get("keybase_test").String()), &published))
+		return published
+	}
+	keybase, err := Open(ctx, WithExpvar("keybase_test"))
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key1"))
+	_, err = keybase.CountEntries(ctx, true, false)
+	assert.NoError(t, err)
+	published := vars()
+	assert.Equal(t, int64(2), published["operations"][string(OpPut)])
+	assert.Equal(t, int64(0), published["errors"][string(OpPut)])
+	assert.Equal(t, map[string]int64{"total": 2, "active": 2}, published["entries"])
+
+	assert.NoError(t, keybase.Close())
+	published = vars()
+	assert.Equal(t, int64(2), published["operations"][string(OpPut)])
+	assert.NotContains(t, published, "entries")
+
+	keybase, err = Open(ctx, WithExpvar("keybase_test"))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.Equal(t, map[string]int64{"total": 0, "active": 0}, vars()["entries"])
+
+	if expvar.Get("keybase_test_taken") == nil {
+		expvar.NewInt("keybase_test_taken")
+	}
+	_, err = Open(ctx, WithExpvar("keybase_test_taken"))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	_, err = Open(ctx, WithExpvar(""))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+}
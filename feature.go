@@ -0,0 +1,121 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FeatureStatus state of an optional background feature
+type FeatureStatus struct {
+	Running   bool
+	Interval  time.Duration
+	Runs      int64
+	LastRun   time.Time
+	LastError error
+}
+
+// Feature handle used to control an optional background feature at runtime
+type Feature struct {
+	mu     *sync.Mutex
+	task   func(ctx context.Context) error
+	status FeatureStatus
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newFeature(interval time.Duration, task func(ctx context.Context) error) *Feature {
+	return &Feature{
+		mu:     new(sync.Mutex),
+		task:   task,
+		status: FeatureStatus{Interval: interval},
+	}
+}
+
+// Start runs the feature in the background, doing nothing if it is already running
+func (f *Feature) Start() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.status.Running {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+	f.done = make(chan struct{})
+	f.status.Running = true
+	go f.run(ctx, f.status.Interval, f.done)
+}
+
+// Stop stops the feature and waits for a task in progress to return
+func (f *Feature) Stop() {
+	f.mu.Lock()
+	if !f.status.Running {
+		f.mu.Unlock()
+		return
+	}
+	f.cancel()
+	done := f.done
+	f.status.Running = false
+	f.mu.Unlock()
+	<-done
+}
+
+// Status reports the current state of the feature
+func (f *Feature) Status() FeatureStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.status
+}
+
+func (f *Feature) setInterval(interval time.Duration) {
+	running := f.Status().Running
+	f.Stop()
+	f.mu.Lock()
+	f.status.Interval = interval
+	f.mu.Unlock()
+	if running {
+		f.Start()
+	}
+}
+
+func (f *Feature) run(ctx context.Context, interval time.Duration, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := f.task(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			f.mu.Lock()
+			f.status.Runs++
+			f.status.LastRun = time.Now()
+			f.status.LastError = err
+			f.mu.Unlock()
+		}
+	}
+}
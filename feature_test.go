@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeature(t *testing.T) {
+	runs := new(atomic.Int64)
+	feature := newFeature(time.Millisecond*5, func(ctx context.Context) error {
+		runs.Add(1)
+		return errors.New("some error")
+	})
+	assert.False(t, feature.Status().Running)
+	feature.Stop()
+
+	feature.Start()
+	feature.Start()
+	assert.True(t, feature.Status().Running)
+	assert.Eventually(t, func() bool {
+		return runs.Load() >= 2
+	}, time.Second, time.Millisecond)
+	feature.Stop()
+	feature.Stop()
+
+	status := feature.Status()
+	assert.False(t, status.Running)
+	assert.Equal(t, time.Millisecond*5, status.Interval)
+	assert.GreaterOrEqual(t, status.Runs, int64(2))
+	assert.False(t, status.LastRun.IsZero())
+	assert.Error(t, status.LastError)
+}
+
+func TestAutoPrune(t *testing.T) {
+	keybase, err := Open(context.Background(), WithTTL(time.Millisecond), WithAutoPrune(time.Millisecond*5))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.True(t, keybase.AutoPrune().Status().Running)
+
+	err = keybase.Put(context.Background(), "namespace", "key")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		count, err := keybase.CountEntries(context.Background(), false, false)
+		return err == nil && count == 0
+	}, time.Second, time.Millisecond)
+
+	keybase.AutoPrune().Stop()
+	assert.False(t, keybase.AutoPrune().Status().Running)
+	err = keybase.Put(context.Background(), "namespace", "key")
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 20)
+	count, err := keybase.CountEntries(context.Background(), false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestFeatureSetInterval(t *testing.T) {
+	feature := newFeature(time.Hour, func(ctx context.Context) error {
+		return nil
+	})
+	feature.setInterval(time.Minute)
+	assert.False(t, feature.Status().Running)
+	assert.Equal(t, time.Minute, feature.Status().Interval)
+
+	feature.Start()
+	feature.setInterval(time.Second)
+	assert.True(t, feature.Status().Running)
+	assert.Equal(t, time.Second, feature.Status().Interval)
+	feature.Stop()
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// federatedOps are the operations that can span attached databases
+var federatedOps = []Op{OpGetNamespaces, OpCountEntries}
+
+var aliasPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// federation tracks the databases added with Attach. SQLite attachments
+// belong to a single connection, so each federated operation runs on a
+// connection that is first synced with the tracked attachments.
+type federation struct {
+	mu      sync.RWMutex
+	ops     map[Op]bool
+	aliases []string
+	paths   map[string]string
+}
+
+// Span operations across the databases added with Attach, limited to ops
+// when given. Only GetNamespaces and CountEntries can be federated.
+func WithFederation(ops ...Op) Option {
+	return Option{
+		key:   "federation",
+		value: ops,
+	}
+}
+
+func newFederation(ops []Op) (*federation, error) {
+	if len(ops) == 0 {
+		ops = federatedOps
+	}
+	f := &federation{
+		ops:   map[Op]bool{},
+		paths: map[string]string{},
+	}
+	for _, op := range ops {
+		if !slices.Contains(federatedOps, op) {
+			return nil, fmt.Errorf("%w: %s cannot be federated", ErrUnsupportedOption, op)
+		}
+		f.ops[op] = true
+	}
+	return f, nil
+}
+
+// Attach adds the keybase stored at path under alias, so that federated
+// operations include its entries. Only the main table of the attached
+// keybase is read; it is never written.
+func (k *Keybase) Attach(ctx context.Context, alias, path string) error {
+	if k.federation == nil {
+		return fmt.Errorf("keybase.Attach: %w: federation is not enabled", ErrUnsupportedOption)
+	}
+	if !aliasPattern.MatchString(alias) || strings.EqualFold(alias, "main") || strings.EqualFold(alias, "temp") {
+		return fmt.Errorf("keybase.Attach: %w: invalid alias %q", ErrInvalidArgument, alias)
+	}
+	info, err := os.Stat(path)
+	if err == nil && info.IsDir() {
+		err = fmt.Errorf("%s is a directory", path)
+	}
+	if err != nil {
+		return fmt.Errorf("keybase.Attach: %w: %w", ErrInvalidStorage, err)
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("keybase.Attach: %w: %w", ErrInvalidStorage, err)
+	}
+	err = k.read(ctx, OpAttach, nil, func(ctx context.Context) error {
+		k.federation.mu.Lock()
+		defer k.federation.mu.Unlock()
+		if _, ok := k.federation.paths[alias]; ok {
+			return fmt.Errorf("%w: %s is already attached", ErrInvalidArgument, alias)
+		}
+		k.federation.aliases = append(k.federation.aliases, alias)
+		k.federation.paths[alias] = path
+		// make sure the attachment can be read before federated operations
+		// depend on it
+		err := k.federation.run(ctx, k, func(db querier, attached []string) error {
+			_, err := newCountEntriesQuery(QueryParams{Attached: []string{alias}}).queryCount(ctx, db)
+			return err
+		})
+		if err != nil {
+			k.federation.aliases = k.federation.aliases[:len(k.federation.aliases)-1]
+			delete(k.federation.paths, alias)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("keybase.Attach: failed to attach database: %w", err)
+	}
+	return nil
+}
+
+// Detach removes a database added with Attach
+func (k *Keybase) Detach(ctx context.Context, alias string) error {
+	if k.federation == nil {
+		return fmt.Errorf("keybase.Detach: %w: federation is not enabled", ErrUnsupportedOption)
+	}
+	err := k.read(ctx, OpDetach, nil, func(ctx context.Context) error {
+		k.federation.mu.Lock()
+		defer k.federation.mu.Unlock()
+		if _, ok := k.federation.paths[alias]; !ok {
+			return ErrNotFound
+		}
+		k.federation.aliases = slices.DeleteFunc(k.federation.aliases, func(attached string) bool {
+			return attached == alias
+		})
+		delete(k.federation.paths, alias)
+		// other pooled connections detach lazily when they are next synced
+		return k.federation.run(ctx, k, func(querier, []string) error { return nil })
+	})
+	if err != nil {
+		return fmt.Errorf("keybase.Detach: failed to detach database: %w", err)
+	}
+	return nil
+}
+
+// Attached lists the aliases of the databases added with Attach
+func (k *Keybase) Attached() []string {
+	if k.federation == nil {
+		return nil
+	}
+	k.federation.mu.RLock()
+	defer k.federation.mu.RUnlock()
+	return slices.Clone(k.federation.aliases)
+}
+
+// span runs fn against the main database, or against a connection with the
+// attached databases when op is federated
+func (k *Keybase) span(ctx context.Context, op Op, fn func(db querier, attached []string) error) error {
+	if k.federation == nil || !k.federation.ops[op] {
+		return fn(k.conn, nil)
+	}
+	k.federation.mu.RLock()
+	defer k.federation.mu.RUnlock()
+	if len(k.federation.aliases) == 0 {
+		return fn(k.conn, nil)
+	}
+	return k.federation.run(ctx, k, fn)
+}
+
+// run syncs a pooled connection with the attachments and runs fn on it,
+// with the federation lock held by the caller
+func (f *federation) run(ctx context.Context, k *Keybase, fn func(db querier, attached []string) error) error {
+	conn, err := k.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	db := &instrumentedDB{querier: conn, stats: k.stats, table: k.conn.table}
+	attached := map[string]string{}
+	err = newDatabaseListQuery().queryRows(withOperation(ctx, OpDatabaseList), db, func(rows *sql.Rows) error {
+		name, file := "", ""
+		err := rows.Scan(&name, &file)
+		attached[name] = file
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for name, file := range attached {
+		if f.paths[name] != file {
+			err = newDetachQuery(QueryParams{Alias: name}).queryExec(withOperation(ctx, OpDetach), db)
+			if err != nil {
+				return err
+			}
+			delete(attached, name)
+		}
+	}
+	for _, alias := range f.aliases {
+		if _, ok := attached[alias]; !ok {
+			err = newAttachQuery(QueryParams{Alias: alias, Path: f.paths[alias]}).queryExec(withOperation(ctx, OpAttach), db)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return fn(db, slices.Clone(f.aliases))
+}
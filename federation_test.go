@@ -0,0 +1,90 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFederation(t *testing.T) {
+	ctx := context.Background()
+	directory := t.TempDir()
+	for _, namespace := range []string{"east", "west"} {
+		keybase, err := Open(ctx, WithStorage(filepath.Join(directory, namespace+".db")))
+		assert.NoError(t, err)
+		assert.NoError(t, keybase.Put(ctx, namespace, "key0"))
+		assert.NoError(t, keybase.Put(ctx, "shared", "key0"))
+		assert.NoError(t, keybase.Close())
+	}
+
+	keybase, err := Open(ctx, WithFederation())
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.NoError(t, keybase.Put(ctx, "local", "key0"))
+	assert.NoError(t, keybase.Attach(ctx, "east", filepath.Join(directory, "east.db")))
+	assert.NoError(t, keybase.Attach(ctx, "west", filepath.Join(directory, "west.db")))
+	assert.Equal(t, []string{"east", "west"}, keybase.Attached())
+
+	namespaces, err := keybase.GetNamespaces(ctx, true)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"local", "east", "shared", "west"}, namespaces)
+	count, err := keybase.CountEntries(ctx, true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, count)
+	count, err = keybase.CountKeys(ctx, "shared", true, false)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+
+	assert.ErrorIs(t, keybase.Attach(ctx, "east", filepath.Join(directory, "west.db")), ErrInvalidArgument)
+	assert.ErrorIs(t, keybase.Attach(ctx, "main", filepath.Join(directory, "west.db")), ErrInvalidArgument)
+	assert.ErrorIs(t, keybase.Attach(ctx, "north", filepath.Join(directory, "north.db")), ErrInvalidStorage)
+	assert.ErrorIs(t, keybase.Attach(ctx, "north", directory), ErrInvalidStorage)
+
+	assert.NoError(t, keybase.Detach(ctx, "east"))
+	assert.ErrorIs(t, keybase.Detach(ctx, "east"), ErrNotFound)
+	count, err = keybase.CountEntries(ctx, true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	scoped, err := Open(ctx, WithFederation(OpCountEntries))
+	assert.NoError(t, err)
+	defer scoped.Close()
+	assert.NoError(t, scoped.Attach(ctx, "west", filepath.Join(directory, "west.db")))
+	count, err = scoped.CountEntries(ctx, true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	namespaces, err = scoped.GetNamespaces(ctx, true)
+	assert.NoError(t, err)
+	assert.Empty(t, namespaces)
+
+	_, err = Open(ctx, WithFederation(OpPut))
+	assert.ErrorIs(t, err, ErrUnsupportedOption)
+	plain, err := Open(ctx)
+	assert.NoError(t, err)
+	defer plain.Close()
+	assert.ErrorIs(t, plain.Attach(ctx, "west", filepath.Join(directory, "west.db")), ErrUnsupportedOption)
+	assert.ErrorIs(t, plain.Detach(ctx, "west"), ErrUnsupportedOption)
+	assert.Nil(t, plain.Attached())
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Fence advances the generation of a namespace and returns it as a fencing
+// token. Writes made with PutFenced using an older token are rejected, so a
+// worker that has been failed over cannot overwrite its successor's work.
+func (k *Keybase) Fence(ctx context.Context, namespace string) (uint64, error) {
+	var generation int64
+	err := k.write(ctx, OpFence, []string{namespace}, func(ctx context.Context) error {
+		result, err := newFenceQuery(QueryParams{Namespace: namespace}).queryNullInt(ctx, k.conn)
+		generation = result.Int64
+		return err
+	})
+	k.record(ctx, JournalEntry{Op: OpFence, Namespace: namespace}, err)
+	if err != nil {
+		return 0, fmt.Errorf("keybase.Fence: failed to update fence: %w", err)
+	}
+	return uint64(generation), nil
+}
+
+// PutFenced inserts new value if the token is the latest returned by Fence
+// for the namespace, or zero if the namespace was never fenced, failing with
+// ErrStaleFence otherwise
+func (k *Keybase) PutFenced(ctx context.Context, namespace, key string, token uint64) error {
+	err := k.putFenced(ctx, namespace, key, time.Time{}, token)
+	if err != nil {
+		return fmt.Errorf("keybase.PutFenced: failed to insert key: %w", err)
+	}
+	return nil
+}
+
+// PutUntilFenced inserts new value that expires at the given time if the
+// token is the latest of the namespace, as with PutFenced
+func (k *Keybase) PutUntilFenced(ctx context.Context, namespace, key string, until time.Time, token uint64) error {
+	err := k.putFenced(ctx, namespace, key, until, token)
+	if err != nil {
+		return fmt.Errorf("keybase.PutUntilFenced: failed to insert key: %w", err)
+	}
+	return nil
+}
+
+// putFenced checks the token and inserts the entry in the same transaction,
+// so a concurrent Fence either precedes the check or fails the commit
+func (k *Keybase) putFenced(ctx context.Context, namespace, key string, until time.Time, token uint64) error {
+	tx := &KeybaseTx{keybase: k}
+	err := k.write(ctx, OpPutFenced, []string{namespace}, func(ctx context.Context) error {
+		return k.transaction(ctx, func(db querier) error {
+			generation, err := newGetFenceQuery(QueryParams{Namespace: namespace}).queryNullInt(withOperation(ctx, OpGetFence), db)
+			if err != nil {
+				return err
+			}
+			if uint64(generation.Int64) != token {
+				return fmt.Errorf("%w: token %d, latest is %d", ErrStaleFence, token, generation.Int64)
+			}
+			tx.db = db
+			return tx.put(ctx, namespace, key, until)
+		})
+	})
+	for _, entry := range tx.journal {
+		k.record(ctx, entry, err)
+	}
+	return err
+}
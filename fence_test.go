@@ -0,0 +1,62 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFence(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{nil, {WithChecksums()}, {WithEncryption(make([]byte, 32))}} {
+		clock := &fixedClock{now: time.Now()}
+		keybase, err := Open(ctx, append(opts, WithClock(clock))...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		assert.NoError(t, keybase.PutFenced(ctx, "namespace", "key0", 0))
+		first, err := keybase.Fence(ctx, "namespace")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), first)
+		assert.ErrorIs(t, keybase.PutFenced(ctx, "namespace", "key0", 0), ErrStaleFence)
+		assert.NoError(t, keybase.PutFenced(ctx, "namespace", "key1", first))
+
+		second, err := keybase.Fence(ctx, "namespace")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(2), second)
+		assert.ErrorIs(t, keybase.PutFenced(ctx, "namespace", "key2", first), ErrStaleFence)
+		assert.ErrorIs(t, keybase.PutUntilFenced(ctx, "namespace", "key2", clock.now.Add(time.Hour), first), ErrStaleFence)
+		assert.ErrorIs(t, keybase.PutFenced(ctx, "namespace", "key2", second+1), ErrStaleFence)
+		assert.NoError(t, keybase.PutUntilFenced(ctx, "namespace", "key2", clock.now.Add(time.Hour), second))
+		assert.NoError(t, keybase.PutFenced(ctx, "other", "key0", 0))
+
+		keys, err := keybase.GetKeys(ctx, "namespace", true, false, OrderBy(InsertionOrder))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key0", "key1", "key2"}, keys)
+		expiration, err := keybase.GetExpiration(ctx, "namespace", "key2")
+		assert.NoError(t, err)
+		assert.Equal(t, clock.now.Add(time.Hour).UnixMilli(), expiration.UnixMilli())
+	}
+}
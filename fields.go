@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// PutField sets a field on a key, refreshing the expiration shared by all of
+// the key's fields
+func (k *Keybase) PutField(ctx context.Context, namespace, key, field, value string) error {
+	now := k.clock.Now()
+	err := k.write(ctx, OpPutField, []string{namespace}, func(ctx context.Context) error {
+		params := k.params(QueryParams{
+			Namespace:  namespace,
+			Key:        key,
+			Field:      field,
+			Value:      k.encode(value),
+			Expiration: k.expiration(now),
+			Timestamp:  now.UnixMilli(),
+		})
+		return k.transaction(ctx, func(db querier) error {
+			err := newPutFieldQuery(params).queryExec(ctx, db)
+			if err != nil {
+				return err
+			}
+			return newTouchFieldsQuery(params).queryExec(ctx, db)
+		})
+	})
+	k.record(ctx, JournalEntry{Op: OpPutField, Namespace: namespace, Key: key, Field: field, Value: value}, err)
+	if err != nil {
+		return fmt.Errorf("keybase.PutField: failed to set field: %w", err)
+	}
+	return nil
+}
+
+// GetField gets a single active field of a key, returning ErrNotFound if the
+// field is not set
+func (k *Keybase) GetField(ctx context.Context, namespace, key, field string) (string, error) {
+	timestamp := k.clock.Now().UnixMilli()
+	var values []string
+	err := k.read(ctx, OpGetField, []string{namespace}, func(ctx context.Context) (err error) {
+		values, err = newGetFieldQuery(k.params(QueryParams{Namespace: namespace, Key: key, Field: field, Timestamp: timestamp})).queryValues(ctx, k.conn)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("keybase.GetField: failed to query database: %w", err)
+	}
+	if len(values) == 0 {
+		return "", fmt.Errorf("keybase.GetField: %w", ErrNotFound)
+	}
+	value, err := k.decode(values[0])
+	if err != nil {
+		return "", fmt.Errorf("keybase.GetField: %w", err)
+	}
+	return value, nil
+}
+
+// GetFields collects the active fields of a key
+func (k *Keybase) GetFields(ctx context.Context, namespace, key string) (map[string]string, error) {
+	timestamp := k.clock.Now().UnixMilli()
+	fields := map[string]string{}
+	err := k.read(ctx, OpGetFields, []string{namespace}, func(ctx context.Context) error {
+		return newGetFieldsQuery(k.params(QueryParams{Namespace: namespace, Key: key, Timestamp: timestamp})).queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			field, value := "", ""
+			err := rows.Scan(&field, &value)
+			if err != nil || field == valueField {
+				return err
+			}
+			fields[field], err = k.decode(value)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.GetFields: failed to query database: %w", err)
+	}
+	return fields, nil
+}
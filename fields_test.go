@@ -0,0 +1,68 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFields(t *testing.T) {
+	keybase, err := Open(context.Background(), WithTTL(time.Millisecond*100))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	fields, err := keybase.GetFields(context.Background(), "namespace", "key")
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+
+	assert.NoError(t, keybase.PutField(context.Background(), "namespace", "key", "name", "value"))
+	time.Sleep(time.Millisecond * 60)
+	assert.NoError(t, keybase.PutField(context.Background(), "namespace", "key", "region", "eu"))
+	assert.NoError(t, keybase.PutField(context.Background(), "namespace", "key", "name", "other"))
+	assert.NoError(t, keybase.PutField(context.Background(), "namespace", "otherkey", "name", "value"))
+	time.Sleep(time.Millisecond * 60)
+
+	fields, err = keybase.GetFields(context.Background(), "namespace", "key")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"name": "other", "region": "eu"}, fields)
+	value, err := keybase.GetField(context.Background(), "namespace", "key", "region")
+	assert.NoError(t, err)
+	assert.Equal(t, "eu", value)
+	_, err = keybase.GetField(context.Background(), "namespace", "key", "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	time.Sleep(time.Millisecond * 60)
+	fields, err = keybase.GetFields(context.Background(), "namespace", "key")
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(0))
+	defer cancel()
+	assert.Error(t, keybase.PutField(ctx, "namespace", "key", "name", "value"))
+	_, err = keybase.GetFields(ctx, "namespace", "key")
+	assert.Error(t, err)
+	_, err = keybase.GetField(ctx, "namespace", "key", "name")
+	assert.Error(t, err)
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type groupCommitOption struct {
+	maxDelay time.Duration
+	maxBatch int
+}
+
+// groupedPut put waiting for the group commit, which reports its result on
+// done
+type groupedPut struct {
+	batchedPut
+	async bool
+	done  chan error
+}
+
+// groupCommit coalesces concurrent puts into transactions committed by a
+// single goroutine
+type groupCommit struct {
+	mu       *sync.Mutex
+	keybase  *Keybase
+	maxDelay time.Duration
+	maxBatch int
+	pending  []groupedPut
+	closed   bool
+	once     sync.Once
+	wake     chan struct{}
+	flush    chan struct{}
+	closing  chan struct{}
+	done     chan struct{}
+}
+
+// Coalesce concurrent Puts into a single transaction, committed once the
+// oldest has waited maxDelay or maxBatch are waiting. Each Put returns once
+// its transaction commits, trading latency for throughput. Puts with tags or
+// within WithBatch are written as usual.
+func WithWriteBatching(maxDelay time.Duration, maxBatch int) Option {
+	return Option{
+		key: "writebatching",
+		value: groupCommitOption{
+			maxDelay: maxDelay,
+			maxBatch: maxBatch,
+		},
+	}
+}
+
+func newGroupCommit(k *Keybase, option groupCommitOption) *groupCommit {
+	g := &groupCommit{
+		mu:       new(sync.Mutex),
+		keybase:  k,
+		maxDelay: option.maxDelay,
+		maxBatch: option.maxBatch,
+		wake:     make(chan struct{}, 1),
+		flush:    make(chan struct{}, 1),
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go g.run()
+	return g
+}
+
+// put queues the put and waits for its transaction. If the context is done
+// first, the put may still be committed.
+func (g *groupCommit) put(ctx context.Context, put batchedPut) error {
+	done, err := g.enqueue(ctx, groupedPut{batchedPut: put})
+	if err != nil {
+		return err
+	}
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enqueue queues the put, returning the channel that receives the result of
+// its transaction
+func (g *groupCommit) enqueue(ctx context.Context, put groupedPut) (<-chan error, error) {
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+	put.ctx = context.WithoutCancel(ctx)
+	put.done = make(chan error, 1)
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return nil, ErrClosed
+	}
+	g.pending = append(g.pending, put)
+	full := len(g.pending) >= g.maxBatch
+	g.mu.Unlock()
+	signal(g.wake)
+	if full {
+		signal(g.flush)
+	}
+	return put.done, nil
+}
+
+func (g *groupCommit) run() {
+	defer close(g.done)
+	for {
+		select {
+		case <-g.wake:
+		case <-g.flush:
+		case <-g.closing:
+		}
+		timer := time.NewTimer(g.maxDelay)
+	wait:
+		// signals may be consumed by the select above, so the batch size is
+		// checked rather than relying on flush alone
+		for !g.full() {
+			select {
+			case <-timer.C:
+				break wait
+			case <-g.flush:
+			case <-g.closing:
+				break wait
+			}
+		}
+		timer.Stop()
+		for g.commit() {
+		}
+		g.mu.Lock()
+		closed := g.closed
+		g.mu.Unlock()
+		if closed {
+			return
+		}
+	}
+}
+
+func (g *groupCommit) full() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.pending) >= g.maxBatch
+}
+
+// commit commits up to maxBatch of the pending puts, reporting whether more
+// are pending
+func (g *groupCommit) commit() bool {
+	g.mu.Lock()
+	count := min(len(g.pending), g.maxBatch)
+	grouped := g.pending[:count:count]
+	g.pending = g.pending[count:]
+	more := len(g.pending) > 0
+	g.mu.Unlock()
+	if count == 0 {
+		return false
+	}
+	puts := make([]batchedPut, count)
+	for index, put := range grouped {
+		puts[index] = put.batchedPut
+	}
+	err := g.keybase.commitPuts(context.Background(), OpGroupCommit, puts)
+	for _, put := range grouped {
+		if err != nil && put.async {
+			put.done <- fmt.Errorf("keybase.PutAsync: failed to insert key: %w", err)
+			continue
+		}
+		put.done <- err
+	}
+	return more
+}
+
+// PutAsync queues new value for the group commit enabled with
+// WithWriteBatching, returning a channel that receives the result once its
+// transaction commits. The context is only checked when queueing. Close and
+// Shutdown commit outstanding puts before closing.
+func (k *Keybase) PutAsync(ctx context.Context, namespace, key string) <-chan error {
+	if k.group == nil {
+		result := make(chan error, 1)
+		result <- fmt.Errorf("keybase.PutAsync: %w: write batching is not enabled", ErrUnsupportedOption)
+		return result
+	}
+	var done <-chan error
+	err := k.intercept(ctx, OpInfo{Op: OpPut, Write: true, Namespaces: []string{namespace}}, func(ctx context.Context) (err error) {
+		done, err = k.group.enqueue(ctx, groupedPut{batchedPut: batchedPut{namespace: namespace, key: key, now: k.clock.Now()}, async: true})
+		return err
+	})
+	if err != nil {
+		result := make(chan error, 1)
+		result <- fmt.Errorf("keybase.PutAsync: failed to insert key: %w", err)
+		return result
+	}
+	return done
+}
+
+// close commits the pending puts and stops the goroutine. Later puts fail with
+// ErrClosed.
+func (g *groupCommit) close() {
+	if g == nil {
+		return
+	}
+	g.once.Do(func() {
+		g.mu.Lock()
+		g.closed = true
+		g.mu.Unlock()
+		close(g.closing)
+	})
+	<-g.done
+}
+
+// signal notifies a goroutine waiting on the channel without blocking if it
+// was already notified
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
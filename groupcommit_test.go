@@ -0,0 +1,115 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteBatching(t *testing.T) {
+	ctx := context.Background()
+	keybase, err := Open(ctx, WithStorage(filepath.Join(t.TempDir(), "keybase.db")), WithWriteBatching(time.Hour, 4))
+	assert.NoError(t, err)
+
+	// a full batch is committed without waiting for the delay
+	group := sync.WaitGroup{}
+	for index := range 4 {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			assert.NoError(t, keybase.Put(ctx, "namespace", fmt.Sprint("key", index)))
+		}()
+	}
+	group.Wait()
+	count, err := keybase.CountEntries(ctx, false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	// tagged puts are written as usual
+	assert.NoError(t, keybase.Put(ctx, "namespace", "tagged", WithTags("tag")))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, keybase.Put(cancelled, "namespace", "key4"), context.Canceled)
+
+	// pending puts are committed when the keybase is closed
+	put := make(chan error)
+	go func() {
+		put <- keybase.Put(ctx, "namespace", "key5")
+	}()
+	assert.Eventually(t, func() bool {
+		keybase.group.mu.Lock()
+		defer keybase.group.mu.Unlock()
+		return len(keybase.group.pending) == 1
+	}, time.Second, time.Millisecond)
+	assert.NoError(t, keybase.Close())
+	assert.NoError(t, <-put)
+	assert.ErrorIs(t, keybase.Put(ctx, "namespace", "key6"), ErrClosed)
+}
+
+func TestWriteBatchingDelay(t *testing.T) {
+	ctx := context.Background()
+	keybase, err := Open(ctx, WithWriteBatching(time.Millisecond, 100))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+	assert.NoError(t, keybase.PutUntil(ctx, "namespace", "key", time.Now().Add(time.Minute)))
+	count, err := keybase.CountKey(ctx, "namespace", "key", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	_, err = Open(ctx, WithWriteBatching(0, 100))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	_, err = Open(ctx, WithWriteBatching(time.Millisecond, 0))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+}
+
+func TestPutAsync(t *testing.T) {
+	ctx := context.Background()
+	keybase, err := Open(ctx, WithWriteBatching(time.Hour, 100))
+	assert.NoError(t, err)
+	results := []<-chan error{}
+	for index := range 3 {
+		results = append(results, keybase.PutAsync(ctx, "namespace", fmt.Sprint("key", index)))
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, <-keybase.PutAsync(cancelled, "namespace", "key3"), context.Canceled)
+
+	// outstanding puts are committed by Shutdown
+	assert.NoError(t, keybase.Shutdown(ctx))
+	for _, result := range results {
+		assert.NoError(t, <-result)
+	}
+	assert.ErrorIs(t, <-keybase.PutAsync(ctx, "namespace", "key4"), ErrClosed)
+
+	keybase, err = Open(ctx)
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.ErrorIs(t, <-keybase.PutAsync(ctx, "namespace", "key"), ErrUnsupportedOption)
+}
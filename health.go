@@ -0,0 +1,84 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Health state of a keybase reported by HealthCheck
+type Health struct {
+	// Latency time taken by a trivial query
+	Latency       time.Duration
+	TotalEntries  int
+	ActiveEntries int
+	// LastPrune time of the last prune since the keybase was opened, or zero
+	// if it was never pruned
+	LastPrune time.Time
+}
+
+// Ping checks that the database can be queried
+func (k *Keybase) Ping(ctx context.Context) error {
+	err := k.read(ctx, OpPing, nil, func(ctx context.Context) error {
+		_, err := newPingQuery().queryCount(ctx, k.conn)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("keybase.Ping: failed to query database: %w", err)
+	}
+	return nil
+}
+
+// HealthCheck measures the latency of a trivial query and reports the entry
+// counts and last prune, for use in readiness and liveness probes
+func (k *Keybase) HealthCheck(ctx context.Context) (Health, error) {
+	timestamp := k.clock.Now().UnixMilli()
+	health := Health{}
+	err := k.read(ctx, OpHealthCheck, nil, func(ctx context.Context) (err error) {
+		start := time.Now()
+		_, err = newPingQuery().queryCount(withOperation(ctx, OpPing), k.conn)
+		if err != nil {
+			return err
+		}
+		health.Latency = time.Since(start)
+		health.TotalEntries, health.ActiveEntries, err = k.countEntries(ctx, timestamp)
+		return err
+	})
+	if err != nil {
+		return Health{}, fmt.Errorf("keybase.HealthCheck: failed to query database: %w", err)
+	}
+	k.stats.mu.Lock()
+	health.LastPrune = k.stats.prunes.LastRun
+	k.stats.mu.Unlock()
+	return health, nil
+}
+
+// countEntries counts every entry and the active entries
+func (k *Keybase) countEntries(ctx context.Context, timestamp int64) (total int, active int, err error) {
+	total, err = newCountEntriesQuery(k.params(QueryParams{Timestamp: timestamp})).queryCount(ctx, k.conn)
+	if err != nil {
+		return invalidCount, invalidCount, err
+	}
+	active, err = newCountEntriesQuery(k.params(QueryParams{Active: true, Timestamp: timestamp})).queryCount(ctx, k.conn)
+	return total, active, err
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheck(t *testing.T) {
+	ctx := context.Background()
+	clock := &fixedClock{now: time.Now()}
+	keybase, err := Open(ctx, WithClock(clock), WithTTL(time.Minute))
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.Ping(ctx))
+
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+	assert.NoError(t, keybase.PutUntil(ctx, "namespace", "key1", clock.now.Add(-time.Second)))
+	health, err := keybase.HealthCheck(ctx)
+	assert.NoError(t, err)
+	assert.Positive(t, health.Latency)
+	assert.Equal(t, 2, health.TotalEntries)
+	assert.Equal(t, 1, health.ActiveEntries)
+	assert.True(t, health.LastPrune.IsZero())
+
+	start := time.Now()
+	assert.NoError(t, keybase.PruneEntries(ctx))
+	health, err = keybase.HealthCheck(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, health.TotalEntries)
+	assert.False(t, health.LastPrune.Before(start))
+	assert.Equal(t, int64(3), keybase.QueryStats()[OpPing].Queries)
+
+	assert.NoError(t, keybase.Close())
+	assert.ErrorIs(t, keybase.Ping(ctx), ErrClosed)
+	_, err = keybase.HealthCheck(ctx)
+	assert.ErrorIs(t, err, ErrClosed)
+}
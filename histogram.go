@@ -0,0 +1,147 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Bucket number of active entries expiring within a time range
+type Bucket struct {
+	Start time.Time
+	End   time.Time
+	Count int
+}
+
+// ExpirationHistogram groups the active entries of a namespace into equal
+// width buckets spanning from now until the last entry expires
+func (k *Keybase) ExpirationHistogram(ctx context.Context, namespace string, buckets int) ([]Bucket, error) {
+	if buckets <= 0 {
+		return nil, fmt.Errorf("keybase.ExpirationHistogram: %w: bucket count must be positive", ErrInvalidArgument)
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	histogram := []Bucket{}
+	err := k.read(ctx, OpExpirationHistogram, []string{namespace}, func(ctx context.Context) error {
+		last, err := newLastExpirationQuery(k.params(QueryParams{Namespace: namespace, Timestamp: timestamp})).queryNullInt(ctx, k.conn)
+		if err != nil || !last.Valid {
+			return err
+		}
+		width := (last.Int64 - timestamp + int64(buckets) - 1) / int64(buckets)
+		for index := 0; index < buckets; index++ {
+			histogram = append(histogram, Bucket{
+				Start: time.UnixMilli(timestamp + width*int64(index)),
+				End:   time.UnixMilli(timestamp + width*int64(index+1)),
+			})
+		}
+		return newExpirationHistogramQuery(k.params(QueryParams{
+			Namespace: namespace,
+			Timestamp: timestamp,
+			Buckets:   buckets,
+			Width:     width,
+		})).queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			index, count := 0, 0
+			err := rows.Scan(&index, &count)
+			if err == nil {
+				histogram[index].Count = count
+			}
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.ExpirationHistogram: failed to query database: %w", err)
+	}
+	return histogram, nil
+}
+
+// ExpirationDecay counts the active entries, across every namespace, that
+// expire within each of the horizons from now, so that prune volume and
+// renewal load can be anticipated. Counts are cumulative, as an entry
+// expiring within a second also expires within a minute.
+func (k *Keybase) ExpirationDecay(ctx context.Context, horizons []time.Duration) (map[time.Duration]int, error) {
+	if len(horizons) == 0 {
+		return nil, fmt.Errorf("keybase.ExpirationDecay: %w: no horizons", ErrInvalidArgument)
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	deadlines := make([]int64, len(horizons))
+	for i, horizon := range horizons {
+		if horizon <= 0 {
+			return nil, fmt.Errorf("keybase.ExpirationDecay: %w: horizon must be positive", ErrInvalidArgument)
+		}
+		deadlines[i] = timestamp + horizon.Milliseconds()
+	}
+	decay := make(map[time.Duration]int, len(horizons))
+	err := k.read(ctx, OpExpirationDecay, nil, func(ctx context.Context) error {
+		return newExpirationDecayQuery(k.params(QueryParams{Timestamp: timestamp, Deadlines: deadlines})).queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			counts := make([]int, len(horizons))
+			dest := make([]any, len(counts))
+			for i := range counts {
+				dest[i] = &counts[i]
+			}
+			err := rows.Scan(dest...)
+			for i, horizon := range horizons {
+				decay[horizon] = counts[i]
+			}
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.ExpirationDecay: failed to query database: %w", err)
+	}
+	return decay, nil
+}
+
+// KeyFrequency counts the active entries of a key inserted during the window
+// leading up to now, split into equal width buckets from oldest to newest
+func (k *Keybase) KeyFrequency(ctx context.Context, namespace, key string, buckets int, window time.Duration) ([]int, error) {
+	if buckets <= 0 {
+		return nil, fmt.Errorf("keybase.KeyFrequency: %w: bucket count must be positive", ErrInvalidArgument)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("keybase.KeyFrequency: %w: window must be positive", ErrInvalidArgument)
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	width := (window.Milliseconds() + int64(buckets) - 1) / int64(buckets)
+	frequency := make([]int, buckets)
+	err := k.read(ctx, OpKeyFrequency, []string{namespace}, func(ctx context.Context) error {
+		return newKeyFrequencyQuery(k.params(QueryParams{
+			Namespace: namespace,
+			Key:       key,
+			Timestamp: timestamp,
+			Since:     timestamp - width*int64(buckets),
+			Buckets:   buckets,
+			Width:     width,
+		})).queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			index, count := 0, 0
+			err := rows.Scan(&index, &count)
+			if err == nil {
+				frequency[index] = count
+			}
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.KeyFrequency: failed to query database: %w", err)
+	}
+	return frequency, nil
+}
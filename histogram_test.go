@@ -0,0 +1,123 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpirationHistogram(t *testing.T) {
+	keybase, err := Open(context.Background(), WithTTL(time.Minute))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	_, err = keybase.ExpirationHistogram(context.Background(), "namespace", 0)
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	histogram, err := keybase.ExpirationHistogram(context.Background(), "namespace", 4)
+	assert.NoError(t, err)
+	assert.Empty(t, histogram)
+
+	for _, ttl := range []time.Duration{time.Minute, time.Minute, time.Minute * 15, time.Minute * 40} {
+		assert.NoError(t, keybase.Reconfigure(context.Background(), WithTTL(ttl)))
+		assert.NoError(t, keybase.Put(context.Background(), "namespace", "key"))
+	}
+	assert.NoError(t, keybase.Put(context.Background(), "othernamespace", "key"))
+
+	histogram, err = keybase.ExpirationHistogram(context.Background(), "namespace", 4)
+	assert.NoError(t, err)
+	assert.Len(t, histogram, 4)
+	counts := []int{}
+	for _, bucket := range histogram {
+		assert.WithinDuration(t, bucket.Start.Add(time.Minute*10), bucket.End, time.Millisecond)
+		counts = append(counts, bucket.Count)
+	}
+	assert.Equal(t, []int{2, 1, 0, 1}, counts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(0))
+	defer cancel()
+	_, err = keybase.ExpirationHistogram(ctx, "namespace", 4)
+	assert.Error(t, err)
+}
+
+func TestExpirationDecay(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{nil, {WithChecksums()}} {
+		clock := &fixedClock{now: time.UnixMilli(1700000000000)}
+		keybase, err := Open(ctx, append(opts, WithClock(clock))...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		_, err = keybase.ExpirationDecay(ctx, nil)
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+		_, err = keybase.ExpirationDecay(ctx, []time.Duration{time.Second, 0})
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+
+		horizons := []time.Duration{time.Second, 10 * time.Second, time.Minute}
+		decay, err := keybase.ExpirationDecay(ctx, horizons)
+		assert.NoError(t, err)
+		assert.Equal(t, map[time.Duration]int{time.Second: 0, 10 * time.Second: 0, time.Minute: 0}, decay)
+
+		for _, offset := range []time.Duration{-time.Second, time.Second, time.Second, 5 * time.Second, 30 * time.Second, time.Hour} {
+			assert.NoError(t, keybase.PutUntil(ctx, "namespace", "key", clock.now.Add(offset)))
+		}
+		assert.NoError(t, keybase.PutUntil(ctx, "other", "key", clock.now.Add(500*time.Millisecond)))
+
+		decay, err = keybase.ExpirationDecay(ctx, horizons)
+		assert.NoError(t, err)
+		assert.Equal(t, map[time.Duration]int{time.Second: 3, 10 * time.Second: 4, time.Minute: 5}, decay)
+	}
+}
+
+func TestKeyFrequency(t *testing.T) {
+	ctx := context.Background()
+	start := time.UnixMilli(1700000000000)
+	for _, opts := range [][]Option{nil, {WithChecksums()}} {
+		clock := &fixedClock{now: start}
+		keybase, err := Open(ctx, append(opts, WithClock(clock), WithTTL(time.Hour))...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		_, err = keybase.KeyFrequency(ctx, "namespace", "key", 0, time.Minute)
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+		_, err = keybase.KeyFrequency(ctx, "namespace", "key", 4, 0)
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+
+		for _, offset := range []time.Duration{0, 5 * time.Minute, 5 * time.Minute, 15 * time.Minute, 39 * time.Minute, 40 * time.Minute} {
+			clock.now = start.Add(offset)
+			assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+		}
+		assert.NoError(t, keybase.Put(ctx, "namespace", "other"))
+
+		frequency, err := keybase.KeyFrequency(ctx, "namespace", "key", 4, 40*time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{3, 1, 0, 2}, frequency)
+
+		clock.now = start.Add(time.Hour + 10*time.Minute)
+		frequency, err = keybase.KeyFrequency(ctx, "namespace", "key", 2, 70*time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, frequency)
+	}
+}
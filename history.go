@@ -0,0 +1,76 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// KeyVersion a past insertion of a key
+type KeyVersion struct {
+	Version    int64
+	Time       time.Time
+	Expiration time.Time
+}
+
+// Record the latest n insertions of each key, numbered by increasing
+// version, until the key is pruned
+func WithHistory(n int) Option {
+	return Option{
+		key:   "history",
+		value: n,
+	}
+}
+
+// GetKeyHistory lists the recorded insertions of a key, oldest first
+func (k *Keybase) GetKeyHistory(ctx context.Context, namespace, key string) ([]KeyVersion, error) {
+	if k.history == 0 {
+		return nil, fmt.Errorf("keybase.GetKeyHistory: %w: history is not enabled", ErrUnsupportedOption)
+	}
+	versions := []KeyVersion{}
+	err := k.read(ctx, OpGetKeyHistory, []string{namespace}, func(ctx context.Context) error {
+		return newGetKeyHistoryQuery(k.params(QueryParams{Namespace: namespace, Key: key})).queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			version := KeyVersion{}
+			var inserted, expiration int64
+			err := rows.Scan(&version.Version, &inserted, &expiration)
+			version.Time = time.UnixMilli(inserted)
+			version.Expiration = time.UnixMilli(expiration)
+			versions = append(versions, version)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.GetKeyHistory: failed to query database: %w", err)
+	}
+	return versions, nil
+}
+
+// versioned records an insertion in the history of its key when enabled
+func (k *Keybase) versioned(ctx context.Context, db querier, params QueryParams) error {
+	if k.history == 0 {
+		return nil
+	}
+	params.Limit = k.history
+	return newRecordHistoryQuery(params).queryExec(withOperation(ctx, OpRecordHistory), db)
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyHistory(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{{}, {WithOverflow(8), WithMaxEntries(100, EvictOldest)}, {WithEncryption(make([]byte, 32))}} {
+		start := time.Now().Truncate(time.Millisecond)
+		clock := &fixedClock{now: start}
+		keybase, err := Open(ctx, append(opts, WithClock(clock), WithTTL(time.Minute), WithHistory(3))...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		assert.NoError(t, keybase.Put(ctx, "namespace", "overflowing0"))
+		clock.now = clock.now.Add(time.Second)
+		assert.NoError(t, keybase.PutUntil(ctx, "namespace", "overflowing0", start.Add(time.Hour)))
+		clock.now = clock.now.Add(time.Second)
+		inserted, err := keybase.PutIfAbsent(ctx, "namespace", "overflowing0")
+		assert.NoError(t, err)
+		assert.False(t, inserted)
+		batch, flush := keybase.WithBatch(ctx)
+		assert.NoError(t, keybase.Put(batch, "namespace", "overflowing0"))
+		assert.NoError(t, flush())
+		clock.now = clock.now.Add(time.Second)
+		assert.NoError(t, keybase.Tx(ctx, func(tx *KeybaseTx) error {
+			return tx.Put(ctx, "namespace", "overflowing0")
+		}))
+
+		versions, err := keybase.GetKeyHistory(ctx, "namespace", "overflowing0")
+		assert.NoError(t, err)
+		assert.Equal(t, []KeyVersion{
+			{Version: 2, Time: start.Add(time.Second), Expiration: start.Add(time.Hour)},
+			{Version: 3, Time: start.Add(2 * time.Second), Expiration: start.Add(2*time.Second + time.Minute)},
+			{Version: 4, Time: start.Add(3 * time.Second), Expiration: start.Add(3*time.Second + time.Minute)},
+		}, versions)
+		versions, err = keybase.GetKeyHistory(ctx, "namespace", "key1")
+		assert.NoError(t, err)
+		assert.Empty(t, versions)
+
+		inserted, err = keybase.PutIfAbsent(ctx, "namespace", "key1")
+		assert.NoError(t, err)
+		assert.True(t, inserted)
+		clock.now = start.Add(2 * time.Hour)
+		assert.NoError(t, keybase.PruneEntries(ctx))
+		versions, err = keybase.GetKeyHistory(ctx, "namespace", "overflowing0")
+		assert.NoError(t, err)
+		assert.Empty(t, versions)
+		assert.NoError(t, keybase.Put(ctx, "namespace", "overflowing0"))
+		versions, err = keybase.GetKeyHistory(ctx, "namespace", "overflowing0")
+		assert.NoError(t, err)
+		assert.Equal(t, []KeyVersion{{Version: 1, Time: clock.now, Expiration: clock.now.Add(time.Minute)}}, versions)
+	}
+
+	keybase, err := Open(ctx)
+	assert.NoError(t, err)
+	defer keybase.Close()
+	_, err = keybase.GetKeyHistory(ctx, "namespace", "key0")
+	assert.ErrorIs(t, err, ErrUnsupportedOption)
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SetNamespaceImmutable makes a namespace write-once, so that putting a key
+// it already holds fails with ErrImmutable, even when the stored entries have
+// expired but not been pruned. New keys can still be put, which suits
+// append-only ledgers. A namespace cannot be made mutable again.
+func (k *Keybase) SetNamespaceImmutable(ctx context.Context, namespace string) error {
+	err := k.write(ctx, OpSetImmutable, []string{namespace}, func(ctx context.Context) error {
+		return newSetImmutableQuery(QueryParams{Namespace: namespace}).queryExec(ctx, k.conn)
+	})
+	k.record(ctx, JournalEntry{Op: OpSetImmutable, Namespace: namespace}, err)
+	if err != nil {
+		return fmt.Errorf("keybase.SetNamespaceImmutable: failed to update database: %w", err)
+	}
+	return nil
+}
+
+// immutableViolation message raised by the immutable namespace trigger
+const immutableViolation = "namespace is immutable"
+
+// immutable replaces the error raised by the immutable namespace trigger with
+// ErrImmutable
+func immutable(err error) error {
+	if err != nil && strings.Contains(err.Error(), immutableViolation) {
+		return ErrImmutable
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetNamespaceImmutable(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{nil, {WithOverflow(32)}, {WithEncryption(make([]byte, 32))}} {
+		clock := &fixedClock{now: time.Now()}
+		keybase, err := Open(ctx, append(opts, WithClock(clock))...)
+		assert.NoError(t, err)
+		long := "key" + strings.Repeat("x", 64)
+
+		assert.NoError(t, keybase.Put(ctx, "ledger", "key0"))
+		assert.NoError(t, keybase.Put(ctx, "ledger", long))
+		assert.NoError(t, keybase.PutUntil(ctx, "ledger", "expired", clock.now.Add(-time.Second)))
+		assert.NoError(t, keybase.SetNamespaceImmutable(ctx, "ledger"))
+		assert.NoError(t, keybase.SetNamespaceImmutable(ctx, "ledger"))
+
+		assert.ErrorIs(t, keybase.Put(ctx, "ledger", "key0"), ErrImmutable)
+		assert.ErrorIs(t, keybase.Put(ctx, "ledger", long), ErrImmutable)
+		assert.ErrorIs(t, keybase.PutUntil(ctx, "ledger", "key0", clock.now.Add(time.Hour)), ErrImmutable)
+		_, err = keybase.PutIfAbsent(ctx, "ledger", "expired")
+		assert.ErrorIs(t, err, ErrImmutable)
+		inserted, err := keybase.PutIfAbsent(ctx, "ledger", "key0")
+		assert.NoError(t, err)
+		assert.False(t, inserted)
+		assert.ErrorIs(t, keybase.Tx(ctx, func(tx *KeybaseTx) error {
+			return tx.Put(ctx, "ledger", "key0")
+		}), ErrImmutable)
+
+		batch, flush := keybase.WithBatch(ctx)
+		assert.NoError(t, keybase.Put(batch, "ledger", "key1"))
+		assert.NoError(t, keybase.Put(batch, "ledger", "key1"))
+		assert.ErrorIs(t, flush(), ErrImmutable)
+
+		assert.NoError(t, keybase.Put(ctx, "ledger", "key1"))
+		assert.NoError(t, keybase.Put(ctx, "other", "key0"))
+		assert.NoError(t, keybase.Put(ctx, "other", "key0"))
+		count, err := keybase.CountKey(ctx, "ledger", "key0", false)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.NoError(t, keybase.Close())
+	}
+}
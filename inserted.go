@@ -0,0 +1,46 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GetKeysInsertedBetween collects the keys of a namespace that were inserted
+// at or after from and before to, regardless of whether they have expired.
+// Keys are returned once, in the order they first arrived.
+func (k *Keybase) GetKeysInsertedBetween(ctx context.Context, namespace string, from, to time.Time) ([]string, error) {
+	var keys []string
+	err := k.read(ctx, OpGetKeysInserted, []string{namespace}, func(ctx context.Context) error {
+		values, err := newGetKeysInsertedQuery(k.params(QueryParams{Namespace: namespace, Since: from.UnixMilli(), Until: to.UnixMilli()})).queryValues(ctx, k.conn)
+		if err != nil {
+			return err
+		}
+		keys, err = k.decodeAll(values)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.GetKeysInsertedBetween: failed to query database: %w", err)
+	}
+	return keys, nil
+}
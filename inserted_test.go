@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetKeysInsertedBetween(t *testing.T) {
+	ctx := context.Background()
+	start := time.UnixMilli(1700000000000)
+	for _, opts := range [][]Option{nil, {WithEncryption(make([]byte, 32))}, {WithChecksums()}} {
+		clock := &fixedClock{now: start}
+		keybase, err := Open(ctx, append(opts, WithClock(clock), WithTTL(time.Minute))...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		assert.NoError(t, keybase.Put(ctx, "namespace", "old"))
+		clock.now = start.Add(10 * time.Minute)
+		assert.NoError(t, keybase.Put(ctx, "namespace", "key2"))
+		assert.NoError(t, keybase.Put(ctx, "other", "key3"))
+		clock.now = start.Add(11 * time.Minute)
+		assert.NoError(t, keybase.Put(ctx, "namespace", "key1"))
+		assert.NoError(t, keybase.Put(ctx, "namespace", "key2"))
+
+		keys, err := keybase.GetKeysInsertedBetween(ctx, "namespace", start.Add(5*time.Minute), clock.now.Add(time.Millisecond))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key2", "key1"}, keys)
+
+		clock.now = start.Add(time.Hour)
+		keys, err = keybase.GetKeysInsertedBetween(ctx, "namespace", start, start.Add(10*time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"old"}, keys)
+		keys, err = keybase.GetKeysInsertedBetween(ctx, "namespace", clock.now, clock.now.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Empty(t, keys)
+	}
+}
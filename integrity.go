@@ -0,0 +1,83 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Verify the integrity of the storage when opening it, failing with
+// ErrIntegrity if it is corrupt
+func WithIntegrityCheckOnOpen() Option {
+	return Option{
+		key: "integritycheck",
+	}
+}
+
+// VerifyIntegrity checks the storage file for corruption and that every table
+// of the schema exists, returning ErrIntegrity with the problems found. Unlike
+// VerifyChecksums, it checks the database structure rather than entries.
+func (k *Keybase) VerifyIntegrity(ctx context.Context) error {
+	err := k.read(ctx, OpIntegrityCheck, nil, func(ctx context.Context) error {
+		return verifyIntegrity(ctx, k.conn, len(k.pending) == 0)
+	})
+	if err != nil {
+		return fmt.Errorf("keybase.VerifyIntegrity: %w", err)
+	}
+	return nil
+}
+
+// verifyIntegrity runs the integrity check and, when the schema is up to
+// date, checks that its tables exist
+func verifyIntegrity(ctx context.Context, conn *instrumentedDB, migrated bool) error {
+	problems := []string{}
+	err := newIntegrityCheckQuery().queryRows(withOperation(ctx, OpIntegrityCheck), conn, func(rows *sql.Rows) error {
+		problem := ""
+		err := rows.Scan(&problem)
+		if problem != "ok" {
+			problems = append(problems, problem)
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check integrity: %w", err)
+	}
+	if migrated {
+		tables, err := newListTablesQuery().queryValues(withOperation(ctx, OpListTables), conn)
+		if err != nil {
+			return fmt.Errorf("failed to list tables: %w", err)
+		}
+		for _, table := range schemaTables {
+			table = renameTables(table, conn.table)
+			if !slices.Contains(tables, table) {
+				problems = append(problems, "missing table "+table)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrIntegrity, strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyIntegrity(t *testing.T) {
+	ctx := context.Background()
+	storageDirectory, _ := os.MkdirTemp(os.TempDir(), "keybase-*")
+	defer os.RemoveAll(storageDirectory)
+	storagePath := path.Join(storageDirectory, "keybase.db")
+
+	keybase, err := Open(ctx, WithStorage(storagePath), WithIntegrityCheckOnOpen())
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+	assert.NoError(t, keybase.VerifyIntegrity(ctx))
+	sessions, err := Open(ctx, WithStorage(storagePath), WithTableName("sessions"), WithIntegrityCheckOnOpen())
+	assert.NoError(t, err)
+	assert.NoError(t, sessions.VerifyIntegrity(ctx))
+
+	assert.NoError(t, (&dbtx{query: "DROP TABLE sessions_fences"}).queryExec(ctx, sessions.db))
+	err = sessions.VerifyIntegrity(ctx)
+	assert.ErrorIs(t, err, ErrIntegrity)
+	assert.ErrorContains(t, err, "missing table sessions_fences")
+	assert.NoError(t, keybase.VerifyIntegrity(ctx))
+	assert.NoError(t, sessions.Close())
+	assert.NoError(t, keybase.Close())
+
+	// the schema version is recorded, so the dropped table is not recreated
+	_, err = Open(ctx, WithStorage(storagePath), WithTableName("sessions"), WithIntegrityCheckOnOpen())
+	assert.ErrorIs(t, err, ErrIntegrity)
+	sessions, err = Open(ctx, WithStorage(storagePath), WithTableName("sessions"))
+	assert.NoError(t, err)
+	assert.NoError(t, sessions.Close())
+
+	assert.ErrorIs(t, keybase.VerifyIntegrity(ctx), ErrClosed)
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+)
+
+// OpInfo describes an operation passed to interceptors
+type OpInfo struct {
+	Op Op
+	// Write reports whether the operation modifies the keybase
+	Write bool
+	// Namespaces touched by the operation, or nil when it is not limited to
+	// any namespace
+	Namespaces []string
+}
+
+// Interceptor wraps an operation, which runs when next is called. The context
+// passed to next is used by the operation.
+type Interceptor func(ctx context.Context, op OpInfo, next func(ctx context.Context) error) error
+
+// Wrap every operation with an interceptor, such as for logging, tracing or
+// access checks. Interceptors run in the order they are given, the first one
+// outermost, and also wrap background work such as auto prune.
+func WithInterceptor(interceptor Interceptor) Option {
+	return Option{
+		key:   "interceptor",
+		value: interceptor,
+	}
+}
+
+// internalOps run on behalf of another operation, so they are not intercepted
+var internalOps = map[Op]bool{
+	OpAudit:       true,
+	OpPollChanges: true,
+	OpExpvar:      true,
+	OpAlarm:       true,
+	// the puts of a group commit are intercepted as they are queued
+	OpGroupCommit: true,
+}
+
+// intercept runs fn within the interceptors, once it is authorized
+func (k *Keybase) intercept(ctx context.Context, op OpInfo, fn func(ctx context.Context) error) error {
+	if internalOps[op.Op] {
+		return fn(ctx)
+	}
+	next := func(ctx context.Context) error {
+		err := k.authorize(ctx, op.Op, op.Namespaces)
+		if err != nil {
+			return err
+		}
+		return fn(ctx)
+	}
+	for i := len(k.config.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := k.config.interceptors[i], next
+		next = func(ctx context.Context) error {
+			return interceptor(ctx, op, inner)
+		}
+	}
+	return next(ctx)
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type interceptedKey struct{}
+
+func TestWithInterceptor(t *testing.T) {
+	ctx := context.Background()
+	calls := []string{}
+	ops := []OpInfo{}
+	denied := errors.New("denied")
+	keybase, err := Open(ctx,
+		WithAuditLog(),
+		WithInterceptor(func(ctx context.Context, op OpInfo, next func(ctx context.Context) error) error {
+			calls = append(calls, "outer")
+			ops = append(ops, op)
+			if op.Op == OpClearEntries {
+				return denied
+			}
+			return next(context.WithValue(ctx, interceptedKey{}, op.Op))
+		}),
+		WithInterceptor(func(ctx context.Context, op OpInfo, next func(ctx context.Context) error) error {
+			calls = append(calls, "inner")
+			assert.Equal(t, op.Op, ctx.Value(interceptedKey{}))
+			return next(ctx)
+		}),
+	)
+	assert.NoError(t, err)
+
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+	_, err = keybase.CopyNamespace(ctx, "namespace", "copy", false)
+	assert.NoError(t, err)
+	_, err = keybase.CountEntries(ctx, true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []OpInfo{
+		{Op: OpPut, Write: true, Namespaces: []string{"namespace"}},
+		{Op: OpCopyNamespace, Write: true, Namespaces: []string{"namespace", "copy"}},
+		{Op: OpCountEntries},
+	}, ops)
+	assert.Equal(t, []string{"outer", "inner", "outer", "inner", "outer", "inner"}, calls)
+
+	// an interceptor can stop the operation by not calling next
+	assert.ErrorIs(t, keybase.ClearEntries(ctx), denied)
+	count, err := keybase.CountEntries(ctx, true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	assert.NoError(t, keybase.Close())
+	ops = nil
+	assert.ErrorIs(t, keybase.Put(ctx, "namespace", "key"), ErrClosed)
+	assert.Len(t, ops, 1)
+}
+
+func TestInterceptorGroupCommit(t *testing.T) {
+	ctx := context.WithValue(context.Background(), interceptedKey{}, "caller")
+	ops := []OpInfo{}
+	keybase, err := Open(ctx,
+		WithStorage(filepath.Join(t.TempDir(), "keybase.db")),
+		WithWriteBatching(time.Hour, 2),
+		WithInterceptor(func(ctx context.Context, op OpInfo, next func(ctx context.Context) error) error {
+			assert.Equal(t, "caller", ctx.Value(interceptedKey{}))
+			ops = append(ops, op)
+			return next(ctx)
+		}),
+	)
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	// queued puts are intercepted with the caller's context
+	done := keybase.PutAsync(ctx, "async", "key")
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+	assert.NoError(t, <-done)
+	assert.Equal(t, []OpInfo{
+		{Op: OpPut, Write: true, Namespaces: []string{"async"}},
+		{Op: OpPut, Write: true, Namespaces: []string{"namespace"}},
+	}, ops)
+}
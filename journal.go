@@ -0,0 +1,235 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// JournalEntry state-changing call recorded by WithJournal, along with the
+// error it returned
+type JournalEntry struct {
+	Time      time.Time        `json:"time"`
+	Op        Op               `json:"op"`
+	Namespace string           `json:"namespace,omitempty"`
+	Target    string           `json:"target,omitempty"`
+	Pattern   string           `json:"pattern,omitempty"`
+	Key       string           `json:"key,omitempty"`
+	Field     string           `json:"field,omitempty"`
+	Value     string           `json:"value,omitempty"`
+	Tags      []string         `json:"tags,omitempty"`
+	Delta     int64            `json:"delta,omitempty"`
+	Limit     int              `json:"limit,omitempty"`
+	Policy    CompactionPolicy `json:"policy,omitempty"`
+	TTL       time.Duration    `json:"ttl,omitempty"`
+	Interval  time.Duration    `json:"interval,omitempty"`
+	Overwrite bool             `json:"overwrite,omitempty"`
+	Handle    string           `json:"handle,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+type journal struct {
+	mu      *sync.Mutex
+	encoder *json.Encoder
+}
+
+// Record every state-changing call as a line of JSON, so the sequence can be
+// reproduced with ReplayJournal. Keys and values are written in plain text,
+// even when encryption is enabled.
+func WithJournal(w io.Writer) Option {
+	return Option{
+		key:   "journal",
+		value: w,
+	}
+}
+
+func newJournal(w io.Writer) *journal {
+	return &journal{
+		mu:      new(sync.Mutex),
+		encoder: json.NewEncoder(w),
+	}
+}
+
+// record appends an entry to the journal. Recording is best effort, so a
+// failing writer never fails the call being recorded.
+func (j *journal) record(entry JournalEntry, err error) {
+	if j == nil {
+		return
+	}
+	entry.Time = time.Now()
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_ = j.encoder.Encode(entry)
+}
+
+// ReplayJournal replays the calls recorded by WithJournal against a keybase,
+// keeping the recorded spacing between calls so entries expire as they did
+// originally. A call that fails is an error unless it also failed when it was
+// recorded.
+func ReplayJournal(ctx context.Context, keybase *Keybase, r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	replayer := &replayer{
+		keybase: keybase,
+		claims:  map[string]*Claim{},
+		leases:  map[string]*Lease{},
+	}
+	var start, first time.Time
+	for index := 0; ; index++ {
+		entry := JournalEntry{}
+		err := decoder.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("keybase.ReplayJournal: failed to decode entry %d: %w", index, err)
+		}
+		if index == 0 {
+			start, first = time.Now(), entry.Time
+		}
+		timer := time.NewTimer(time.Until(start.Add(entry.Time.Sub(first))))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("keybase.ReplayJournal: %w", ctx.Err())
+		case <-timer.C:
+		}
+		err = replayer.replay(ctx, entry)
+		if err != nil && entry.Error == "" {
+			return fmt.Errorf("keybase.ReplayJournal: entry %d (%s): %w", index, entry.Op, err)
+		}
+	}
+}
+
+// replayer replays the entries of a journal, keeping the claims and leases it
+// took by the handle they were recorded with, so later entries extend or
+// release the same claim or lease
+type replayer struct {
+	keybase *Keybase
+	claims  map[string]*Claim
+	leases  map[string]*Lease
+}
+
+func (r *replayer) replay(ctx context.Context, entry JournalEntry) (err error) {
+	keybase := r.keybase
+	switch entry.Op {
+	case OpPut:
+		err = keybase.Put(ctx, entry.Namespace, entry.Key, WithTags(entry.Tags...))
+	case OpPutUntil:
+		err = keybase.PutUntil(ctx, entry.Namespace, entry.Key, keybase.clock.Now().Add(entry.TTL))
+	case OpPutIfAbsent:
+		_, err = keybase.PutIfAbsent(ctx, entry.Namespace, entry.Key)
+	case OpSeen:
+		_, err = keybase.Seen(ctx, entry.Namespace, entry.Key)
+	case OpAllow:
+		_, err = NewLimiter(keybase, entry.Namespace, entry.Limit, entry.TTL).Allow(ctx, entry.Key)
+	case OpIncrement:
+		_, err = keybase.Increment(ctx, entry.Namespace, entry.Key, entry.Delta)
+	case OpDeleteKey:
+		err = keybase.Tx(ctx, func(tx *KeybaseTx) error {
+			return tx.Delete(ctx, entry.Namespace, entry.Key)
+		})
+	case OpClaim:
+		var claim *Claim
+		claim, err = keybase.Claim(ctx, entry.Namespace, entry.Key, entry.TTL)
+		if err == nil {
+			r.claims[entry.Handle] = claim
+		}
+	case OpExtendClaim:
+		claim, ok := r.claims[entry.Handle]
+		if !ok {
+			return ErrClaimLost
+		}
+		err = claim.Extend(ctx, entry.TTL)
+	case OpReleaseClaim:
+		claim, ok := r.claims[entry.Handle]
+		if ok {
+			delete(r.claims, entry.Handle)
+			err = claim.Release(ctx)
+		}
+	case OpAcquireLease:
+		var lease *Lease
+		lease, err = keybase.AcquireLease(ctx, entry.Namespace, entry.Key, entry.TTL)
+		if err == nil {
+			r.leases[entry.Handle] = lease
+		}
+	case OpRenewLease:
+		lease, ok := r.leases[entry.Handle]
+		if !ok {
+			return ErrLeaseLost
+		}
+		err = lease.Renew(ctx)
+	case OpReleaseLease:
+		lease, ok := r.leases[entry.Handle]
+		if ok {
+			delete(r.leases, entry.Handle)
+			err = lease.Release(ctx)
+		}
+	case OpExpireMatch:
+		_, err = keybase.ExpireMatch(ctx, entry.Namespace, entry.Pattern, keybase.clock.Now().Add(entry.TTL))
+	case OpDeleteMatch:
+		_, err = keybase.DeleteMatch(ctx, entry.Namespace, entry.Pattern, false)
+	case OpPutField:
+		err = keybase.PutField(ctx, entry.Namespace, entry.Key, entry.Field, entry.Value)
+	case OpPruneEntries:
+		err = keybase.PruneEntries(ctx)
+	case OpClearEntries:
+		err = keybase.ClearEntries(ctx)
+	case OpPurgeArchive:
+		_, err = keybase.PurgeArchive(ctx, entry.Interval)
+	case OpPurgeTombstones:
+		_, err = keybase.PurgeTombstones(ctx, entry.Interval)
+	case OpSetImmutable:
+		err = keybase.SetNamespaceImmutable(ctx, entry.Namespace)
+	case OpFence:
+		_, err = keybase.Fence(ctx, entry.Namespace)
+	case OpCopyNamespace:
+		_, err = keybase.CopyNamespace(ctx, entry.Namespace, entry.Target, entry.Overwrite)
+	case OpPruneNamespace:
+		err = keybase.PruneNamespace(ctx, entry.Namespace)
+	case OpClearNamespace:
+		err = keybase.ClearNamespace(ctx, entry.Namespace)
+	case OpCompactDuplicates:
+		_, err = keybase.CompactDuplicates(ctx, entry.Policy)
+	case OpMoveToColdTier:
+		_, err = keybase.MoveToColdTier(ctx)
+	case OpReconfigure:
+		opts := []Option{}
+		if entry.TTL > 0 {
+			opts = append(opts, WithTTL(entry.TTL))
+		}
+		if entry.Interval > 0 {
+			opts = append(opts, WithAutoPrune(entry.Interval))
+		}
+		err = keybase.Reconfigure(ctx, opts...)
+	default:
+		err = fmt.Errorf("%w: cannot replay %s", ErrInvalidArgument, entry.Op)
+	}
+	return err
+}
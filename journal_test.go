@@ -0,0 +1,158 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	buffer := bytes.Buffer{}
+	keybase, err := Open(context.Background(), WithJournal(&buffer), WithMaxEntries(2, RejectWrites))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	ctx := context.Background()
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+	assert.NoError(t, keybase.Reconfigure(ctx, WithTTL(time.Minute)))
+	_, err = keybase.PutIfAbsent(ctx, "namespace", "key1")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, keybase.Put(ctx, "namespace", "key2"), ErrQuotaExceeded)
+	_, err = keybase.Increment(ctx, "namespace", "counter", 3)
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.PutField(ctx, "namespace", "key0", "field", "value"))
+	_, err = keybase.CompactDuplicates(ctx, KeepEarliest)
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.PruneEntries(ctx))
+	_, err = keybase.GetKeys(ctx, "namespace", true, true)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(t, lines, 8)
+	assert.Contains(t, lines[1], `"ttl":60000000000`)
+	assert.Contains(t, lines[3], `"error":`)
+
+	replayed, err := Open(context.Background(), WithMaxEntries(2, RejectWrites))
+	assert.NoError(t, err)
+	defer replayed.Close()
+	assert.NoError(t, ReplayJournal(ctx, replayed, strings.NewReader(buffer.String())))
+	keys, err := replayed.GetKeys(ctx, "namespace", true, true)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"key0", "key1"}, keys)
+	ttl, err := replayed.GetTTL(ctx, "namespace", "key1")
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Second*30)
+	counter, err := replayed.GetCounter(ctx, "namespace", "counter")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), counter)
+	fields, err := replayed.GetFields(ctx, "namespace", "key0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"field": "value"}, fields)
+}
+
+func TestJournalClaims(t *testing.T) {
+	buffer := bytes.Buffer{}
+	keybase, err := Open(context.Background(), WithJournal(&buffer))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	ctx := context.Background()
+	claim, err := keybase.Claim(ctx, "namespace", "key0", time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, claim.Extend(ctx, time.Hour))
+	assert.NoError(t, claim.Release(ctx))
+	_, err = keybase.Claim(ctx, "namespace", "key0", time.Minute)
+	assert.NoError(t, err)
+	_, err = keybase.Claim(ctx, "namespace", "key0", time.Minute)
+	assert.ErrorIs(t, err, ErrClaimHeld)
+	_, err = keybase.Claim(ctx, "namespace", "key1", time.Minute)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(t, lines, 6)
+	assert.Contains(t, lines[0], `"handle":`)
+	assert.Contains(t, lines[4], `"error":`)
+
+	replayed, err := Open(context.Background())
+	assert.NoError(t, err)
+	defer replayed.Close()
+	assert.NoError(t, ReplayJournal(ctx, replayed, strings.NewReader(buffer.String())))
+	for _, key := range []string{"key0", "key1"} {
+		count, err := replayed.CountKey(ctx, "namespace", key, true)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+	}
+	_, err = replayed.Claim(ctx, "namespace", "key0", time.Minute)
+	assert.ErrorIs(t, err, ErrClaimHeld)
+}
+
+func TestJournalLeases(t *testing.T) {
+	buffer := bytes.Buffer{}
+	keybase, err := Open(context.Background(), WithJournal(&buffer))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	ctx := context.Background()
+	lease, err := keybase.AcquireLease(ctx, "namespace", "key0", time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, lease.Renew(ctx))
+	assert.NoError(t, lease.Release(ctx))
+	_, err = keybase.AcquireLease(ctx, "namespace", "key0", time.Minute)
+	assert.NoError(t, err)
+	_, err = keybase.AcquireLease(ctx, "namespace", "key0", time.Minute)
+	assert.ErrorIs(t, err, ErrLeaseHeld)
+	// the released lease is a different owner, so it leaves the later one
+	assert.NoError(t, lease.Release(ctx))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(t, lines, 6)
+	assert.Contains(t, lines[0], `"handle":`)
+	assert.Contains(t, lines[4], `"error":`)
+
+	replayed, err := Open(context.Background())
+	assert.NoError(t, err)
+	defer replayed.Close()
+	assert.NoError(t, ReplayJournal(ctx, replayed, strings.NewReader(buffer.String())))
+	_, err = replayed.AcquireLease(ctx, "namespace", "key0", time.Minute)
+	assert.ErrorIs(t, err, ErrLeaseHeld)
+}
+
+func TestReplayJournal(t *testing.T) {
+	keybase, err := Open(context.Background())
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	assert.Error(t, ReplayJournal(context.Background(), keybase, strings.NewReader("{")))
+	assert.ErrorIs(t, ReplayJournal(context.Background(), keybase, strings.NewReader(`{"op":"GetKeys"}`)), ErrInvalidArgument)
+	assert.NoError(t, ReplayJournal(context.Background(), keybase, strings.NewReader(`{"op":"GetKeys","error":"failed"}`)))
+
+	journal := `{"time":"2024-01-01T00:00:00Z","op":"ClearEntries"}
+{"time":"2024-01-01T00:01:00Z","op":"ClearEntries"}`
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	assert.ErrorIs(t, ReplayJournal(ctx, keybase, strings.NewReader(journal)), context.DeadlineExceeded)
+}
@@ -24,27 +24,91 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "modernc.org/sqlite"
 )
 
 const (
-	defaultTTL     time.Duration = time.Second * 10
-	defaultStorage string        = ":memory:"
-	invalidCount   int           = -1
+	defaultTTL             time.Duration = time.Second * 10
+	defaultStorage         string        = ":memory:"
+	defaultPruneInterval   time.Duration = time.Minute
+	defaultCompactInterval time.Duration = time.Hour
+	defaultBusyTimeout     time.Duration = time.Second * 5
+	defaultChangeInterval  time.Duration = time.Second
+	defaultPruneBatchSize  int           = 10000
+	invalidCount           int           = -1
 )
 
 type options struct {
-	storage string
-	ttl     time.Duration
+	storage         string
+	ttl             time.Duration
+	autoPrune       bool
+	pruneInterval   time.Duration
+	compact         bool
+	compactInterval time.Duration
+	compactPolicy   CompactionPolicy
+	coldTier        bool
+	coldThreshold   time.Duration
+	coldInterval    time.Duration
+	pragmas         map[string]string
+	serialize       bool
+	createDirs      bool
+	cacheSize       int
+	overflow        int
+	checksums       bool
+	encryption      []byte
+	readOnly        bool
+	maxEntries      int
+	eviction        EvictionPolicy
+	journal         io.Writer
+	slo             *sloOption
+	export          *exportOption
+	migration       MigrationPolicy
+	archive         bool
+	tombstones      bool
+	audit           bool
+	federation      []Op
+	clock           Clock
+	federated       bool
+	history         int
+	jitter          float64
+	shutdownPrune   bool
+	expvar          bool
+	expvarPrefix    string
+	autoCompact     float64
+	writeBatching   *groupCommitOption
+	changePolling   bool
+	changeInterval  time.Duration
+	queryTimeout    time.Duration
+	table           string
+	db              *sql.DB
+	integrityCheck  bool
+	interceptors    []Interceptor
+	authorizer      Authorizer
+	alarm           *alarmOption
 }
 
 func parseOptions(opts ...Option) *options {
 	config := &options{
-		storage: defaultStorage,
-		ttl:     defaultTTL,
+		storage:         defaultStorage,
+		ttl:             defaultTTL,
+		pruneInterval:   defaultPruneInterval,
+		compactInterval: defaultCompactInterval,
+		changeInterval:  defaultChangeInterval,
+		compactPolicy:   KeepLatest,
+		pragmas:         map[string]string{},
+		clock:           systemClock{},
+		table:           defaultTable,
 	}
 	for _, opt := range opts {
 		switch opt.key {
@@ -52,6 +116,92 @@ func parseOptions(opts ...Option) *options {
 			config.ttl = opt.value.(time.Duration)
 		case "storage":
 			config.storage = opt.value.(string)
+		case "autoprune":
+			config.autoPrune = true
+			config.pruneInterval = opt.value.(time.Duration)
+		case "compaction":
+			compaction := opt.value.(compactionOption)
+			config.compact = true
+			config.compactInterval = compaction.interval
+			config.compactPolicy = compaction.policy
+		case "coldtier":
+			tiering := opt.value.(coldTierOption)
+			config.coldTier = true
+			config.coldThreshold = tiering.threshold
+			config.coldInterval = tiering.interval
+		case "jitter":
+			config.jitter = opt.value.(float64)
+		case "querytimeout":
+			config.queryTimeout = opt.value.(time.Duration)
+		case "table":
+			config.table = opt.value.(string)
+		case "integritycheck":
+			config.integrityCheck = true
+		case "interceptor":
+			config.interceptors = append(config.interceptors, opt.value.(Interceptor))
+		case "authorizer":
+			config.authorizer = opt.value.(Authorizer)
+		case "alarm":
+			alarm := opt.value.(alarmOption)
+			config.alarm = &alarm
+		case "changepolling":
+			config.changePolling = true
+			config.changeInterval = opt.value.(time.Duration)
+		case "writebatching":
+			batching := opt.value.(groupCommitOption)
+			config.writeBatching = &batching
+		case "autocompact":
+			config.autoCompact = opt.value.(float64)
+		case "expvar":
+			config.expvar = true
+			config.expvarPrefix = opt.value.(string)
+		case "shutdownprune":
+			config.shutdownPrune = true
+		case "history":
+			config.history = opt.value.(int)
+		case "clock":
+			config.clock = opt.value.(Clock)
+		case "federation":
+			config.federation = opt.value.([]Op)
+			config.federated = true
+		case "audit":
+			config.audit = true
+		case "archive":
+			config.archive = true
+		case "tombstones":
+			config.tombstones = true
+		case "migration":
+			config.migration = opt.value.(MigrationPolicy)
+		case "export":
+			export := opt.value.(exportOption)
+			config.export = &export
+		case "slo":
+			slo := opt.value.(sloOption)
+			config.slo = &slo
+		case "journal":
+			config.journal = opt.value.(io.Writer)
+		case "maxentries":
+			quota := opt.value.(quotaOption)
+			config.maxEntries = quota.entries
+			config.eviction = quota.policy
+		case "readonly":
+			config.readOnly = true
+		case "encryption":
+			config.encryption = opt.value.([]byte)
+		case "checksums":
+			config.checksums = true
+		case "overflow":
+			config.overflow = opt.value.(int)
+		case "cache":
+			config.cacheSize = opt.value.(int)
+		case "createdirs":
+			config.createDirs = true
+		case "serialize":
+			config.serialize = true
+		case "pragmas":
+			for name, value := range opt.value.(map[string]string) {
+				config.pragmas[name] = value
+			}
 		}
 	}
 	return config
@@ -73,6 +223,41 @@ func WithTTL(ttl time.Duration) Option {
 	}
 }
 
+// Randomize the TTL of each entry within plus or minus the fraction, so keys
+// put together do not all expire together
+func WithTTLJitter(fraction float64) Option {
+	return Option{
+		key:   "jitter",
+		value: fraction,
+	}
+}
+
+// Periodically prune stale entries in the background, in batches so that
+// other operations are not blocked by a large delete
+func WithAutoPrune(interval time.Duration) Option {
+	return Option{
+		key:   "autoprune",
+		value: interval,
+	}
+}
+
+// Serialize operations behind a single lock instead of relying on the
+// connection pool and SQLite locking for concurrency
+func WithSerialization() Option {
+	return Option{
+		key:   "serialize",
+		value: true,
+	}
+}
+
+// Open the keybase without allowing writes
+func WithReadOnly() Option {
+	return Option{
+		key:   "readonly",
+		value: true,
+	}
+}
+
 // Option opaque configuration parameter
 type Option struct {
 	key   string
@@ -81,158 +266,1215 @@ type Option struct {
 
 // Keybase concurrent key storage with timeouts and optional persistence
 type Keybase struct {
-	mu  *sync.RWMutex
-	db  *sql.DB
-	ttl time.Duration
+	mu         *sync.RWMutex
+	inflight   sync.RWMutex
+	db         *sql.DB
+	conn       *instrumentedDB
+	stats      *queryStats
+	ttl        atomic.Int64
+	serialize  bool
+	autoPrune  *Feature
+	compact    *Feature
+	tiering    *Feature
+	export     *Feature
+	cold       bool
+	threshold  time.Duration
+	expire     *expireDispatcher
+	cache      *readCache
+	overflow   int
+	checksums  bool
+	cipher     *keyCipher
+	readOnly   bool
+	config     *options
+	maxEntries int
+	eviction   EvictionPolicy
+	journal    *journal
+	slo        *sloTracker
+	alarm      *entryAlarm
+	pending    []Migration
+	archive    bool
+	tombstones bool
+	audit      bool
+	federation *federation
+	group      *groupCommit
+	changes    *changeWatcher
+	clock      Clock
+	history    int
+	jitter     float64
+	vacuum     float64
+	timeout    time.Duration
+	cleanup    func() error
+	closed     atomic.Bool
 }
 
 // Open opens new or existing keybase
 func Open(ctx context.Context, opts ...Option) (*Keybase, error) {
-	config := parseOptions(opts...)
-	db, err := sqlOpen("sqlite", config.storage)
+	return open(ctx, parseOptions(opts...))
+}
+
+func open(ctx context.Context, config *options) (*Keybase, error) {
+	err := ctx.Err()
 	if err != nil {
-		return nil, fmt.Errorf("keybase.Open: failed to open database: %v", err)
+		return nil, fmt.Errorf("keybase.Open: %w", err)
+	}
+	var encryption *keyCipher
+	if config.encryption != nil {
+		encryption, err = newKeyCipher(config.encryption)
+		if err != nil {
+			return nil, fmt.Errorf("keybase.Open: invalid encryption key: %w", err)
+		}
+	}
+	if config.jitter < 0 || config.jitter >= 1 {
+		return nil, fmt.Errorf("keybase.Open: %w: TTL jitter must be at least 0 and less than 1", ErrInvalidArgument)
+	}
+	if config.queryTimeout < 0 {
+		return nil, fmt.Errorf("keybase.Open: %w: query timeout must not be negative", ErrInvalidArgument)
+	}
+	if !validTable.MatchString(config.table) {
+		return nil, fmt.Errorf("keybase.Open: %w: invalid table name %q", ErrInvalidArgument, config.table)
+	}
+	if config.autoCompact < 0 || config.autoCompact > 1 {
+		return nil, fmt.Errorf("keybase.Open: %w: auto compaction threshold must be between 0 and 1", ErrInvalidArgument)
+	}
+	if config.writeBatching != nil && (config.writeBatching.maxDelay <= 0 || config.writeBatching.maxBatch <= 0) {
+		return nil, fmt.Errorf("keybase.Open: %w: write batching delay and size must be positive", ErrInvalidArgument)
+	}
+	if config.pruneInterval <= 0 || config.compactInterval <= 0 || config.changeInterval <= 0 ||
+		(config.coldTier && config.coldInterval <= 0) || (config.export != nil && config.export.interval <= 0) {
+		return nil, fmt.Errorf("keybase.Open: %w: intervals must be positive", ErrInvalidArgument)
+	}
+	if config.alarm != nil && (config.alarm.threshold <= 0 || config.alarm.fn == nil) {
+		return nil, fmt.Errorf("keybase.Open: %w: alarm threshold must be positive and the callback set", ErrInvalidArgument)
+	}
+	if config.changePolling && isMemory(config.storage) {
+		return nil, fmt.Errorf("keybase.Open: %w: change polling requires persistent storage", ErrUnsupportedOption)
+	}
+	if config.expvar {
+		err = checkExpvar(config.expvarPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("keybase.Open: %w", err)
+		}
+	}
+	var federated *federation
+	if config.federated {
+		federated, err = newFederation(config.federation)
+		if err != nil {
+			return nil, fmt.Errorf("keybase.Open: %w", err)
+		}
+	}
+	db := config.db
+	if db == nil {
+		db, err = openStorage(ctx, config)
+		if err != nil {
+			return nil, fmt.Errorf("keybase.Open: %w", err)
+		}
+	}
+	stats := newQueryStats()
+	conn := &instrumentedDB{querier: db, stats: stats, table: config.table}
+	var pending []Migration
+	if !config.readOnly {
+		pending, err = migrate(ctx, db, stats, config.table, config.migration)
 	}
-	err = newCreateTableQuery().queryExec(ctx, db)
 	if err != nil {
-		return nil, fmt.Errorf("keybase.Open: failed to create table: %v", err)
+		_ = config.closeDB(db)
+		return nil, fmt.Errorf("keybase.Open: failed to migrate schema: %w", err)
+	}
+	if len(pending) > 0 {
+		// a dry run leaves the storage untouched, so nothing may be written
+		config.readOnly = true
 	}
-	return &Keybase{
-		mu:  new(sync.RWMutex),
-		db:  db,
-		ttl: config.ttl,
-	}, nil
+	if config.checksums && !config.readOnly {
+		err = migrateChecksums(ctx, conn)
+		if err != nil {
+			_ = config.closeDB(db)
+			return nil, fmt.Errorf("keybase.Open: failed to add checksum column: %w", err)
+		}
+	}
+	if config.integrityCheck {
+		err = verifyIntegrity(ctx, conn, len(pending) == 0)
+		if err != nil {
+			_ = config.closeDB(db)
+			return nil, fmt.Errorf("keybase.Open: %w", err)
+		}
+	}
+	k := &Keybase{
+		mu:        new(sync.RWMutex),
+		db:        db,
+		conn:      conn,
+		stats:     stats,
+		serialize: config.serialize,
+		readOnly:  config.readOnly,
+		config:    config,
+		pending:   pending,
+		expire:    newExpireDispatcher(),
+	}
+	k.ttl.Store(int64(config.ttl))
+	k.overflow = config.overflow
+	k.checksums = config.checksums
+	k.archive = config.archive
+	k.tombstones = config.tombstones
+	k.audit = config.audit
+	k.federation = federated
+	k.clock = config.clock
+	k.history = max(config.history, 0)
+	k.jitter = config.jitter
+	k.vacuum = config.autoCompact
+	k.timeout = config.queryTimeout
+	k.cipher = encryption
+	k.maxEntries = config.maxEntries
+	k.eviction = config.eviction
+	if config.journal != nil && !config.readOnly {
+		k.journal = newJournal(config.journal)
+	}
+	if config.slo != nil {
+		k.slo = newSLOTracker(*config.slo)
+	}
+	if config.cacheSize > 0 {
+		k.cache = newReadCache(config.cacheSize)
+	}
+	if config.alarm != nil {
+		k.alarm = newEntryAlarm(*config.alarm)
+	}
+	k.autoPrune = newFeature(config.pruneInterval, func(ctx context.Context) error {
+		return k.PruneEntriesBatched(ctx, defaultPruneBatchSize)
+	})
+	if config.autoPrune && !config.readOnly {
+		k.autoPrune.Start()
+	}
+	k.compact = newFeature(config.compactInterval, func(ctx context.Context) error {
+		_, err := k.CompactDuplicates(ctx, config.compactPolicy)
+		return err
+	})
+	if config.compact && !config.readOnly {
+		k.compact.Start()
+	}
+	k.cold = config.coldTier
+	k.threshold = config.coldThreshold
+	k.tiering = newFeature(config.coldInterval, func(ctx context.Context) error {
+		_, err := k.MoveToColdTier(ctx)
+		return err
+	})
+	if config.coldTier && !config.readOnly {
+		k.tiering.Start()
+	}
+	export := exportOption{interval: defaultPruneInterval}
+	if config.export != nil {
+		export = *config.export
+	}
+	k.export = newFeature(export.interval, func(ctx context.Context) error {
+		if export.dir == "" {
+			return nil
+		}
+		_, err := k.ExportNamespaces(ctx, export.pattern, export.dir, export.codec)
+		return err
+	})
+	if config.export != nil {
+		k.export.Start()
+	}
+	k.changes = newChangeWatcher(config.changeInterval, k.pollChanges)
+	if config.changePolling {
+		k.changes.feature.Start()
+	}
+	if config.writeBatching != nil && !config.readOnly {
+		k.group = newGroupCommit(k, *config.writeBatching)
+	}
+	if config.expvar {
+		publishExpvar(config.expvarPrefix, k)
+	}
+	return k, nil
 }
 
-// Close closes keybase
-func (k *Keybase) Close() {
-	_ = k.db.Close() // error is unreachable
+// openStorage opens the database of the configured storage
+func openStorage(ctx context.Context, config *options) (*sql.DB, error) {
+	err := validateStorage(config.storage, config.createDirs)
+	if err == nil && config.readOnly && !isMemory(config.storage) {
+		_, err = os.Stat(storagePath(config.storage))
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrInvalidStorage, err)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage: %w", err)
+	}
+	if _, ok := config.pragmas["busy_timeout"]; !ok {
+		config.pragmas["busy_timeout"] = fmt.Sprint(defaultBusyTimeout.Milliseconds())
+	}
+	if config.readOnly {
+		config.pragmas["query_only"] = "1"
+	}
+	db, err := sqlOpen(ctx, "sqlite", dataSource(config.storage, config.pragmas))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w: %w", ErrInvalidStorage, err)
+	}
+	if isMemory(config.storage) {
+		// every connection to :memory: opens a separate, empty database
+		db.SetMaxOpenConns(1)
+	}
+	return db, nil
+}
+
+// closeDB closes the database unless it was opened by the application
+func (config *options) closeDB(db *sql.DB) error {
+	if config.db != nil {
+		return nil
+	}
+	return db.Close()
+}
+
+// Close stops background features, waits for in-flight operations and
+// closes the database. Calling Close more than once has no effect.
+func (k *Keybase) Close() error {
+	// grouped puts are committed while the keybase is still open
+	k.group.close()
+	if !k.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	k.stopFeatures()
+	k.expire.close()
+	k.inflight.Lock()
+	defer k.inflight.Unlock()
+	err := k.release()
+	if err != nil {
+		return fmt.Errorf("keybase.Close: %w", err)
+	}
+	return nil
+}
+
+func (k *Keybase) stopFeatures() {
+	k.autoPrune.Stop()
+	k.compact.Stop()
+	k.tiering.Stop()
+	k.export.Stop()
+	k.changes.feature.Stop()
+}
+
+func (k *Keybase) release() error {
+	k.changes.close()
+	err := k.config.closeDB(k.db)
+	if err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	if k.cleanup != nil {
+		err = k.cleanup()
+		if err != nil {
+			return fmt.Errorf("failed to remove storage: %w", err)
+		}
+	}
+	return nil
 }
 
 // Put inserts new value
-func (k *Keybase) Put(ctx context.Context, namespace, key string) error {
-	expiration := time.Now().Add(k.ttl).UnixMilli()
-	k.mu.Lock()
-	defer k.mu.Unlock()
-	tx := newPutQuery(namespace, key, expiration)
-	err := tx.queryExec(ctx, k.db)
+func (k *Keybase) Put(ctx context.Context, namespace, key string, opts ...PutOption) error {
+	put := parsePutOptions(opts...)
+	if slices.Contains(put.tags, "") {
+		return fmt.Errorf("keybase.Put: %w: empty tag", ErrInvalidArgument)
+	}
+	err := k.put(ctx, namespace, key, time.Time{}, put.tags)
 	if err != nil {
-		return fmt.Errorf("keybase.Put: failed to insert key: %v", err)
+		return fmt.Errorf("keybase.Put: failed to insert key: %w", err)
 	}
 	return nil
 }
 
+// PutUntil inserts new value that expires at the given time instead of after
+// the TTL
+func (k *Keybase) PutUntil(ctx context.Context, namespace, key string, until time.Time) error {
+	err := k.put(ctx, namespace, key, until, nil)
+	if err != nil {
+		return fmt.Errorf("keybase.PutUntil: failed to insert key: %w", err)
+	}
+	return nil
+}
+
+// put inserts an entry expiring at until, or after the TTL if until is zero.
+// Tagged entries are written immediately, even within a batch, so the entry
+// and its tags are inserted in the same transaction.
+func (k *Keybase) put(ctx context.Context, namespace, key string, until time.Time, tags []string) error {
+	now := k.clock.Now()
+	if b := k.batch(ctx); b != nil && len(tags) == 0 {
+		return b.add(namespace, key, now, until)
+	}
+	if k.group != nil && len(tags) == 0 {
+		// group commits run without the caller's context, so the put is
+		// intercepted and authorized as it is queued
+		return k.intercept(ctx, OpInfo{Op: OpPut, Write: true, Namespaces: []string{namespace}}, func(ctx context.Context) error {
+			return k.group.put(ctx, batchedPut{namespace: namespace, key: key, now: now, until: until})
+		})
+	}
+	err := k.write(ctx, OpPut, []string{namespace}, func(ctx context.Context) error {
+		expiration := k.expiration(now)
+		if !until.IsZero() {
+			expiration = until.UnixMilli()
+		}
+		params := k.params(QueryParams{Namespace: namespace, Key: key, Expiration: expiration, Timestamp: now.UnixMilli()})
+		insert := func(db querier) error {
+			err := newPutQuery(params).queryExec(ctx, db)
+			if err != nil {
+				return err
+			}
+			err = k.versioned(ctx, db, params)
+			if err != nil {
+				return err
+			}
+			for _, tag := range tags {
+				tagged := params
+				tagged.Tag = tag
+				err = newTagKeyQuery(tagged).queryExec(withOperation(ctx, OpTagKey), db)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if len(tags) == 0 {
+			return k.insert(ctx, key, insert)
+		}
+		return k.transaction(ctx, func(db querier) error {
+			return k.insertWith(ctx, db, key, insert)
+		})
+	})
+	entry := putEntry(namespace, key, now, until)
+	entry.Tags = tags
+	k.record(ctx, entry, err)
+	return err
+}
+
+// putEntry journals a Put, or a PutUntil with the TTL it was given so that a
+// replay expires it after the same delay
+func putEntry(namespace, key string, now, until time.Time) JournalEntry {
+	if until.IsZero() {
+		return JournalEntry{Op: OpPut, Namespace: namespace, Key: key}
+	}
+	return JournalEntry{Op: OpPutUntil, Namespace: namespace, Key: key, TTL: until.Sub(now)}
+}
+
+// PutIfAbsent inserts new value only if the key has no active entries,
+// reporting whether the value was inserted
+func (k *Keybase) PutIfAbsent(ctx context.Context, namespace, key string) (bool, error) {
+	inserted, err := k.putIfAbsent(ctx, OpPutIfAbsent, namespace, key)
+	if err != nil {
+		return false, fmt.Errorf("keybase.PutIfAbsent: failed to insert key: %w", err)
+	}
+	return inserted, nil
+}
+
+// Seen reports whether the key already has an active entry, inserting one if
+// it does not, so that only the first of concurrent callers sees false
+func (k *Keybase) Seen(ctx context.Context, namespace, key string) (bool, error) {
+	inserted, err := k.putIfAbsent(ctx, OpSeen, namespace, key)
+	if err != nil {
+		return false, fmt.Errorf("keybase.Seen: failed to insert key: %w", err)
+	}
+	return !inserted, nil
+}
+
+func (k *Keybase) putIfAbsent(ctx context.Context, op Op, namespace, key string) (bool, error) {
+	now := k.clock.Now()
+	inserted := false
+	err := k.write(ctx, op, []string{namespace}, func(ctx context.Context) error {
+		params := k.params(QueryParams{
+			Namespace:  namespace,
+			Key:        key,
+			Expiration: k.expiration(now),
+			Timestamp:  now.UnixMilli(),
+		})
+		return k.insert(ctx, key, func(db querier) error {
+			rows, err := newPutIfAbsentQuery(params).queryRowsAffected(ctx, db)
+			inserted = rows > 0
+			if err != nil || !inserted {
+				return err
+			}
+			return k.versioned(ctx, db, params)
+		})
+	})
+	k.record(ctx, JournalEntry{Op: op, Namespace: namespace, Key: key}, err)
+	return inserted, err
+}
+
 // MatchKey collect list of keys from a given namespace that match a specific pattern
-func (k *Keybase) MatchKey(ctx context.Context, namespace, pattern string, active, unique bool) ([]string, error) {
-	timestamp := time.Now().UnixMilli()
-	k.mu.RLock()
-	defer k.mu.RUnlock()
-	keys, err := newMatchKeyQuery(namespace, pattern, active, unique, timestamp).queryValues(ctx, k.db)
+func (k *Keybase) MatchKey(ctx context.Context, namespace, pattern string, active, unique bool, opts ...QueryOption) ([]string, error) {
+	query, err := parseQueryOptions(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("keybase.MatchKey: %w", err)
+	}
+	err = ValidatePattern(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("keybase.MatchKey: %w", err)
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	var keys []string
+	err = k.read(ctx, OpMatchKey, []string{namespace}, func(ctx context.Context) (err error) {
+		keys, err = k.match(ctx, k.conn, k.params(QueryParams{Namespace: namespace, Pattern: pattern, Active: active, Unique: unique, Order: query.order, Exclude: query.exclude, Timestamp: timestamp}))
+		keys = k.excludeKeys(keys, query.exclude)
+		k.sortKeys(keys, query.order)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.MatchKey: failed to query database: %w", err)
+	}
+	return keys, nil
+}
+
+func (k *Keybase) match(ctx context.Context, db querier, params QueryParams) ([]string, error) {
+	if k.cipher == nil {
+		return newMatchKeyQuery(params).queryValues(ctx, db)
+	}
+	// encrypted keys cannot be matched by SQLite, so they are filtered here
+	keys, err := newGetKeysQuery(params).queryValues(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	keys, err = k.decodeAll(keys)
+	if err != nil {
+		return nil, err
+	}
+	matcher := likePattern(params.Pattern)
+	return slices.DeleteFunc(keys, func(key string) bool {
+		return !matcher.MatchString(key)
+	}), nil
+}
+
+// MatchKeyAny collects the keys from a given namespace that match any of the
+// patterns in a single query
+func (k *Keybase) MatchKeyAny(ctx context.Context, namespace string, patterns []string, active, unique bool) ([]string, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("keybase.MatchKeyAny: %w: no patterns", ErrInvalidArgument)
+	}
+	for _, pattern := range patterns {
+		err := ValidatePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("keybase.MatchKeyAny: %w", err)
+		}
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	var keys []string
+	err := k.read(ctx, OpMatchKeyAny, []string{namespace}, func(ctx context.Context) (err error) {
+		params := k.params(QueryParams{Namespace: namespace, Patterns: patterns, Active: active, Unique: unique, Timestamp: timestamp})
+		if k.cipher == nil {
+			keys, err = newMatchKeyAnyQuery(params).queryValues(ctx, k.conn)
+			return err
+		}
+		// encrypted keys cannot be matched by SQLite, so they are filtered
+		// here
+		keys, err = newGetKeysQuery(params).queryValues(ctx, k.conn)
+		if err != nil {
+			return err
+		}
+		keys, err = k.decodeAll(keys)
+		if err != nil {
+			return err
+		}
+		matchers := make([]*regexp.Regexp, len(patterns))
+		for i, pattern := range patterns {
+			matchers[i] = likePattern(pattern)
+		}
+		keys = slices.DeleteFunc(keys, func(key string) bool {
+			return !slices.ContainsFunc(matchers, func(matcher *regexp.Regexp) bool {
+				return matcher.MatchString(key)
+			})
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.MatchKeyAny: failed to query database: %w", err)
+	}
+	return keys, nil
+}
+
+// NamespacedKey a key along with the namespace it was found in
+type NamespacedKey struct {
+	Namespace string
+	Key       string
+}
+
+// MatchKeyAcross searches the given namespaces, or every namespace when none
+// are given, for keys matching the pattern in a single query. Keys are grouped
+// by namespace.
+func (k *Keybase) MatchKeyAcross(ctx context.Context, namespaces []string, pattern string, active, unique bool) ([]NamespacedKey, error) {
+	err := ValidatePattern(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("keybase.MatchKeyAcross: %w", err)
+	}
+	params := QueryParams{Namespaces: namespaces, Pattern: pattern, Active: active, Unique: unique, Timestamp: k.clock.Now().UnixMilli()}
+	if k.cipher != nil {
+		// encrypted keys cannot be matched by SQLite, so they are filtered
+		// here
+		params.Pattern = ""
+	}
+	keys := []NamespacedKey{}
+	err = k.read(ctx, OpMatchKeyAcross, namespaces, func(ctx context.Context) error {
+		matcher := likePattern(pattern)
+		return newMatchKeyAcrossQuery(k.params(params)).queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			key := NamespacedKey{}
+			err := rows.Scan(&key.Namespace, &key.Key)
+			if err != nil {
+				return err
+			}
+			key.Key, err = k.decode(key.Key)
+			if err != nil {
+				return err
+			}
+			if k.cipher != nil && !matcher.MatchString(key.Key) {
+				return nil
+			}
+			keys = append(keys, key)
+			return nil
+		})
+	})
 	if err != nil {
-		return nil, fmt.Errorf("keybase.MatchKey: failed to query database: %v", err)
+		return nil, fmt.Errorf("keybase.MatchKeyAcross: failed to query database: %w", err)
 	}
 	return keys, nil
 }
 
+// CountMatch counts the keys from a given namespace matching the pattern, using
+// the same wildcards as MatchKey
+func (k *Keybase) CountMatch(ctx context.Context, namespace, pattern string, active, unique bool) (int, error) {
+	err := ValidatePattern(pattern)
+	if err != nil {
+		return invalidCount, fmt.Errorf("keybase.CountMatch: %w", err)
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	count := invalidCount
+	err = k.read(ctx, OpCountMatch, []string{namespace}, func(ctx context.Context) error {
+		params := k.params(QueryParams{Namespace: namespace, Pattern: pattern, Active: active, Unique: unique, Timestamp: timestamp})
+		if k.cipher != nil {
+			// encrypted keys cannot be matched by SQLite, so they are
+			// counted here
+			keys, err := k.match(ctx, k.conn, params)
+			count = len(keys)
+			return err
+		}
+		var err error
+		count, err = newCountMatchQuery(params).queryCount(ctx, k.conn)
+		return err
+	})
+	if err != nil {
+		return invalidCount, fmt.Errorf("keybase.CountMatch: failed to query database: %w", err)
+	}
+	return count, nil
+}
+
 // CountKey count active frequency of a specific key from a given namespace
 func (k *Keybase) CountKey(ctx context.Context, namespace, key string, active bool) (int, error) {
-	timestamp := time.Now().UnixMilli()
-	k.mu.RLock()
-	defer k.mu.RUnlock()
-	count, err := newCountKeyQuery(namespace, key, active, timestamp).queryCount(ctx, k.db)
+	timestamp := k.clock.Now().UnixMilli()
+	count := invalidCount
+	err := k.read(ctx, OpCountKey, []string{namespace}, func(ctx context.Context) error {
+		value, err := k.cached(ctx, cacheKey{op: OpCountKey, namespace: namespace, key: key, active: active}, timestamp, func() (any, error) {
+			return newCountKeyQuery(k.params(QueryParams{Namespace: namespace, Key: key, Active: active, Timestamp: timestamp})).queryCount(ctx, k.conn)
+		})
+		if err == nil {
+			count = value.(int)
+		}
+		return err
+	})
 	if err != nil {
-		return invalidCount, fmt.Errorf("keybase.CountKey: failed to query database: %v", err)
+		return invalidCount, fmt.Errorf("keybase.CountKey: failed to query database: %w", err)
 	}
 	return count, nil
 }
 
+// GetExpiration gets the time at which the last active entry of a key expires,
+// returning ErrNotFound if the key has no active entries
+func (k *Keybase) GetExpiration(ctx context.Context, namespace, key string) (time.Time, error) {
+	timestamp := k.clock.Now().UnixMilli()
+	var expiration sql.NullInt64
+	err := k.read(ctx, OpGetExpiration, []string{namespace}, func(ctx context.Context) (err error) {
+		expiration, err = newGetExpirationQuery(k.params(QueryParams{Namespace: namespace, Key: key, Timestamp: timestamp})).queryNullInt(ctx, k.conn)
+		return err
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("keybase.GetExpiration: failed to query database: %w", err)
+	}
+	if !expiration.Valid {
+		return time.Time{}, fmt.Errorf("keybase.GetExpiration: %w", ErrNotFound)
+	}
+	return time.UnixMilli(expiration.Int64), nil
+}
+
+// ExpireMatch sets the expiration of every entry of a namespace with a key
+// matching the pattern, returning the number of entries updated. A time in
+// the past expires the entries immediately.
+func (k *Keybase) ExpireMatch(ctx context.Context, namespace, pattern string, at time.Time) (int, error) {
+	err := ValidatePattern(pattern)
+	if err != nil {
+		return 0, fmt.Errorf("keybase.ExpireMatch: %w", err)
+	}
+	now := k.clock.Now()
+	updated := 0
+	err = k.write(ctx, OpExpireMatch, []string{namespace}, func(ctx context.Context) error {
+		params := k.params(QueryParams{Namespace: namespace, Pattern: pattern, Expiration: at.UnixMilli()})
+		if k.cipher == nil {
+			rows, err := newExpireMatchQuery(params).queryRowsAffected(ctx, k.conn)
+			updated = int(rows)
+			return err
+		}
+		// encrypted keys cannot be matched by SQLite, so each matching key
+		// is updated on its own
+		return k.transaction(ctx, func(db querier) error {
+			keys, err := k.match(ctx, db, k.params(QueryParams{Namespace: namespace, Pattern: pattern, Unique: true}))
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				params.Key = k.params(QueryParams{Key: key}).Key
+				rows, err := newExpireKeyQuery(params).queryRowsAffected(withOperation(ctx, OpExpireKey), db)
+				if err != nil {
+					return err
+				}
+				updated += int(rows)
+			}
+			return nil
+		})
+	})
+	k.record(ctx, JournalEntry{Op: OpExpireMatch, Namespace: namespace, Pattern: pattern, TTL: at.Sub(now)}, err)
+	if err != nil {
+		return 0, fmt.Errorf("keybase.ExpireMatch: failed to update expiration: %w", err)
+	}
+	return updated, nil
+}
+
+// DeleteMatch removes every entry of a namespace with a key matching the
+// pattern, as Delete does within a transaction, returning the number of
+// entries removed. A dry run only counts the entries that would be removed.
+func (k *Keybase) DeleteMatch(ctx context.Context, namespace, pattern string, dryRun bool) (int, error) {
+	err := ValidatePattern(pattern)
+	if err != nil {
+		return 0, fmt.Errorf("keybase.DeleteMatch: %w", err)
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	params := k.params(QueryParams{Namespace: namespace, Pattern: pattern, Timestamp: timestamp})
+	deleted := 0
+	if dryRun {
+		err = k.read(ctx, OpDeleteMatch, []string{namespace}, func(ctx context.Context) error {
+			keys, err := k.match(ctx, k.conn, params)
+			deleted = len(keys)
+			return err
+		})
+		if err != nil {
+			return 0, fmt.Errorf("keybase.DeleteMatch: failed to query database: %w", err)
+		}
+		return deleted, nil
+	}
+	err = k.write(ctx, OpDeleteMatch, []string{namespace}, func(ctx context.Context) error {
+		return k.transaction(ctx, func(db querier) error {
+			keys, err := k.match(ctx, db, params)
+			if err != nil {
+				return err
+			}
+			deleted = len(keys)
+			slices.Sort(keys)
+			for _, key := range slices.Compact(keys) {
+				err = newDeleteKeyQuery(k.params(QueryParams{Namespace: namespace, Key: key, Timestamp: timestamp})).queryExec(withOperation(ctx, OpDeleteKey), db)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	k.record(ctx, JournalEntry{Op: OpDeleteMatch, Namespace: namespace, Pattern: pattern}, err)
+	if err != nil {
+		return 0, fmt.Errorf("keybase.DeleteMatch: failed to delete keys: %w", err)
+	}
+	return deleted, nil
+}
+
+// GetTTL gets the remaining duration until the last active entry of a key expires,
+// returning ErrNotFound if the key has no active entries
+func (k *Keybase) GetTTL(ctx context.Context, namespace, key string) (time.Duration, error) {
+	expiration, err := k.GetExpiration(ctx, namespace, key)
+	if err != nil {
+		return 0, err
+	}
+	return expiration.Sub(k.clock.Now()), nil
+}
+
 // GetKeys collects a list of active keys from a given namespace
-func (k *Keybase) GetKeys(ctx context.Context, namespace string, active, unique bool) ([]string, error) {
-	timestamp := time.Now().UnixMilli()
-	k.mu.RLock()
-	defer k.mu.RUnlock()
-	keys, err := newGetKeysQuery(namespace, active, unique, timestamp).queryValues(ctx, k.db)
+func (k *Keybase) GetKeys(ctx context.Context, namespace string, active, unique bool, opts ...QueryOption) ([]string, error) {
+	query, err := parseQueryOptions(opts...)
 	if err != nil {
-		return nil, fmt.Errorf("keybase.GetKeys: failed to query database: %v", err)
+		return nil, fmt.Errorf("keybase.GetKeys: %w", err)
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	var keys []string
+	err = k.read(ctx, OpGetKeys, []string{namespace}, func(ctx context.Context) error {
+		value, err := k.cached(ctx, cacheKey{op: OpGetKeys, namespace: namespace, active: active, unique: unique, order: query.order, exclude: strings.Join(query.exclude, "\x00")}, timestamp, func() (any, error) {
+			keys, err := newGetKeysQuery(k.params(QueryParams{Namespace: namespace, Active: active, Unique: unique, Order: query.order, Exclude: query.exclude, Timestamp: timestamp})).queryValues(ctx, k.conn)
+			if err != nil {
+				return nil, err
+			}
+			keys, err = k.decodeAll(keys)
+			keys = k.excludeKeys(keys, query.exclude)
+			k.sortKeys(keys, query.order)
+			return keys, err
+		})
+		if err == nil {
+			keys = slices.Clone(value.([]string))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.GetKeys: failed to query database: %w", err)
 	}
 	return keys, nil
 }
 
 // CountKeys counts the active keys from a given namespace
 func (k *Keybase) CountKeys(ctx context.Context, namespace string, active, unique bool) (int, error) {
-	timestamp := time.Now().UnixMilli()
-	k.mu.RLock()
-	defer k.mu.RUnlock()
-	count, err := newCountKeysQuery(namespace, active, unique, timestamp).queryCount(ctx, k.db)
+	timestamp := k.clock.Now().UnixMilli()
+	count := invalidCount
+	err := k.read(ctx, OpCountKeys, []string{namespace}, func(ctx context.Context) (err error) {
+		count, err = newCountKeysQuery(k.params(QueryParams{Namespace: namespace, Active: active, Unique: unique, Timestamp: timestamp})).queryCount(ctx, k.conn)
+		return err
+	})
 	if err != nil {
-		return invalidCount, fmt.Errorf("keybase.CountKeys: failed to query database: %v", err)
+		return invalidCount, fmt.Errorf("keybase.CountKeys: failed to query database: %w", err)
 	}
 	return count, nil
 }
 
+// CountKeysByNamespace counts the keys of every namespace in a single query
+func (k *Keybase) CountKeysByNamespace(ctx context.Context, active, unique bool) (map[string]int, error) {
+	timestamp := k.clock.Now().UnixMilli()
+	counts := map[string]int{}
+	err := k.read(ctx, OpCountKeysByNamespace, nil, func(ctx context.Context) error {
+		return newCountKeysByNamespaceQuery(k.params(QueryParams{Active: active, Unique: unique, Timestamp: timestamp})).queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			namespace, count := "", 0
+			err := rows.Scan(&namespace, &count)
+			counts[namespace] = count
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.CountKeysByNamespace: failed to query database: %w", err)
+	}
+	return counts, nil
+}
+
 // GetNamespace collects a list of active namespaces
 func (k *Keybase) GetNamespaces(ctx context.Context, active bool) ([]string, error) {
-	timestamp := time.Now().UnixMilli()
-	k.mu.RLock()
-	defer k.mu.RUnlock()
-	keys, err := newGetNamespacesQuery(active, timestamp).queryValues(ctx, k.db)
+	timestamp := k.clock.Now().UnixMilli()
+	var namespaces []string
+	err := k.read(ctx, OpGetNamespaces, nil, func(ctx context.Context) (err error) {
+		return k.span(ctx, OpGetNamespaces, func(db querier, attached []string) (err error) {
+			namespaces, err = newGetNamespacesQuery(k.params(QueryParams{Active: active, Timestamp: timestamp, Attached: attached})).queryValues(ctx, db)
+			return err
+		})
+	})
 	if err != nil {
-		return nil, fmt.Errorf("keybase.GetNamespaces: failed to query database: %v", err)
+		return nil, fmt.Errorf("keybase.GetNamespaces: failed to query database: %w", err)
 	}
-	return keys, nil
+	return namespaces, nil
+}
+
+// MatchNamespaces collects a list of namespaces that match a specific pattern
+func (k *Keybase) MatchNamespaces(ctx context.Context, pattern string, active bool) ([]string, error) {
+	err := ValidatePattern(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("keybase.MatchNamespaces: %w", err)
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	var namespaces []string
+	err = k.read(ctx, OpMatchNamespaces, nil, func(ctx context.Context) (err error) {
+		namespaces, err = newMatchNamespacesQuery(k.params(QueryParams{Pattern: pattern, Active: active, Timestamp: timestamp})).queryValues(ctx, k.conn)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.MatchNamespaces: failed to query database: %w", err)
+	}
+	return namespaces, nil
 }
 
 // CountNamespaces counts active namespaces
 func (k *Keybase) CountNamespaces(ctx context.Context, active bool) (int, error) {
-	timestamp := time.Now().UnixMilli()
-	k.mu.RLock()
-	defer k.mu.RUnlock()
-	count, err := newCountNamespacesQuery(active, timestamp).queryCount(ctx, k.db)
+	timestamp := k.clock.Now().UnixMilli()
+	count := invalidCount
+	err := k.read(ctx, OpCountNamespaces, nil, func(ctx context.Context) (err error) {
+		count, err = newCountNamespacesQuery(k.params(QueryParams{Active: active, Timestamp: timestamp})).queryCount(ctx, k.conn)
+		return err
+	})
 	if err != nil {
-		return invalidCount, fmt.Errorf("keybase.CountNamespaces: failed to query database: %v", err)
+		return invalidCount, fmt.Errorf("keybase.CountNamespaces: failed to query database: %w", err)
 	}
 	return count, nil
 }
 
 // CountEntries counts all keys in all namespaces
 func (k *Keybase) CountEntries(ctx context.Context, active, unique bool) (int, error) {
-	timestamp := time.Now().UnixMilli()
-	k.mu.RLock()
-	defer k.mu.RUnlock()
-	count, err := newCountEntriesQuery(active, unique, timestamp).queryCount(ctx, k.db)
+	timestamp := k.clock.Now().UnixMilli()
+	count := invalidCount
+	err := k.read(ctx, OpCountEntries, nil, func(ctx context.Context) (err error) {
+		return k.span(ctx, OpCountEntries, func(db querier, attached []string) (err error) {
+			count, err = newCountEntriesQuery(k.params(QueryParams{Active: active, Unique: unique, Timestamp: timestamp, Attached: attached})).queryCount(ctx, db)
+			return err
+		})
+	})
 	if err != nil {
-		return invalidCount, fmt.Errorf("keybase.CountEntries: failed to query database: %v", err)
+		return invalidCount, fmt.Errorf("keybase.CountEntries: failed to query database: %w", err)
 	}
 	return count, nil
 }
 
 // PruneEntries removes stale entries.
 func (k *Keybase) PruneEntries(ctx context.Context) error {
-	timestamp := time.Now().UnixMilli()
-	k.mu.Lock()
-	defer k.mu.Unlock()
-	err := newPruneEntriesQuery(timestamp).queryExec(ctx, k.db)
+	err := k.prune(ctx, OpPruneEntries, QueryParams{})
 	if err != nil {
-		return fmt.Errorf("keybase.PruneEntries: failed to insert key: %v", err)
+		return fmt.Errorf("keybase.PruneEntries: failed to prune entries: %w", err)
+	}
+	err = k.checkAlarm(ctx)
+	if err != nil {
+		return fmt.Errorf("keybase.PruneEntries: failed to check alarm: %w", err)
 	}
 	return nil
 }
 
+// PruneNamespace removes stale entries of a single namespace, leaving other
+// namespaces untouched
+func (k *Keybase) PruneNamespace(ctx context.Context, namespace string) error {
+	err := k.prune(ctx, OpPruneNamespace, QueryParams{Namespace: namespace}.scoped())
+	if err != nil {
+		return fmt.Errorf("keybase.PruneNamespace: failed to prune entries: %w", err)
+	}
+	err = k.checkAlarm(ctx)
+	if err != nil {
+		return fmt.Errorf("keybase.PruneNamespace: failed to check alarm: %w", err)
+	}
+	return nil
+}
+
+// PruneEntriesBatched removes stale entries like PruneEntries, deleting at most
+// batchSize entries per write so that other operations can run between
+// batches instead of waiting for a single large delete
+func (k *Keybase) PruneEntriesBatched(ctx context.Context, batchSize int) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("keybase.PruneEntriesBatched: %w: batch size must be positive", ErrInvalidArgument)
+	}
+	// the batches are recorded as a single prune
+	total := int64(0)
+	var err error
+	for {
+		var pruned int64
+		pruned, err = k.pruneBatch(ctx, OpPruneEntries, QueryParams{Limit: batchSize})
+		total += pruned
+		if err != nil || pruned < int64(batchSize) {
+			break
+		}
+		runtime.Gosched()
+	}
+	k.record(ctx, JournalEntry{Op: OpPruneEntries}, err)
+	if err == nil {
+		k.stats.recordPrune(total)
+		err = k.autoCompact(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("keybase.PruneEntriesBatched: failed to prune entries: %w", err)
+	}
+	err = k.checkAlarm(ctx)
+	if err != nil {
+		return fmt.Errorf("keybase.PruneEntriesBatched: failed to check alarm: %w", err)
+	}
+	return nil
+}
+
+func (k *Keybase) prune(ctx context.Context, op Op, params QueryParams) error {
+	pruned, err := k.pruneBatch(ctx, op, params)
+	k.record(ctx, JournalEntry{Op: op, Namespace: params.Namespace}, err)
+	if err != nil {
+		return err
+	}
+	k.stats.recordPrune(pruned)
+	return k.autoCompact(ctx)
+}
+
+// pruneBatch removes stale entries, returning how many were removed. With a
+// limit, only that many are removed, and the other tables are only pruned by
+// the last batch.
+func (k *Keybase) pruneBatch(ctx context.Context, op Op, params QueryParams) (int64, error) {
+	params.Timestamp = k.clock.Now().UnixMilli()
+	expired := []expiredEntry{}
+	pruned := int64(0)
+	var namespaces []string
+	if params.Scoped {
+		namespaces = []string{params.Namespace}
+	}
+	err := k.write(ctx, op, namespaces, func(ctx context.Context) error {
+		params := k.params(params)
+		run := func(db querier) error {
+			var err error
+			if k.archive {
+				err = newArchiveEntriesQuery(params).queryExec(withOperation(ctx, OpArchiveEntries), db)
+				if err != nil {
+					return err
+				}
+			}
+			if k.expire.active() {
+				err = newExpireEntriesQuery(params).queryRows(ctx, db, func(rows *sql.Rows) error {
+					entry := expiredEntry{}
+					err := rows.Scan(&entry.namespace, &entry.key)
+					if err != nil {
+						return err
+					}
+					entry.key, err = k.decode(entry.key)
+					expired = append(expired, entry)
+					return err
+				})
+				pruned = int64(len(expired))
+			} else {
+				pruned, err = newPruneEntriesQuery(params).queryRowsAffected(ctx, db)
+			}
+			if err != nil {
+				return err
+			}
+			if params.Limit > 0 && pruned == int64(params.Limit) {
+				return nil
+			}
+			for _, build := range pruneQueries {
+				err = build(params).queryExec(ctx, db)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if k.archive {
+			// entries must not be deleted unless they were archived
+			return k.transaction(ctx, run)
+		}
+		return run(k.conn)
+	})
+	if err != nil {
+		return 0, err
+	}
+	k.expire.dispatch(ctx, expired)
+	return pruned, nil
+}
+
 // ClearEntries removes all entries.
 func (k *Keybase) ClearEntries(ctx context.Context) error {
-	k.mu.Lock()
-	defer k.mu.Unlock()
-	err := newClearEntriesQuery().queryExec(ctx, k.db)
+	err := k.write(ctx, OpClearEntries, nil, func(ctx context.Context) error {
+		return newClearEntriesQuery().queryExec(ctx, k.conn)
+	})
+	k.record(ctx, JournalEntry{Op: OpClearEntries}, err)
 	if err != nil {
-		return fmt.Errorf("keybase.PruneEntries: failed to insert key: %v", err)
+		return fmt.Errorf("keybase.ClearEntries: failed to clear entries: %w", err)
 	}
 	return nil
 }
 
-func sqlOpen(driverName string, dataSourceName string) (*sql.DB, error) {
+// CopyNamespace copies the active entries of one namespace into another,
+// returning the number of entries copied. The copies keep their expiration
+// unless overwriteExpiration is set, in which case they expire after the TTL.
+func (k *Keybase) CopyNamespace(ctx context.Context, src, dst string, overwriteExpiration bool) (int, error) {
+	now := k.clock.Now()
+	copied := 0
+	err := k.write(ctx, OpCopyNamespace, []string{src, dst}, func(ctx context.Context) error {
+		params := k.params(QueryParams{Namespace: src, Target: dst, Timestamp: now.UnixMilli()})
+		if overwriteExpiration {
+			params.Expiration = k.expiration(now)
+		}
+		return k.transaction(ctx, func(db querier) error {
+			rows, err := newCopyNamespaceQuery(params).queryRowsAffected(ctx, db)
+			copied = int(rows)
+			if err != nil {
+				return err
+			}
+			return k.enforceQuota(ctx, db)
+		})
+	})
+	k.record(ctx, JournalEntry{Op: OpCopyNamespace, Namespace: src, Target: dst, Overwrite: overwriteExpiration}, err)
+	if err != nil {
+		return 0, fmt.Errorf("keybase.CopyNamespace: failed to copy entries: %w", err)
+	}
+	return copied, nil
+}
+
+// ClearNamespace removes all entries, counters, leases, and fields of a single
+// namespace, leaving other namespaces untouched
+func (k *Keybase) ClearNamespace(ctx context.Context, namespace string) error {
+	err := k.write(ctx, OpClearNamespace, []string{namespace}, func(ctx context.Context) error {
+		return newClearNamespaceQuery(QueryParams{Namespace: namespace}).queryExec(ctx, k.conn)
+	})
+	k.record(ctx, JournalEntry{Op: OpClearNamespace, Namespace: namespace}, err)
+	if err != nil {
+		return fmt.Errorf("keybase.ClearNamespace: failed to clear entries: %w", err)
+	}
+	return nil
+}
+
+// Reconfigure changes options without reopening the keybase. Only WithTTL and
+// WithAutoPrune can be changed at runtime.
+func (k *Keybase) Reconfigure(ctx context.Context, opts ...Option) error {
+	for _, opt := range opts {
+		switch opt.key {
+		case "ttl":
+		case "autoprune":
+			if opt.value.(time.Duration) <= 0 {
+				return fmt.Errorf("keybase.Reconfigure: %w: auto prune interval must be positive", ErrInvalidArgument)
+			}
+		default:
+			return fmt.Errorf("keybase.Reconfigure: %w: %s", ErrUnsupportedOption, opt.key)
+		}
+	}
+	entry := JournalEntry{Op: OpReconfigure}
+	err := k.write(ctx, OpReconfigure, nil, func(ctx context.Context) error {
+		for _, opt := range opts {
+			switch opt.key {
+			case "ttl":
+				entry.TTL = opt.value.(time.Duration)
+				k.ttl.Store(int64(entry.TTL))
+			case "autoprune":
+				entry.Interval = opt.value.(time.Duration)
+			}
+		}
+		return nil
+	})
+	k.record(ctx, entry, err)
+	if err != nil {
+		return fmt.Errorf("keybase.Reconfigure: failed to apply options: %w", err)
+	}
+	// with serialization the prune task takes the write lock, so features are
+	// updated after releasing it
+	for _, opt := range opts {
+		if opt.key == "autoprune" {
+			k.autoPrune.setInterval(opt.value.(time.Duration))
+			k.autoPrune.Start()
+		}
+	}
+	return nil
+}
+
+// expiration computes the expiration of an entry written at the given time
+func (k *Keybase) expiration(now time.Time) int64 {
+	ttl := time.Duration(k.ttl.Load())
+	if k.jitter > 0 {
+		ttl += time.Duration((rand.Float64()*2 - 1) * k.jitter * float64(ttl))
+	}
+	return now.Add(ttl).UnixMilli()
+}
+
+// params fills in the instance settings shared by every query
+func (k *Keybase) params(params QueryParams) QueryParams {
+	params.Cold = k.cold
+	params.Overflow = k.overflow > 0
+	params.Checksums = k.checksums
+	params.Tombstones = k.tombstones
+	if k.cipher != nil && params.Order.byKey() {
+		params.Order = Unordered
+	}
+	if k.cipher != nil {
+		params.Exclude = nil
+	}
+	params.Key = k.encode(params.Key)
+	if k.overflows(params.Key) {
+		params.Key = overflowRef(params.Key)
+	}
+	return params
+}
+
+// insert runs an insert of the given key. When the key overflows or a quota
+// is set, it runs in a transaction that also stores the full key and
+// enforces the quota, so the insert is rolled back if either fails.
+func (k *Keybase) insert(ctx context.Context, key string, fn func(db querier) error) error {
+	if !k.overflows(k.encode(key)) && k.maxEntries == 0 && k.history == 0 {
+		return fn(k.conn)
+	}
+	return k.transaction(ctx, func(db querier) error {
+		return k.insertWith(ctx, db, key, fn)
+	})
+}
+
+// insertWith runs an insert of the given key within a transaction the caller
+// already started
+func (k *Keybase) insertWith(ctx context.Context, db querier, key string, fn func(db querier) error) error {
+	stored := k.encode(key)
+	if k.overflows(stored) {
+		err := newPutOverflowQuery(QueryParams{Key: overflowRef(stored), Value: stored}).queryExec(withOperation(ctx, OpPutOverflow), db)
+		if err != nil {
+			return err
+		}
+	}
+	err := fn(db)
+	if err != nil {
+		return err
+	}
+	return k.enforceQuota(ctx, db)
+}
+
+// transaction runs fn in a database transaction, committing if it succeeds
+func (k *Keybase) transaction(ctx context.Context, fn func(db querier) error) error {
+	tx, err := k.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = fn(&instrumentedDB{querier: tx, stats: k.stats, table: k.conn.table})
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (k *Keybase) read(ctx context.Context, op Op, namespaces []string, fn func(ctx context.Context) error) error {
+	return k.intercept(ctx, OpInfo{Op: op, Namespaces: namespaces}, func(ctx context.Context) error {
+		start := time.Now()
+		k.inflight.RLock()
+		defer k.inflight.RUnlock()
+		if k.closed.Load() {
+			return ErrClosed
+		}
+		if !k.slo.admit(op) {
+			return ErrOverloaded
+		}
+		if k.serialize {
+			k.mu.RLock()
+			defer k.mu.RUnlock()
+		}
+		err := fn(withQueryTimeout(withOperation(ctx, op), k.timeout))
+		k.slo.observe(time.Since(start))
+		return err
+	})
+}
+
+func (k *Keybase) write(ctx context.Context, op Op, namespaces []string, fn func(ctx context.Context) error) error {
+	return k.intercept(ctx, OpInfo{Op: op, Write: true, Namespaces: namespaces}, func(ctx context.Context) error {
+		start := time.Now()
+		k.inflight.RLock()
+		defer k.inflight.RUnlock()
+		if k.closed.Load() {
+			return ErrClosed
+		}
+		if k.readOnly {
+			return ErrReadOnly
+		}
+		if k.serialize {
+			k.mu.Lock()
+			defer k.mu.Unlock()
+		}
+		admitted := time.Now()
+		err := immutable(fn(withQueryTimeout(withOperation(ctx, op), k.timeout)))
+		k.stats.recordWrite(admitted.Sub(start), time.Since(admitted))
+		k.slo.observe(time.Since(start))
+		k.cache.invalidate()
+		return err
+	})
+}
+
+// AutoPrune handle for the background prune feature, which can be started
+// even if it was not enabled with WithAutoPrune
+func (k *Keybase) AutoPrune() *Feature {
+	return k.autoPrune
+}
+
+func isMemory(storage string) bool {
+	return storage == ":memory:" || strings.HasPrefix(storage, "file::memory:") || strings.Contains(storage, "mode=memory")
+}
+
+func sqlOpen(ctx context.Context, driverName string, dataSourceName string) (*sql.DB, error) {
 	db, _ := sql.Open(driverName, dataSourceName)
-	err := db.Ping()
+	err := db.PingContext(ctx)
 	if err != nil {
+		_ = db.Close()
 		return nil, err
 	}
 	return db, nil
@@ -21,10 +21,13 @@
 package keybase
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"path"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -54,10 +57,82 @@ func TestPut(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(0))
 	defer cancel()
 	err = keybase.Put(ctx, "namespace", "keyvalue")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestPutIfAbsent(t *testing.T) {
+	keybase, err := Open(context.Background(), WithTTL(time.Millisecond*50))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	inserted, err := keybase.PutIfAbsent(context.Background(), "namespace", "key")
+	assert.NoError(t, err)
+	assert.True(t, inserted)
+	inserted, err = keybase.PutIfAbsent(context.Background(), "namespace", "key")
+	assert.NoError(t, err)
+	assert.False(t, inserted)
+	inserted, err = keybase.PutIfAbsent(context.Background(), "othernamespace", "key")
+	assert.NoError(t, err)
+	assert.True(t, inserted)
+
+	time.Sleep(time.Millisecond * 50)
+
+	inserted, err = keybase.PutIfAbsent(context.Background(), "namespace", "key")
+	assert.NoError(t, err)
+	assert.True(t, inserted)
+	count, err := keybase.CountKey(context.Background(), "namespace", "key", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(0))
+	defer cancel()
+	_, err = keybase.PutIfAbsent(ctx, "namespace", "key")
+	assert.Error(t, err)
+}
+
+func TestSeen(t *testing.T) {
+	ctx := context.Background()
+	clock := &fixedClock{now: time.Now()}
+	keybase, err := Open(ctx, WithClock(clock), WithTTL(time.Minute))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	results := make(chan bool, 8)
+	group := sync.WaitGroup{}
+	for range cap(results) {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			seen, err := keybase.Seen(ctx, "namespace", "key")
+			assert.NoError(t, err)
+			results <- seen
+		}()
+	}
+	group.Wait()
+	close(results)
+	unseen := 0
+	for seen := range results {
+		if !seen {
+			unseen++
+		}
+	}
+	assert.Equal(t, 1, unseen)
+
+	clock.now = clock.now.Add(time.Minute)
+	seen, err := keybase.Seen(ctx, "namespace", "key")
+	assert.NoError(t, err)
+	assert.False(t, seen)
+	seen, err = keybase.Seen(ctx, "namespace", "key")
+	assert.NoError(t, err)
+	assert.True(t, seen)
+
+	cancelled, cancel := context.WithTimeout(ctx, time.Duration(0))
+	defer cancel()
+	_, err = keybase.Seen(cancelled, "namespace", "key")
 	assert.Error(t, err)
 }
 
-// TestKey tests MatchKey and CountKey
+// TestKey tests MatchKey, CountMatch and CountKey
 func TestKey(t *testing.T) {
 	namespace := "default"
 	keys := []string{
@@ -84,7 +159,19 @@ func TestKey(t *testing.T) {
 	assert.Len(t, matchedKeys, 2)
 	assert.NoError(t, err)
 
-	count, err := keybase.CountKey(context.Background(), namespace, keys[0], true)
+	count, err := keybase.CountMatch(context.Background(), namespace, pattern, true, false)
+	assert.Equal(t, 3, count)
+	assert.NoError(t, err)
+
+	count, err = keybase.CountMatch(context.Background(), namespace, pattern, true, true)
+	assert.Equal(t, 2, count)
+	assert.NoError(t, err)
+
+	count, err = keybase.CountMatch(context.Background(), namespace, "key1", false, false)
+	assert.Equal(t, 1, count)
+	assert.NoError(t, err)
+
+	count, err = keybase.CountKey(context.Background(), namespace, keys[0], true)
 	assert.Equal(t, 2, count)
 	assert.NoError(t, err)
 
@@ -92,10 +179,107 @@ func TestKey(t *testing.T) {
 	defer cancel()
 	_, err = keybase.MatchKey(ctx, namespace, pattern, true, false)
 	assert.Error(t, err)
+	_, err = keybase.CountMatch(ctx, namespace, pattern, true, false)
+	assert.Error(t, err)
 	_, err = keybase.CountKey(ctx, namespace, keys[0], true)
 	assert.Error(t, err)
 }
 
+func TestMatchKeyAcross(t *testing.T) {
+	ctx := context.Background()
+	clock := &fixedClock{now: time.Now()}
+	keybase, err := Open(ctx, WithClock(clock))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	assert.NoError(t, keybase.Put(ctx, "green", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "green", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "blue", "key1"))
+	assert.NoError(t, keybase.Put(ctx, "blue", "other"))
+	assert.NoError(t, keybase.PutUntil(ctx, "red", "key2", clock.now.Add(-time.Second)))
+
+	keys, err := keybase.MatchKeyAcross(ctx, nil, "key*", false, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []NamespacedKey{{"blue", "key1"}, {"green", "key0"}, {"red", "key2"}}, keys)
+	keys, err = keybase.MatchKeyAcross(ctx, nil, "key*", true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []NamespacedKey{{"blue", "key1"}, {"green", "key0"}, {"green", "key0"}}, keys)
+	keys, err = keybase.MatchKeyAcross(ctx, []string{"green", "red"}, "*", false, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []NamespacedKey{{"green", "key0"}, {"red", "key2"}}, keys)
+	keys, err = keybase.MatchKeyAcross(ctx, []string{"yellow"}, "*", false, false)
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	cancelled, cancel := context.WithTimeout(ctx, time.Duration(0))
+	defer cancel()
+	_, err = keybase.MatchKeyAcross(cancelled, nil, "*", true, false)
+	assert.Error(t, err)
+}
+
+func TestMatchKeyAny(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{nil, {WithEncryption(make([]byte, 32))}} {
+		clock := &fixedClock{now: time.Now()}
+		keybase, err := Open(ctx, append(opts, WithClock(clock))...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+		assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+		assert.NoError(t, keybase.Put(ctx, "namespace", "other"))
+		assert.NoError(t, keybase.Put(ctx, "namespace", "skip"))
+		assert.NoError(t, keybase.PutUntil(ctx, "namespace", "key1", clock.now.Add(-time.Second)))
+
+		keys, err := keybase.MatchKeyAny(ctx, "namespace", []string{"key*", "oth?r"}, false, true)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"key0", "other", "key1"}, keys)
+		keys, err = keybase.MatchKeyAny(ctx, "namespace", []string{"key*", "key0"}, true, false)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key0", "key0"}, keys)
+		keys, err = keybase.MatchKeyAny(ctx, "namespace", []string{"none"}, false, false)
+		assert.NoError(t, err)
+		assert.Empty(t, keys)
+
+		_, err = keybase.MatchKeyAny(ctx, "namespace", nil, false, false)
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+		_, err = keybase.MatchKeyAny(ctx, "namespace", []string{"key*", `key\`}, false, false)
+		assert.ErrorIs(t, err, ErrInvalidPattern)
+	}
+}
+
+// TestExpiration tests GetExpiration and GetTTL
+func TestExpiration(t *testing.T) {
+	keybase, err := Open(context.Background(), WithTTL(time.Minute))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	_, err = keybase.GetExpiration(context.Background(), "namespace", "key")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = keybase.GetTTL(context.Background(), "namespace", "key")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	before := time.Now()
+	err = keybase.Put(context.Background(), "namespace", "key")
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.Reconfigure(context.Background(), WithTTL(time.Hour)))
+	err = keybase.Put(context.Background(), "namespace", "key")
+	assert.NoError(t, err)
+
+	expiration, err := keybase.GetExpiration(context.Background(), "namespace", "key")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, before.Add(time.Hour), expiration, time.Second)
+	ttl, err := keybase.GetTTL(context.Background(), "namespace", "key")
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour, ttl, float64(time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(0))
+	defer cancel()
+	_, err = keybase.GetExpiration(ctx, "namespace", "key")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotFound)
+}
+
 // TestKeys tests GetKeys and CountKeys
 func TestKeys(t *testing.T) {
 	namespace := "default"
@@ -130,12 +314,22 @@ func TestKeys(t *testing.T) {
 	assert.Equal(t, 2, count)
 	assert.NoError(t, err)
 
+	counts, err := keybase.CountKeysByNamespace(context.Background(), true, false)
+	assert.Equal(t, map[string]int{namespace: 3, "othernamespace": 1}, counts)
+	assert.NoError(t, err)
+
+	counts, err = keybase.CountKeysByNamespace(context.Background(), false, true)
+	assert.Equal(t, map[string]int{namespace: 2, "othernamespace": 1}, counts)
+	assert.NoError(t, err)
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(0))
 	defer cancel()
 	_, err = keybase.GetKeys(ctx, namespace, true, false)
 	assert.Error(t, err)
 	_, err = keybase.CountKeys(ctx, namespace, true, false)
 	assert.Error(t, err)
+	_, err = keybase.CountKeysByNamespace(ctx, true, false)
+	assert.Error(t, err)
 }
 
 func TestNamespaces(t *testing.T) {
@@ -161,12 +355,23 @@ func TestNamespaces(t *testing.T) {
 	assert.Equal(t, 3, count)
 	assert.NoError(t, err)
 
+	err = keybase.Put(context.Background(), "othernamespace", "key0")
+	assert.NoError(t, err)
+	namespaces, err = keybase.MatchNamespaces(context.Background(), "namespace?", true)
+	assert.ElementsMatch(t, []string{"namespace0", "namespace1", "namespace2"}, namespaces)
+	assert.NoError(t, err)
+	namespaces, err = keybase.MatchNamespaces(context.Background(), "*namespace", false)
+	assert.Equal(t, []string{"othernamespace"}, namespaces)
+	assert.NoError(t, err)
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(0))
 	defer cancel()
 	_, err = keybase.GetNamespaces(ctx, true)
 	assert.Error(t, err)
 	_, err = keybase.CountNamespaces(ctx, true)
 	assert.Error(t, err)
+	_, err = keybase.MatchNamespaces(ctx, "*", true)
+	assert.Error(t, err)
 }
 
 // TestEntries tests CountEntries, PruneEntries, and ClearEntries
@@ -230,6 +435,300 @@ func TestEntries(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestPruneEntriesBatched(t *testing.T) {
+	ctx := context.Background()
+	clock := &fixedClock{now: time.Now()}
+	buffer := bytes.Buffer{}
+	keybase, err := Open(ctx, WithClock(clock), WithTTL(time.Minute), WithArchiveExpired(), WithJournal(&buffer))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	assert.ErrorIs(t, keybase.PruneEntriesBatched(ctx, 0), ErrInvalidArgument)
+	for index := range 7 {
+		assert.NoError(t, keybase.Put(ctx, "namespace", fmt.Sprint("key", index)))
+	}
+	_, err = keybase.Increment(ctx, "namespace", "counter", 1)
+	assert.NoError(t, err)
+	clock.now = clock.now.Add(time.Hour)
+	assert.NoError(t, keybase.Put(ctx, "namespace", "active"))
+
+	assert.NoError(t, keybase.PruneEntriesBatched(ctx, 3))
+	stats, err := keybase.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Prunes.Runs)
+	assert.Equal(t, int64(7), stats.Prunes.Entries)
+	count, err := keybase.CountEntries(ctx, false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	archived, err := keybase.GetArchivedKeys(ctx, "namespace")
+	assert.NoError(t, err)
+	assert.Len(t, archived, 7)
+	counter, err := keybase.GetCounter(ctx, "namespace", "counter")
+	assert.NoError(t, err)
+	assert.Zero(t, counter)
+	assert.Equal(t, 1, strings.Count(buffer.String(), string(OpPruneEntries)))
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(0))
+	defer cancel()
+	assert.Error(t, keybase.PruneEntriesBatched(ctx, 3))
+}
+
+// TestNamespaceEntries tests PruneNamespace and ClearNamespace
+func TestNamespaceEntries(t *testing.T) {
+	ctx := context.Background()
+	keybase, err := Open(ctx, WithTTL(time.Millisecond*50))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	for _, namespace := range []string{"tenant0", "tenant1"} {
+		assert.NoError(t, keybase.Put(ctx, namespace, "key"))
+		_, err = keybase.Increment(ctx, namespace, "counter", 1)
+		assert.NoError(t, err)
+		assert.NoError(t, keybase.PutField(ctx, namespace, "key", "field", "value"))
+	}
+	time.Sleep(time.Millisecond * 50)
+
+	assert.NoError(t, keybase.PruneNamespace(ctx, "tenant0"))
+	count, err := keybase.CountEntries(ctx, false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	keys, err := keybase.GetKeys(ctx, "tenant1", false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key"}, keys)
+
+	for _, namespace := range []string{"tenant0", "tenant1"} {
+		assert.NoError(t, keybase.Put(ctx, namespace, "key"))
+		_, err = keybase.Increment(ctx, namespace, "counter", 1)
+		assert.NoError(t, err)
+		assert.NoError(t, keybase.PutField(ctx, namespace, "key", "field", "value"))
+	}
+	assert.NoError(t, keybase.ClearNamespace(ctx, "tenant0"))
+	count, err = keybase.CountKey(ctx, "tenant0", "key", false)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+	counter, err := keybase.GetCounter(ctx, "tenant0", "counter")
+	assert.NoError(t, err)
+	assert.Zero(t, counter)
+	fields, err := keybase.GetFields(ctx, "tenant0", "key")
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+
+	count, err = keybase.CountKey(ctx, "tenant1", "key", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	fields, err = keybase.GetFields(ctx, "tenant1", "key")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"field": "value"}, fields)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Error(t, keybase.PruneNamespace(cancelled, "tenant1"))
+	assert.Error(t, keybase.ClearNamespace(cancelled, "tenant1"))
+}
+
+// TestCopyNamespace tests CopyNamespace
+func TestCopyNamespace(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{{WithTTL(time.Minute)}, {WithTTL(time.Minute), WithChecksums(), WithEncryption(make([]byte, 32))}} {
+		keybase, err := Open(ctx, opts...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		assert.NoError(t, keybase.Put(ctx, "blue", "key0"))
+		assert.NoError(t, keybase.Put(ctx, "blue", "key0"))
+		assert.NoError(t, keybase.Put(ctx, "blue", "key1"))
+		expiration, err := keybase.GetExpiration(ctx, "blue", "key1")
+		assert.NoError(t, err)
+
+		copied, err := keybase.CopyNamespace(ctx, "blue", "green", false)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, copied)
+		keys, err := keybase.MatchKey(ctx, "green", "*", true, true)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"key0", "key1"}, keys)
+		copiedExpiration, err := keybase.GetExpiration(ctx, "green", "key1")
+		assert.NoError(t, err)
+		assert.Equal(t, expiration, copiedExpiration)
+
+		assert.NoError(t, keybase.Reconfigure(ctx, WithTTL(time.Hour)))
+		copied, err = keybase.CopyNamespace(ctx, "blue", "promoted", true)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, copied)
+		copiedExpiration, err = keybase.GetExpiration(ctx, "promoted", "key1")
+		assert.NoError(t, err)
+		assert.True(t, copiedExpiration.After(expiration.Add(time.Minute*30)))
+
+		copied, err = keybase.CopyNamespace(ctx, "missing", "green", false)
+		assert.NoError(t, err)
+		assert.Zero(t, copied)
+		if quarantined, err := keybase.VerifyChecksums(ctx); err == nil {
+			assert.Zero(t, quarantined)
+		}
+	}
+
+	keybase, err := Open(ctx, WithMaxEntries(3, RejectWrites))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.NoError(t, keybase.Put(ctx, "blue", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "blue", "key1"))
+	_, err = keybase.CopyNamespace(ctx, "blue", "green", false)
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	count, err := keybase.CountEntries(ctx, true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+// TestPutUntil tests PutUntil
+func TestPutUntil(t *testing.T) {
+	ctx := context.Background()
+	buffer := bytes.Buffer{}
+	keybase, err := Open(ctx, WithTTL(time.Minute), WithJournal(&buffer))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	until := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	assert.NoError(t, keybase.PutUntil(ctx, "namespace", "key0", until))
+	expiration, err := keybase.GetExpiration(ctx, "namespace", "key0")
+	assert.NoError(t, err)
+	assert.True(t, until.Equal(expiration))
+
+	assert.NoError(t, keybase.Tx(ctx, func(tx *KeybaseTx) error {
+		return tx.PutUntil(ctx, "namespace", "key1", until)
+	}))
+	batch, flush := keybase.WithBatch(ctx)
+	assert.NoError(t, keybase.PutUntil(batch, "namespace", "key2", until))
+	assert.NoError(t, flush())
+	assert.NoError(t, keybase.PutUntil(ctx, "namespace", "key3", time.Now().Add(-time.Second)))
+	keys, err := keybase.MatchKey(ctx, "namespace", "*", true, true)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"key0", "key1", "key2"}, keys)
+	for _, key := range keys {
+		expiration, err = keybase.GetExpiration(ctx, "namespace", key)
+		assert.NoError(t, err)
+		assert.True(t, until.Equal(expiration))
+	}
+
+	replayed, err := Open(ctx)
+	assert.NoError(t, err)
+	defer replayed.Close()
+	assert.NoError(t, ReplayJournal(ctx, replayed, &buffer))
+	expiration, err = replayed.GetExpiration(ctx, "namespace", "key1")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, until, expiration, time.Second)
+}
+
+func TestExpireMatch(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{{}, {WithChecksums(), WithOverflow(8)}, {WithEncryption(make([]byte, 32))}} {
+		buffer := bytes.Buffer{}
+		keybase, err := Open(ctx, append(opts, WithTTL(time.Minute), WithJournal(&buffer))...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+		for _, key := range []string{"user:0", "user:1", "user:1", "session:overflowing"} {
+			assert.NoError(t, keybase.Put(ctx, "namespace", key))
+		}
+		assert.NoError(t, keybase.Put(ctx, "other", "user:0"))
+
+		until := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+		updated, err := keybase.ExpireMatch(ctx, "namespace", "user:*", until)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, updated)
+		expiration, err := keybase.GetExpiration(ctx, "namespace", "user:1")
+		assert.NoError(t, err)
+		assert.True(t, until.Equal(expiration))
+
+		updated, err = keybase.ExpireMatch(ctx, "namespace", "*overflow*", time.Now().Add(-time.Second))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, updated)
+		keys, err := keybase.MatchKey(ctx, "namespace", "*", true, true)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"user:0", "user:1"}, keys)
+		count, err := keybase.CountKeys(ctx, "other", true, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		updated, err = keybase.ExpireMatch(ctx, "namespace", "missing*", until)
+		assert.NoError(t, err)
+		assert.Zero(t, updated)
+
+		replayed, err := Open(ctx, opts...)
+		assert.NoError(t, err)
+		defer replayed.Close()
+		assert.NoError(t, ReplayJournal(ctx, replayed, &buffer))
+		expiration, err = replayed.GetExpiration(ctx, "namespace", "user:0")
+		assert.NoError(t, err)
+		assert.WithinDuration(t, until, expiration, time.Second)
+	}
+}
+
+func TestDeleteMatch(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{{}, {WithChecksums(), WithOverflow(8), WithTombstones()}, {WithEncryption(make([]byte, 32))}} {
+		buffer := bytes.Buffer{}
+		clock := &fixedClock{now: time.Now()}
+		keybase, err := Open(ctx, append(opts, WithClock(clock), WithJournal(&buffer))...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+		for _, key := range []string{"tmp:0", "tmp:1", "tmp:1", "user:overflowing"} {
+			assert.NoError(t, keybase.Put(ctx, "namespace", key))
+		}
+		assert.NoError(t, keybase.PutUntil(ctx, "namespace", "tmp:2", clock.now.Add(-time.Second)))
+		assert.NoError(t, keybase.Put(ctx, "other", "tmp:0"))
+
+		deleted, err := keybase.DeleteMatch(ctx, "namespace", "tmp:*", true)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, deleted)
+		count, err := keybase.CountEntries(ctx, false, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 6, count)
+
+		deleted, err = keybase.DeleteMatch(ctx, "namespace", "tmp:*", false)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, deleted)
+		keys, err := keybase.GetKeys(ctx, "namespace", false, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"user:overflowing"}, keys)
+		count, err = keybase.CountKeys(ctx, "other", true, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		deleted, err = keybase.DeleteMatch(ctx, "namespace", "tmp:*", false)
+		assert.NoError(t, err)
+		assert.Zero(t, deleted)
+		_, err = keybase.DeleteMatch(ctx, "namespace", "", true)
+		assert.ErrorIs(t, err, ErrInvalidPattern)
+
+		replayed, err := Open(ctx, opts...)
+		assert.NoError(t, err)
+		defer replayed.Close()
+		assert.NoError(t, replayed.Put(ctx, "namespace", "tmp:3"))
+		assert.NoError(t, ReplayJournal(ctx, replayed, &buffer))
+		keys, err = replayed.GetKeys(ctx, "namespace", false, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"user:overflowing"}, keys)
+	}
+}
+
+func TestTTLJitter(t *testing.T) {
+	ctx := context.Background()
+	clock := &fixedClock{now: time.Now().Truncate(time.Millisecond)}
+	keybase, err := Open(ctx, WithClock(clock), WithTTL(time.Hour), WithTTLJitter(0.1))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	expirations := map[time.Time]bool{}
+	for key := 0; key < 20; key++ {
+		assert.NoError(t, keybase.Put(ctx, "namespace", fmt.Sprintf("key%d", key)))
+		expiration, err := keybase.GetExpiration(ctx, "namespace", fmt.Sprintf("key%d", key))
+		assert.NoError(t, err)
+		assert.WithinDuration(t, clock.now.Add(time.Hour), expiration, 6*time.Minute)
+		expirations[expiration] = true
+	}
+	assert.Greater(t, len(expirations), 1)
+
+	for _, fraction := range []float64{-0.1, 1} {
+		_, err = Open(ctx, WithTTLJitter(fraction))
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+	}
+}
+
 // TestStorage tests filesystem
 func TestStorage(t *testing.T) {
 	storageDirectory, _ := os.MkdirTemp(os.TempDir(), "keybase-*")
@@ -257,9 +756,173 @@ func TestStorage(t *testing.T) {
 	}
 
 	_, err := Open(context.Background(), WithStorage(storageDirectory))
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrInvalidStorage)
 
 	initAndStore(context.Background())
 	count := loadAndCount(context.Background())
 	assert.Equal(t, 9, count)
 }
+
+func TestConcurrency(t *testing.T) {
+	storageDirectory, _ := os.MkdirTemp(os.TempDir(), "keybase-*")
+	defer os.RemoveAll(storageDirectory)
+	configs := map[string][]Option{
+		"memory":     {},
+		"persistent": {WithStorage(path.Join(storageDirectory, "keybase.db")), WithWAL()},
+		"serialized": {WithStorage(path.Join(storageDirectory, "serialized.db")), WithSerialization()},
+	}
+	for name, opts := range configs {
+		t.Run(name, func(t *testing.T) {
+			keybase, err := Open(context.Background(), opts...)
+			assert.NoError(t, err)
+			defer keybase.Close()
+			wg := sync.WaitGroup{}
+			for worker := 0; worker < 8; worker++ {
+				wg.Add(1)
+				go func(worker int) {
+					defer wg.Done()
+					for key := 0; key < 25; key++ {
+						assert.NoError(t, keybase.Put(context.Background(), "namespace", fmt.Sprintf("key%d-%d", worker, key)))
+						assert.NoError(t, keybase.PutField(context.Background(), "namespace", "fields", fmt.Sprintf("field%d", worker), "value"))
+						_, err := keybase.CountKeys(context.Background(), "namespace", true, true)
+						assert.NoError(t, err)
+					}
+				}(worker)
+			}
+			wg.Wait()
+			count, err := keybase.CountKeys(context.Background(), "namespace", true, true)
+			assert.NoError(t, err)
+			assert.Equal(t, 200, count)
+		})
+	}
+}
+
+func TestIsMemory(t *testing.T) {
+	assert.True(t, isMemory(":memory:"))
+	assert.True(t, isMemory("file::memory:?cache=shared"))
+	assert.True(t, isMemory("file:keybase?mode=memory"))
+	assert.False(t, isMemory("/tmp/keybase.db"))
+}
+
+func benchmarkKeybase(b *testing.B, opts ...Option) {
+	storageDirectory, _ := os.MkdirTemp(os.TempDir(), "keybase-*")
+	defer os.RemoveAll(storageDirectory)
+	opts = append([]Option{WithStorage(path.Join(storageDirectory, "keybase.db")), WithWAL(), WithSynchronous("NORMAL")}, opts...)
+	keybase, err := Open(context.Background(), opts...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer keybase.Close()
+	for key := 0; key < 1000; key++ {
+		_ = keybase.Put(context.Background(), "namespace", fmt.Sprintf("key%d", key))
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if i%4 == 0 {
+				_ = keybase.Put(context.Background(), "namespace", fmt.Sprintf("key%d", i%1000))
+			} else {
+				_, _ = keybase.CountKey(context.Background(), "namespace", fmt.Sprintf("key%d", i%1000), true)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkConcurrent(b *testing.B) {
+	benchmarkKeybase(b)
+}
+
+func BenchmarkSerialized(b *testing.B) {
+	benchmarkKeybase(b, WithSerialization())
+}
+
+func TestClosed(t *testing.T) {
+	keybase, err := Open(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.Close())
+	assert.NoError(t, keybase.Close())
+
+	ctx := context.Background()
+	assert.ErrorIs(t, keybase.Put(ctx, "namespace", "key"), ErrClosed)
+	_, err = keybase.MatchKey(ctx, "namespace", "*", true, true)
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = keybase.CountKey(ctx, "namespace", "key", true)
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = keybase.GetKeys(ctx, "namespace", true, true)
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = keybase.CountKeys(ctx, "namespace", true, true)
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = keybase.GetNamespaces(ctx, true)
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = keybase.CountNamespaces(ctx, true)
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = keybase.CountEntries(ctx, true, true)
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.ErrorIs(t, keybase.PruneEntries(ctx), ErrClosed)
+	assert.ErrorIs(t, keybase.ClearEntries(ctx), ErrClosed)
+
+	keybase, err = Open(context.Background())
+	assert.NoError(t, err)
+	wg := sync.WaitGroup{}
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				err := keybase.Put(ctx, "namespace", "key")
+				if err != nil {
+					assert.ErrorIs(t, err, ErrClosed)
+					return
+				}
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond * 10)
+	assert.NoError(t, keybase.Close())
+	wg.Wait()
+}
+
+func TestIntervals(t *testing.T) {
+	ctx := context.Background()
+	for _, opt := range []Option{
+		WithAutoPrune(0),
+		WithDuplicateCompaction(-time.Second, KeepLatest),
+		WithColdTier(time.Minute, 0),
+		WithScheduledExport("*", 0, t.TempDir(), JSONCodec{}),
+		WithChangePolling(0),
+	} {
+		_, err := Open(ctx, opt)
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	keybase, err := Open(context.Background())
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	err = keybase.Reconfigure(context.Background(), WithStorage("keybase.db"))
+	assert.ErrorIs(t, err, ErrUnsupportedOption)
+	err = keybase.Reconfigure(context.Background(), WithAutoPrune(0))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	err = keybase.Reconfigure(context.Background(), WithTTL(time.Millisecond), WithAutoPrune(time.Millisecond*5))
+	assert.NoError(t, err)
+	assert.True(t, keybase.AutoPrune().Status().Running)
+	assert.Equal(t, time.Millisecond*5, keybase.AutoPrune().Status().Interval)
+
+	err = keybase.Put(context.Background(), "namespace", "key")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		count, err := keybase.CountEntries(context.Background(), false, false)
+		return err == nil && count == 0
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(0))
+	defer cancel()
+	assert.NoError(t, keybase.Close())
+	err = keybase.Reconfigure(ctx, WithTTL(time.Second))
+	assert.ErrorIs(t, err, ErrClosed)
+}
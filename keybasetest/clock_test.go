@@ -0,0 +1,54 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybasetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maxtek6/keybase-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+	assert.Equal(t, start, clock.Now())
+	clock.Advance(time.Minute)
+	assert.Equal(t, start.Add(time.Minute), clock.Now())
+	clock.Set(start)
+	assert.Equal(t, start, clock.Now())
+
+	ctx := context.Background()
+	kb, err := keybase.Open(ctx, keybase.WithClock(clock), keybase.WithTTL(time.Minute))
+	assert.NoError(t, err)
+	defer kb.Close()
+	assert.NoError(t, kb.Put(ctx, "namespace", "key0"))
+	expiration, err := kb.GetExpiration(ctx, "namespace", "key0")
+	assert.NoError(t, err)
+	assert.True(t, start.Add(time.Minute).Equal(expiration))
+
+	clock.Advance(time.Minute)
+	count, err := kb.CountKey(ctx, "namespace", "key0", true)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,586 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybasetest
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maxtek6/keybase-go"
+)
+
+type fakeEntry struct {
+	namespace  string
+	key        string
+	expiration time.Time
+	inserted   time.Time
+}
+
+// Fake in-memory keybase.Store implementing the entry methods without a
+// database. Other methods are delegated to the embedded Store, which may be
+// set to a real keybase and is nil by default.
+type Fake struct {
+	keybase.Store
+	mu      sync.Mutex
+	ttl     time.Duration
+	clock   keybase.Clock
+	entries []fakeEntry
+	closed  bool
+}
+
+var _ keybase.Store = (*Fake)(nil)
+
+// NewFake creates an empty fake whose entries expire after ttl, as told by
+// clock, or by the system time if clock is nil
+func NewFake(ttl time.Duration, clock keybase.Clock) *Fake {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	return &Fake{ttl: ttl, clock: clock}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Put inserts new value. Options such as tags are ignored.
+func (f *Fake) Put(ctx context.Context, namespace, key string, _ ...keybase.PutOption) error {
+	return f.PutUntil(ctx, namespace, key, f.clock.Now().Add(f.ttl))
+}
+
+// PutAsync inserts new value, returning a channel that already holds the
+// result
+func (f *Fake) PutAsync(ctx context.Context, namespace, key string) <-chan error {
+	result := make(chan error, 1)
+	result <- f.Put(ctx, namespace, key)
+	return result
+}
+
+// PutUntil inserts new value that expires at until
+func (f *Fake) PutUntil(ctx context.Context, namespace, key string, until time.Time) error {
+	return f.do(ctx, "PutUntil", func(now time.Time) error {
+		f.entries = append(f.entries, fakeEntry{namespace: namespace, key: key, expiration: until, inserted: now})
+		return nil
+	})
+}
+
+// PutIfAbsent inserts new value only if the key has no active entries
+func (f *Fake) PutIfAbsent(ctx context.Context, namespace, key string) (bool, error) {
+	inserted := false
+	err := f.do(ctx, "PutIfAbsent", func(now time.Time) error {
+		if len(f.filter(namespace, key, nil, true, now)) > 0 {
+			return nil
+		}
+		f.entries = append(f.entries, fakeEntry{namespace: namespace, key: key, expiration: now.Add(f.ttl), inserted: now})
+		inserted = true
+		return nil
+	})
+	return inserted, err
+}
+
+// Seen reports whether the key has active entries, inserting one if not
+func (f *Fake) Seen(ctx context.Context, namespace, key string) (bool, error) {
+	inserted, err := f.PutIfAbsent(ctx, namespace, key)
+	return err == nil && !inserted, err
+}
+
+var (
+	partEscaper   = strings.NewReplacer("%", "%25", "/", "%2F")
+	partUnescaper = strings.NewReplacer("%25", "%", "%2F", "/")
+	escapedPart   = regexp.MustCompile(`^([^%]|%25|%2F)*$`)
+)
+
+// PutParts inserts new value for a key composed of ordered parts, encoded as
+// the keybase does
+func (f *Fake) PutParts(ctx context.Context, namespace string, parts ...string) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("keybasetest.Fake.PutParts: %w: key must have at least one part", keybase.ErrInvalidArgument)
+	}
+	escaped := make([]string, len(parts))
+	for index, part := range parts {
+		escaped[index] = partEscaper.Replace(part)
+	}
+	return f.Put(ctx, namespace, strings.Join(escaped, "/"))
+}
+
+// MatchParts collects the composite keys of a namespace that have as many
+// parts as patterns, each part matching its pattern
+func (f *Fake) MatchParts(ctx context.Context, namespace string, active, unique bool, patterns ...string) ([][]string, error) {
+	matched := [][]string{}
+	err := f.do(ctx, "MatchParts", func(now time.Time) error {
+		if len(patterns) == 0 {
+			return fmt.Errorf("%w: key must have at least one part", keybase.ErrInvalidArgument)
+		}
+		matchers := make([]*regexp.Regexp, len(patterns))
+		for index, pattern := range patterns {
+			matcher, err := globPattern(pattern)
+			if err != nil {
+				return err
+			}
+			matchers[index] = matcher
+		}
+		for _, key := range keysOf(f.filter(namespace, "", nil, active, now), unique) {
+			parts := strings.Split(key, "/")
+			if len(parts) != len(matchers) {
+				continue
+			}
+			matches := true
+			for index, part := range parts {
+				parts[index] = partUnescaper.Replace(part)
+				matches = matches && escapedPart.MatchString(part) && matchers[index].MatchString(parts[index])
+			}
+			if matches {
+				matched = append(matched, parts)
+			}
+		}
+		return nil
+	})
+	return matched, err
+}
+
+// MatchKey searches the namespace for keys matching the pattern. Keys are
+// returned in insertion order and query options are ignored.
+func (f *Fake) MatchKey(ctx context.Context, namespace, pattern string, active, unique bool, _ ...keybase.QueryOption) ([]string, error) {
+	var keys []string
+	err := f.do(ctx, "MatchKey", func(now time.Time) error {
+		matcher, err := globPattern(pattern)
+		if err != nil {
+			return err
+		}
+		keys = keysOf(f.filter(namespace, "", matcher, active, now), unique)
+		return nil
+	})
+	return keys, err
+}
+
+// MatchKeyAcross searches the namespaces, or every namespace when none are
+// given, for keys matching the pattern
+func (f *Fake) MatchKeyAcross(ctx context.Context, namespaces []string, pattern string, active, unique bool) ([]keybase.NamespacedKey, error) {
+	keys := []keybase.NamespacedKey{}
+	err := f.do(ctx, "MatchKeyAcross", func(now time.Time) error {
+		matcher, err := globPattern(pattern)
+		if err != nil {
+			return err
+		}
+		namespaces := slices.Clone(namespaces)
+		if len(namespaces) == 0 {
+			namespaces = f.namespaces(active, now)
+		}
+		slices.Sort(namespaces)
+		for _, namespace := range slices.Compact(namespaces) {
+			for _, key := range keysOf(f.filter(namespace, "", matcher, active, now), unique) {
+				keys = append(keys, keybase.NamespacedKey{Namespace: namespace, Key: key})
+			}
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// MatchKeyAny searches the namespace for keys matching any of the patterns
+func (f *Fake) MatchKeyAny(ctx context.Context, namespace string, patterns []string, active, unique bool) ([]string, error) {
+	var keys []string
+	err := f.do(ctx, "MatchKeyAny", func(now time.Time) error {
+		if len(patterns) == 0 {
+			return fmt.Errorf("%w: no patterns", keybase.ErrInvalidArgument)
+		}
+		expressions := make([]string, len(patterns))
+		for i, pattern := range patterns {
+			matcher, err := globPattern(pattern)
+			if err != nil {
+				return err
+			}
+			expressions[i] = matcher.String()
+		}
+		matcher := regexp.MustCompile(strings.Join(expressions, "|"))
+		keys = keysOf(f.filter(namespace, "", matcher, active, now), unique)
+		return nil
+	})
+	return keys, err
+}
+
+// CountMatch counts the keys matching the pattern
+func (f *Fake) CountMatch(ctx context.Context, namespace, pattern string, active, unique bool) (int, error) {
+	keys, err := f.MatchKey(ctx, namespace, pattern, active, unique)
+	return len(keys), err
+}
+
+// CountKey counts the entries of a key
+func (f *Fake) CountKey(ctx context.Context, namespace, key string, active bool) (int, error) {
+	count := 0
+	err := f.do(ctx, "CountKey", func(now time.Time) error {
+		count = len(f.filter(namespace, key, nil, active, now))
+		return nil
+	})
+	return count, err
+}
+
+// GetExpiration gets the time at which the last active entry of a key
+// expires, returning keybase.ErrNotFound if the key has no active entries
+func (f *Fake) GetExpiration(ctx context.Context, namespace, key string) (time.Time, error) {
+	var expiration time.Time
+	err := f.do(ctx, "GetExpiration", func(now time.Time) error {
+		entries := f.filter(namespace, key, nil, true, now)
+		if len(entries) == 0 {
+			return keybase.ErrNotFound
+		}
+		for _, entry := range entries {
+			if entry.expiration.After(expiration) {
+				expiration = entry.expiration
+			}
+		}
+		return nil
+	})
+	return expiration, err
+}
+
+// GetTTL gets the remaining duration until the last active entry of a key
+// expires
+func (f *Fake) GetTTL(ctx context.Context, namespace, key string) (time.Duration, error) {
+	expiration, err := f.GetExpiration(ctx, namespace, key)
+	if err != nil {
+		return 0, err
+	}
+	return expiration.Sub(f.clock.Now()), nil
+}
+
+// ExpireMatch sets the expiration of every entry with a key matching the
+// pattern
+func (f *Fake) ExpireMatch(ctx context.Context, namespace, pattern string, at time.Time) (int, error) {
+	updated := 0
+	err := f.do(ctx, "ExpireMatch", func(now time.Time) error {
+		matcher, err := globPattern(pattern)
+		if err != nil {
+			return err
+		}
+		for index := range f.entries {
+			if f.entries[index].namespace == namespace && matcher.MatchString(f.entries[index].key) {
+				f.entries[index].expiration = at
+				updated++
+			}
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// DeleteMatch removes every entry with a key matching the pattern, returning
+// the number of entries removed. A dry run only counts them.
+func (f *Fake) DeleteMatch(ctx context.Context, namespace, pattern string, dryRun bool) (int, error) {
+	deleted := 0
+	err := f.do(ctx, "DeleteMatch", func(now time.Time) error {
+		matcher, err := globPattern(pattern)
+		if err != nil {
+			return err
+		}
+		deleted = len(f.filter(namespace, "", matcher, false, now))
+		if !dryRun {
+			f.entries = slices.DeleteFunc(f.entries, func(entry fakeEntry) bool {
+				return entry.namespace == namespace && matcher.MatchString(entry.key)
+			})
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+// GetKeys collects the keys of a namespace. Keys are returned in insertion
+// order and query options are ignored.
+func (f *Fake) GetKeys(ctx context.Context, namespace string, active, unique bool, _ ...keybase.QueryOption) ([]string, error) {
+	var keys []string
+	err := f.do(ctx, "GetKeys", func(now time.Time) error {
+		keys = keysOf(f.filter(namespace, "", nil, active, now), unique)
+		return nil
+	})
+	return keys, err
+}
+
+// Scan collects up to count active keys of a namespace in ascending order,
+// starting from the cursor returned by the previous call
+func (f *Fake) Scan(ctx context.Context, namespace, cursor string, count int) ([]string, string, error) {
+	if count <= 0 {
+		return nil, "", fmt.Errorf("keybasetest.Fake.Scan: %w: count must be positive", keybase.ErrInvalidArgument)
+	}
+	lower, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, "", fmt.Errorf("keybasetest.Fake.Scan: %w: invalid cursor %q", keybase.ErrInvalidArgument, cursor)
+	}
+	var keys []string
+	next := ""
+	err = f.do(ctx, "Scan", func(now time.Time) error {
+		keys = slices.DeleteFunc(keysOf(f.filter(namespace, "", nil, true, now), true), func(key string) bool {
+			return key < string(lower)
+		})
+		slices.Sort(keys)
+		if len(keys) > count {
+			next = base64.RawURLEncoding.EncodeToString([]byte(keys[count]))
+			keys = keys[:count]
+		}
+		return nil
+	})
+	return keys, next, err
+}
+
+// SampleKeys collects up to n distinct keys of a namespace chosen at random
+func (f *Fake) SampleKeys(ctx context.Context, namespace string, n int, active bool) ([]string, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("keybasetest.Fake.SampleKeys: %w: n must be positive", keybase.ErrInvalidArgument)
+	}
+	var keys []string
+	err := f.do(ctx, "SampleKeys", func(now time.Time) error {
+		keys = keysOf(f.filter(namespace, "", nil, active, now), true)
+		rand.Shuffle(len(keys), func(i, j int) {
+			keys[i], keys[j] = keys[j], keys[i]
+		})
+		keys = keys[:min(n, len(keys))]
+		return nil
+	})
+	return keys, err
+}
+
+// GetKeysInsertedBetween collects the keys of a namespace that were inserted
+// at or after from and before to, in the order they first arrived
+func (f *Fake) GetKeysInsertedBetween(ctx context.Context, namespace string, from, to time.Time) ([]string, error) {
+	var keys []string
+	err := f.do(ctx, "GetKeysInsertedBetween", func(now time.Time) error {
+		entries := slices.DeleteFunc(f.filter(namespace, "", nil, false, now), func(entry fakeEntry) bool {
+			return entry.inserted.UnixMilli() < from.UnixMilli() || entry.inserted.UnixMilli() >= to.UnixMilli()
+		})
+		keys = keysOf(entries, true)
+		return nil
+	})
+	return keys, err
+}
+
+// CountKeys counts the keys of a namespace
+func (f *Fake) CountKeys(ctx context.Context, namespace string, active, unique bool) (int, error) {
+	keys, err := f.GetKeys(ctx, namespace, active, unique)
+	return len(keys), err
+}
+
+// CountKeysByNamespace counts the keys of every namespace
+func (f *Fake) CountKeysByNamespace(ctx context.Context, active, unique bool) (map[string]int, error) {
+	counts := map[string]int{}
+	err := f.do(ctx, "CountKeysByNamespace", func(now time.Time) error {
+		for _, namespace := range f.namespaces(active, now) {
+			counts[namespace] = len(keysOf(f.filter(namespace, "", nil, active, now), unique))
+		}
+		return nil
+	})
+	return counts, err
+}
+
+// GetNamespaces collects the namespaces
+func (f *Fake) GetNamespaces(ctx context.Context, active bool) ([]string, error) {
+	var namespaces []string
+	err := f.do(ctx, "GetNamespaces", func(now time.Time) error {
+		namespaces = f.namespaces(active, now)
+		return nil
+	})
+	return namespaces, err
+}
+
+// MatchNamespaces collects the namespaces matching the pattern
+func (f *Fake) MatchNamespaces(ctx context.Context, pattern string, active bool) ([]string, error) {
+	matcher, err := globPattern(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("keybasetest.Fake.MatchNamespaces: %w", err)
+	}
+	namespaces, err := f.GetNamespaces(ctx, active)
+	return slices.DeleteFunc(namespaces, func(namespace string) bool {
+		return !matcher.MatchString(namespace)
+	}), err
+}
+
+// CountNamespaces counts the namespaces
+func (f *Fake) CountNamespaces(ctx context.Context, active bool) (int, error) {
+	namespaces, err := f.GetNamespaces(ctx, active)
+	return len(namespaces), err
+}
+
+// CountEntries counts the keys of every namespace
+func (f *Fake) CountEntries(ctx context.Context, active, unique bool) (int, error) {
+	count := 0
+	err := f.do(ctx, "CountEntries", func(now time.Time) error {
+		for _, namespace := range f.namespaces(active, now) {
+			count += len(keysOf(f.filter(namespace, "", nil, active, now), unique))
+		}
+		return nil
+	})
+	return count, err
+}
+
+// PruneEntries removes expired entries
+func (f *Fake) PruneEntries(ctx context.Context) error {
+	return f.remove(ctx, "PruneEntries", func(entry fakeEntry, now time.Time) bool {
+		return !entry.expiration.After(now)
+	})
+}
+
+// PruneEntriesBatched removes expired entries, all at once since there is no
+// database to hold up
+func (f *Fake) PruneEntriesBatched(ctx context.Context, batchSize int) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("keybasetest.Fake.PruneEntriesBatched: %w: batch size must be positive", keybase.ErrInvalidArgument)
+	}
+	return f.remove(ctx, "PruneEntriesBatched", func(entry fakeEntry, now time.Time) bool {
+		return !entry.expiration.After(now)
+	})
+}
+
+// PruneNamespace removes the expired entries of a namespace
+func (f *Fake) PruneNamespace(ctx context.Context, namespace string) error {
+	return f.remove(ctx, "PruneNamespace", func(entry fakeEntry, now time.Time) bool {
+		return entry.namespace == namespace && !entry.expiration.After(now)
+	})
+}
+
+// ClearEntries removes every entry
+func (f *Fake) ClearEntries(ctx context.Context) error {
+	return f.remove(ctx, "ClearEntries", func(fakeEntry, time.Time) bool {
+		return true
+	})
+}
+
+// ClearNamespace removes every entry of a namespace
+func (f *Fake) ClearNamespace(ctx context.Context, namespace string) error {
+	return f.remove(ctx, "ClearNamespace", func(entry fakeEntry, _ time.Time) bool {
+		return entry.namespace == namespace
+	})
+}
+
+// Close marks the fake as closed, so later calls fail with keybase.ErrClosed
+func (f *Fake) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+// Shutdown closes the fake, as there is nothing in flight to wait for
+func (f *Fake) Shutdown(ctx context.Context) error {
+	return f.Close()
+}
+
+func (f *Fake) do(ctx context.Context, method string, fn func(now time.Time) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := ctx.Err()
+	if err == nil && f.closed {
+		err = keybase.ErrClosed
+	}
+	if err == nil {
+		err = fn(f.clock.Now())
+	}
+	if err != nil {
+		return fmt.Errorf("keybasetest.Fake.%s: %w", method, err)
+	}
+	return nil
+}
+
+func (f *Fake) remove(ctx context.Context, method string, match func(entry fakeEntry, now time.Time) bool) error {
+	return f.do(ctx, method, func(now time.Time) error {
+		f.entries = slices.DeleteFunc(f.entries, func(entry fakeEntry) bool {
+			return match(entry, now)
+		})
+		return nil
+	})
+}
+
+// filter selects the entries of a namespace, limited to a key or the keys
+// matching a pattern when given
+func (f *Fake) filter(namespace, key string, matcher *regexp.Regexp, active bool, now time.Time) []fakeEntry {
+	entries := []fakeEntry{}
+	for _, entry := range f.entries {
+		if entry.namespace != namespace || key != "" && entry.key != key {
+			continue
+		}
+		if matcher != nil && !matcher.MatchString(entry.key) {
+			continue
+		}
+		if active && !entry.expiration.After(now) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func (f *Fake) namespaces(active bool, now time.Time) []string {
+	namespaces := []string{}
+	for _, entry := range f.entries {
+		if active && !entry.expiration.After(now) {
+			continue
+		}
+		if !slices.Contains(namespaces, entry.namespace) {
+			namespaces = append(namespaces, entry.namespace)
+		}
+	}
+	return namespaces
+}
+
+func keysOf(entries []fakeEntry, unique bool) []string {
+	keys := []string{}
+	for _, entry := range entries {
+		if !unique || !slices.Contains(keys, entry.key) {
+			keys = append(keys, entry.key)
+		}
+	}
+	return keys
+}
+
+// globPattern matches the * and ? wildcards case-insensitively, as the
+// keybase does, rejecting the patterns the keybase rejects
+func globPattern(pattern string) (*regexp.Regexp, error) {
+	err := keybase.ValidatePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	expression := strings.Builder{}
+	expression.WriteString("(?is)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			expression.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			expression.WriteString(".*")
+		case r == '?':
+			expression.WriteString(".")
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expression.WriteString("$")
+	return regexp.MustCompile(expression.String()), nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybasetest
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/maxtek6/keybase-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFake runs the same calls against the fake and a real keybase, which
+// must agree
+func TestFake(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now().Truncate(time.Millisecond)
+	clock := NewClock(start)
+	stores := map[string]keybase.Store{
+		"fake":    NewFake(time.Minute, clock),
+		"keybase": NewTestKeybase(t, keybase.WithClock(clock), keybase.WithTTL(time.Minute)),
+	}
+	results := map[string][]any{}
+	for name, store := range stores {
+		record := func(values ...any) {
+			results[name] = append(results[name], values...)
+		}
+		clock.Set(start)
+		assert.NoError(t, store.Put(ctx, "namespace", "key0"))
+		assert.NoError(t, store.Put(ctx, "namespace", "key0"))
+		assert.NoError(t, store.PutUntil(ctx, "namespace", "Key1", start.Add(time.Hour)))
+		assert.NoError(t, store.PutUntil(ctx, "other", "key0", start.Add(-time.Second)))
+		record(store.PutIfAbsent(ctx, "namespace", "key0"))
+		record(store.PutIfAbsent(ctx, "namespace", "key2"))
+		record(store.Seen(ctx, "namespace", "key2"))
+		record(store.MatchKey(ctx, "namespace", "KEY?", true, true))
+		record(store.MatchKeyAcross(ctx, nil, "key0", false, true))
+		record(store.MatchKeyAny(ctx, "namespace", []string{"key0", "KEY1", "nope*"}, true, true))
+		assert.NoError(t, store.Put(ctx, "inserted", "key0"))
+		clock.Advance(time.Second)
+		assert.NoError(t, store.Put(ctx, "inserted", "key1"))
+		assert.NoError(t, store.Put(ctx, "inserted", "key0"))
+		record(store.GetKeysInsertedBetween(ctx, "inserted", start, start.Add(time.Second)))
+		record(store.GetKeysInsertedBetween(ctx, "inserted", start, start.Add(time.Minute)))
+		record(store.GetKeysInsertedBetween(ctx, "inserted", start.Add(time.Second), start.Add(time.Minute)))
+		assert.NoError(t, store.ClearNamespace(ctx, "inserted"))
+		clock.Set(start)
+		assert.NoError(t, store.Put(ctx, "namespace", "key*"))
+		record(store.MatchKey(ctx, "namespace", `key\*`, true, true))
+		record(store.MatchKey(ctx, "namespace", "key%", true, true))
+		_, err := store.MatchKey(ctx, "namespace", `key\`, true, true)
+		assert.ErrorIs(t, err, keybase.ErrInvalidPattern)
+		_, err = store.MatchNamespaces(ctx, "", true)
+		assert.ErrorIs(t, err, keybase.ErrInvalidPattern)
+		assert.NoError(t, store.PutParts(ctx, "parts", "user/1", "DEVICE", "session%"))
+		assert.NoError(t, store.PutParts(ctx, "parts", "user", "device"))
+		assert.NoError(t, store.Put(ctx, "parts", "user/%zz/device"))
+		record(store.MatchParts(ctx, "parts", true, true, "user*", "device", "*"))
+		record(store.MatchParts(ctx, "parts", true, true, "*", "*"))
+		assert.ErrorIs(t, store.PutParts(ctx, "parts"), keybase.ErrInvalidArgument)
+		_, err = store.MatchParts(ctx, "parts", true, true)
+		assert.ErrorIs(t, err, keybase.ErrInvalidArgument)
+		assert.NoError(t, store.ClearNamespace(ctx, "parts"))
+		record(store.CountKey(ctx, "namespace", "key0", true))
+		record(store.GetExpiration(ctx, "namespace", "Key1"))
+		record(store.GetTTL(ctx, "namespace", "key0"))
+		record(store.CountKeys(ctx, "namespace", true, false))
+		keys, cursor, err := store.Scan(ctx, "namespace", "", 2)
+		record(keys, cursor, err)
+		record(store.Scan(ctx, "namespace", cursor, 2))
+		_, _, err = store.Scan(ctx, "namespace", "", 0)
+		assert.ErrorIs(t, err, keybase.ErrInvalidArgument)
+		keys, err = store.SampleKeys(ctx, "namespace", 2, true)
+		assert.NoError(t, err)
+		assert.Len(t, keys, 2)
+		keys, err = store.SampleKeys(ctx, "namespace", 10, true)
+		slices.Sort(keys)
+		record(keys, err)
+		_, err = store.SampleKeys(ctx, "namespace", 0, true)
+		assert.ErrorIs(t, err, keybase.ErrInvalidArgument)
+		record(store.CountKeysByNamespace(ctx, false, true))
+		record(store.MatchNamespaces(ctx, "oth*", false))
+		record(store.CountNamespaces(ctx, true))
+		record(store.CountEntries(ctx, false, true))
+		record(store.ExpireMatch(ctx, "namespace", "key*", start.Add(-time.Second)))
+		record(store.GetKeys(ctx, "namespace", true, true))
+		record(store.DeleteMatch(ctx, "namespace", "KEY1", true))
+		record(store.DeleteMatch(ctx, "namespace", "key2", false))
+		record(store.GetKeys(ctx, "namespace", false, true))
+		_, err = store.DeleteMatch(ctx, "namespace", "", false)
+		assert.ErrorIs(t, err, keybase.ErrInvalidPattern)
+		clock.Advance(time.Minute)
+		assert.NoError(t, store.PruneNamespace(ctx, "other"))
+		record(store.GetNamespaces(ctx, false))
+		assert.ErrorIs(t, store.PruneEntriesBatched(ctx, 0), keybase.ErrInvalidArgument)
+		assert.NoError(t, store.PruneEntriesBatched(ctx, 1))
+		record(store.CountEntries(ctx, false, false))
+		assert.NoError(t, store.PruneEntries(ctx))
+		record(store.CountEntries(ctx, false, false))
+		assert.NoError(t, store.ClearNamespace(ctx, "namespace"))
+		assert.NoError(t, store.Put(ctx, "namespace", "key0"))
+		assert.NoError(t, store.ClearEntries(ctx))
+		record(store.CountEntries(ctx, false, false))
+		_, err = store.GetExpiration(ctx, "namespace", "key0")
+		assert.ErrorIs(t, err, keybase.ErrNotFound)
+		assert.NoError(t, store.Close())
+		assert.ErrorIs(t, store.Put(ctx, "namespace", "key0"), keybase.ErrClosed)
+	}
+	assert.Equal(t, results["keybase"], results["fake"])
+
+	fake := NewFake(time.Minute, nil)
+	fake.Store = NewTestKeybase(t)
+	_, err := fake.Increment(ctx, "namespace", "counter", 1)
+	assert.NoError(t, err)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, fake.Put(cancelled, "namespace", "key0"), context.Canceled)
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package keybasetest provides fixtures, assertions and a fake clock for
+// testing code built on a keybase.
+package keybasetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maxtek6/keybase-go"
+)
+
+// Fixture entry seeded by SeedEntries. A zero Expiration uses the TTL of the
+// keybase, and one in the past seeds an expired entry.
+type Fixture struct {
+	Namespace  string
+	Key        string
+	Expiration time.Time
+}
+
+// NewTestKeybase opens an in-memory keybase with the given options that is
+// closed when the test finishes
+func NewTestKeybase(t testing.TB, opts ...keybase.Option) *keybase.Keybase {
+	t.Helper()
+	kb, err := keybase.Open(context.Background(), append([]keybase.Option{keybase.WithStorage(":memory:")}, opts...)...)
+	if err != nil {
+		t.Fatalf("keybasetest: failed to open keybase: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = kb.Close()
+	})
+	return kb
+}
+
+// SeedEntries inserts the fixtures in order, failing the test if any insert
+// fails
+func SeedEntries(t testing.TB, kb *keybase.Keybase, fixtures []Fixture) {
+	t.Helper()
+	ctx := context.Background()
+	for _, fixture := range fixtures {
+		var err error
+		if fixture.Expiration.IsZero() {
+			err = kb.Put(ctx, fixture.Namespace, fixture.Key)
+		} else {
+			err = kb.PutUntil(ctx, fixture.Namespace, fixture.Key, fixture.Expiration)
+		}
+		if err != nil {
+			t.Fatalf("keybasetest: failed to seed %s/%s: %v", fixture.Namespace, fixture.Key, err)
+		}
+	}
+}
+
+// AssertKeyActive reports an error unless the key has an active entry
+func AssertKeyActive(t testing.TB, kb *keybase.Keybase, namespace, key string) bool {
+	t.Helper()
+	count, ok := countKey(t, kb, namespace, key)
+	if ok && count == 0 {
+		t.Errorf("keybasetest: expected %s/%s to be active", namespace, key)
+		return false
+	}
+	return ok
+}
+
+// AssertKeyInactive reports an error if the key has an active entry
+func AssertKeyInactive(t testing.TB, kb *keybase.Keybase, namespace, key string) bool {
+	t.Helper()
+	count, ok := countKey(t, kb, namespace, key)
+	if ok && count > 0 {
+		t.Errorf("keybasetest: expected %s/%s to be inactive, found %d active entries", namespace, key, count)
+		return false
+	}
+	return ok
+}
+
+// AssertKeyCount reports an error unless the namespace has exactly count
+// active keys
+func AssertKeyCount(t testing.TB, kb *keybase.Keybase, namespace string, count int) bool {
+	t.Helper()
+	actual, err := kb.CountKeys(context.Background(), namespace, true, true)
+	if err != nil {
+		t.Errorf("keybasetest: failed to count keys of %s: %v", namespace, err)
+		return false
+	}
+	if actual != count {
+		t.Errorf("keybasetest: expected %d active keys in %s, found %d", count, namespace, actual)
+		return false
+	}
+	return true
+}
+
+func countKey(t testing.TB, kb *keybase.Keybase, namespace, key string) (int, bool) {
+	t.Helper()
+	count, err := kb.CountKey(context.Background(), namespace, key, true)
+	if err != nil {
+		t.Errorf("keybasetest: failed to count %s/%s: %v", namespace, key, err)
+		return 0, false
+	}
+	return count, true
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybasetest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/maxtek6/keybase-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder captures the failures reported by the assertion helpers
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestSeedEntries(t *testing.T) {
+	clock := NewClock(time.Now())
+	kb := NewTestKeybase(t, keybase.WithClock(clock), keybase.WithTTL(time.Minute))
+	SeedEntries(t, kb, []Fixture{
+		{Namespace: "namespace", Key: "key0"},
+		{Namespace: "namespace", Key: "key1", Expiration: clock.Now().Add(time.Hour)},
+		{Namespace: "namespace", Key: "key2", Expiration: clock.Now().Add(-time.Second)},
+	})
+	assert.True(t, AssertKeyActive(t, kb, "namespace", "key0"))
+	assert.True(t, AssertKeyActive(t, kb, "namespace", "key1"))
+	assert.True(t, AssertKeyInactive(t, kb, "namespace", "key2"))
+	assert.True(t, AssertKeyCount(t, kb, "namespace", 2))
+
+	clock.Advance(time.Minute)
+	assert.True(t, AssertKeyInactive(t, kb, "namespace", "key0"))
+	assert.True(t, AssertKeyCount(t, kb, "namespace", 1))
+
+	r := &recorder{TB: t}
+	assert.False(t, AssertKeyActive(r, kb, "namespace", "key0"))
+	assert.False(t, AssertKeyInactive(r, kb, "namespace", "key1"))
+	assert.False(t, AssertKeyCount(r, kb, "namespace", 3))
+	assert.Len(t, r.errors, 3)
+
+	assert.NoError(t, kb.Close())
+	r = &recorder{TB: t}
+	assert.False(t, AssertKeyActive(r, kb, "namespace", "key1"))
+	assert.False(t, AssertKeyCount(r, kb, "namespace", 1))
+	assert.Len(t, r.errors, 2)
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Lease exclusive ownership of a key, held until it expires or is released
+type Lease struct {
+	keybase   *Keybase
+	namespace string
+	key       string
+	owner     string
+	ttl       time.Duration
+}
+
+// AcquireLease takes exclusive ownership of a key for the given duration,
+// failing with ErrLeaseHeld if another owner holds an unexpired lease on it
+func (k *Keybase) AcquireLease(ctx context.Context, namespace, key string, ttl time.Duration) (*Lease, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("keybase.AcquireLease: %w: ttl must be positive", ErrInvalidArgument)
+	}
+	owner, err := newOwnerToken()
+	if err != nil {
+		return nil, fmt.Errorf("keybase.AcquireLease: failed to generate owner: %w", err)
+	}
+	now := k.clock.Now()
+	acquired := false
+	err = k.write(ctx, OpAcquireLease, []string{namespace}, func(ctx context.Context) error {
+		rows, err := newAcquireLeaseQuery(k.params(QueryParams{
+			Namespace:  namespace,
+			Key:        key,
+			Owner:      owner,
+			Expiration: now.Add(ttl).UnixMilli(),
+			Timestamp:  now.UnixMilli(),
+		})).queryRowsAffected(ctx, k.conn)
+		acquired = rows > 0
+		return err
+	})
+	if err == nil && !acquired {
+		err = ErrLeaseHeld
+	}
+	k.record(ctx, JournalEntry{Op: OpAcquireLease, Namespace: namespace, Key: key, TTL: ttl, Handle: owner}, err)
+	if errors.Is(err, ErrLeaseHeld) {
+		return nil, fmt.Errorf("keybase.AcquireLease: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keybase.AcquireLease: failed to acquire lease: %w", err)
+	}
+	return &Lease{
+		keybase:   k,
+		namespace: namespace,
+		key:       key,
+		owner:     owner,
+		ttl:       ttl,
+	}, nil
+}
+
+// Renew extends the lease by its duration, failing with ErrLeaseLost if the
+// lease already expired or was released
+func (l *Lease) Renew(ctx context.Context) error {
+	now := l.keybase.clock.Now()
+	renewed := false
+	err := l.keybase.write(ctx, OpRenewLease, []string{l.namespace}, func(ctx context.Context) error {
+		rows, err := newRenewLeaseQuery(l.keybase.params(QueryParams{
+			Namespace:  l.namespace,
+			Key:        l.key,
+			Owner:      l.owner,
+			Expiration: now.Add(l.ttl).UnixMilli(),
+			Timestamp:  now.UnixMilli(),
+		})).queryRowsAffected(ctx, l.keybase.conn)
+		renewed = rows > 0
+		return err
+	})
+	if err == nil && !renewed {
+		err = ErrLeaseLost
+	}
+	l.keybase.record(ctx, JournalEntry{Op: OpRenewLease, Namespace: l.namespace, Key: l.key, Handle: l.owner}, err)
+	if errors.Is(err, ErrLeaseLost) {
+		return fmt.Errorf("keybase.Lease.Renew: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("keybase.Lease.Renew: failed to renew lease: %w", err)
+	}
+	return nil
+}
+
+// Release gives up ownership of the key so it can be acquired immediately
+func (l *Lease) Release(ctx context.Context) error {
+	err := l.keybase.write(ctx, OpReleaseLease, []string{l.namespace}, func(ctx context.Context) error {
+		return newReleaseLeaseQuery(l.keybase.params(QueryParams{Namespace: l.namespace, Key: l.key, Owner: l.owner})).queryExec(ctx, l.keybase.conn)
+	})
+	l.keybase.record(ctx, JournalEntry{Op: OpReleaseLease, Namespace: l.namespace, Key: l.key, Handle: l.owner}, err)
+	if err != nil {
+		return fmt.Errorf("keybase.Lease.Release: failed to release lease: %w", err)
+	}
+	return nil
+}
+
+func newOwnerToken() (string, error) {
+	token := make([]byte, 16)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLease(t *testing.T) {
+	keybase, err := Open(context.Background())
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	_, err = keybase.AcquireLease(context.Background(), "namespace", "key", 0)
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	lease, err := keybase.AcquireLease(context.Background(), "namespace", "key", time.Millisecond*50)
+	assert.NoError(t, err)
+	assert.NotNil(t, lease)
+
+	_, err = keybase.AcquireLease(context.Background(), "namespace", "key", time.Minute)
+	assert.ErrorIs(t, err, ErrLeaseHeld)
+	other, err := keybase.AcquireLease(context.Background(), "othernamespace", "key", time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, other.Release(context.Background()))
+
+	assert.NoError(t, lease.Renew(context.Background()))
+	assert.NoError(t, lease.Release(context.Background()))
+	assert.ErrorIs(t, lease.Renew(context.Background()), ErrLeaseLost)
+
+	lease, err = keybase.AcquireLease(context.Background(), "namespace", "key", time.Millisecond*50)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 50)
+	assert.ErrorIs(t, lease.Renew(context.Background()), ErrLeaseLost)
+
+	next, err := keybase.AcquireLease(context.Background(), "namespace", "key", time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, lease.Release(context.Background()))
+	_, err = keybase.AcquireLease(context.Background(), "namespace", "key", time.Minute)
+	assert.ErrorIs(t, err, ErrLeaseHeld)
+	assert.NoError(t, next.Release(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(0))
+	defer cancel()
+	_, err = keybase.AcquireLease(ctx, "namespace", "key", time.Minute)
+	assert.Error(t, err)
+	assert.Error(t, next.Renew(ctx))
+	assert.Error(t, next.Release(ctx))
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Limiter sliding-window rate limiter, allowing at most limit entries of a key
+// within the window
+type Limiter struct {
+	keybase   *Keybase
+	namespace string
+	limit     int
+	window    time.Duration
+}
+
+// NewLimiter creates a limiter that records the entries of each key in
+// namespace, where they expire after window
+func NewLimiter(kb *Keybase, namespace string, limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		keybase:   kb,
+		namespace: namespace,
+		limit:     limit,
+		window:    window,
+	}
+}
+
+// Allow counts the active entries of the key and inserts a new one if there
+// are fewer than the limit, as a single statement. It reports whether the
+// entry was inserted.
+func (l *Limiter) Allow(ctx context.Context, key string) (bool, error) {
+	if l.limit <= 0 || l.window <= 0 {
+		return false, fmt.Errorf("keybase.Allow: %w: limit and window must be positive", ErrInvalidArgument)
+	}
+	k := l.keybase
+	now := k.clock.Now()
+	allowed := false
+	err := k.write(ctx, OpAllow, []string{l.namespace}, func(ctx context.Context) error {
+		params := k.params(QueryParams{
+			Namespace:  l.namespace,
+			Key:        key,
+			Expiration: now.Add(l.window).UnixMilli(),
+			Timestamp:  now.UnixMilli(),
+			Limit:      l.limit,
+		})
+		return k.insert(ctx, key, func(db querier) error {
+			rows, err := newAllowQuery(params).queryRowsAffected(ctx, db)
+			allowed = rows > 0
+			if err != nil || !allowed {
+				return err
+			}
+			return k.versioned(ctx, db, params)
+		})
+	})
+	k.record(ctx, JournalEntry{Op: OpAllow, Namespace: l.namespace, Key: key, TTL: l.window, Limit: l.limit}, err)
+	if err != nil {
+		return false, fmt.Errorf("keybase.Allow: failed to insert key: %w", err)
+	}
+	return allowed, nil
+}
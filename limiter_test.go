@@ -0,0 +1,78 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	clock := &fixedClock{now: time.Now()}
+	journal := &bytes.Buffer{}
+	keybase, err := Open(ctx, WithClock(clock), WithJournal(journal), WithChecksums())
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	limiter := NewLimiter(keybase, "limits", 2, time.Second)
+	for _, expected := range []bool{true, true, false} {
+		allowed, err := limiter.Allow(ctx, "client0")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, allowed)
+	}
+	allowed, err := limiter.Allow(ctx, "client1")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	allowed, err = limiter.Allow(ctx, "client0")
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	allowed, err = limiter.Allow(ctx, "client0")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+	count, err := keybase.CountKey(ctx, "limits", "client0", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	replayed, err := Open(ctx)
+	assert.NoError(t, err)
+	defer replayed.Close()
+	assert.NoError(t, ReplayJournal(ctx, replayed, journal))
+	count, err = replayed.CountKey(ctx, "limits", "client0", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	_, err = NewLimiter(keybase, "limits", 0, time.Second).Allow(ctx, "client0")
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	_, err = NewLimiter(keybase, "limits", 1, 0).Allow(ctx, "client0")
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	cancelled, cancel := context.WithTimeout(ctx, time.Duration(0))
+	defer cancel()
+	_, err = limiter.Allow(cancelled, "client0")
+	assert.Error(t, err)
+}
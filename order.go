@@ -0,0 +1,132 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// Order of the keys returned by MatchKey and GetKeys
+type Order int
+
+const (
+	// Unordered leaves the order to SQLite
+	Unordered Order = iota
+	// KeyAscending sorts keys in ascending byte order
+	KeyAscending
+	// KeyDescending sorts keys in descending byte order
+	KeyDescending
+	// ExpirationOrder sorts keys soonest to expire first, using the latest
+	// expiration of unique keys
+	ExpirationOrder
+	// InsertionOrder sorts keys in the order their entries were inserted,
+	// using the first insertion of unique keys
+	InsertionOrder
+)
+
+// QueryOption adjusts the results of key queries
+type QueryOption struct {
+	key   string
+	value any
+}
+
+// Sort the returned keys, so that listings are stable between calls
+func OrderBy(order Order) QueryOption {
+	return QueryOption{
+		key:   "order",
+		value: order,
+	}
+}
+
+// Leave out keys matching any of the patterns, so that well known keys can
+// be skipped without listing them
+func WithExclude(patterns ...string) QueryOption {
+	return QueryOption{
+		key:   "exclude",
+		value: patterns,
+	}
+}
+
+type queryOptions struct {
+	order   Order
+	exclude []string
+}
+
+func parseQueryOptions(opts ...QueryOption) (queryOptions, error) {
+	query := queryOptions{}
+	for _, opt := range opts {
+		switch opt.key {
+		case "order":
+			query.order = opt.value.(Order)
+		case "exclude":
+			query.exclude = append(query.exclude, opt.value.([]string)...)
+		}
+	}
+	for _, pattern := range query.exclude {
+		err := ValidatePattern(pattern)
+		if err != nil {
+			return query, err
+		}
+	}
+	if query.order < Unordered || query.order > InsertionOrder {
+		return query, fmt.Errorf("%w: unknown order %d", ErrInvalidArgument, query.order)
+	}
+	return query, nil
+}
+
+// byKey reports whether the order compares the keys themselves
+func (order Order) byKey() bool {
+	return order == KeyAscending || order == KeyDescending
+}
+
+// sortKeys sorts decoded keys when they are encrypted, as SQLite can only
+// order the ciphertext
+func (k *Keybase) sortKeys(keys []string, order Order) {
+	if k.cipher == nil {
+		return
+	}
+	switch order {
+	case KeyAscending:
+		slices.Sort(keys)
+	case KeyDescending:
+		slices.Sort(keys)
+		slices.Reverse(keys)
+	}
+}
+
+// excludeKeys removes the excluded keys when they are encrypted, as SQLite can
+// only match the ciphertext
+func (k *Keybase) excludeKeys(keys []string, exclude []string) []string {
+	if k.cipher == nil || len(exclude) == 0 {
+		return keys
+	}
+	matchers := make([]*regexp.Regexp, len(exclude))
+	for i, pattern := range exclude {
+		matchers[i] = likePattern(pattern)
+	}
+	return slices.DeleteFunc(keys, func(key string) bool {
+		return slices.ContainsFunc(matchers, func(matcher *regexp.Regexp) bool {
+			return matcher.MatchString(key)
+		})
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderBy(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{
+		{},
+		{WithChecksums()},
+		{WithEncryption(bytes.Repeat([]byte{1}, 32))},
+	} {
+		clock := &fixedClock{now: time.Now()}
+		keybase, err := Open(ctx, append(opts, WithClock(clock), WithTTL(time.Minute))...)
+		assert.NoError(t, err)
+		assert.NoError(t, keybase.PutUntil(ctx, "namespace", "key1", clock.now.Add(3*time.Second)))
+		assert.NoError(t, keybase.PutUntil(ctx, "namespace", "key0", clock.now.Add(2*time.Second)))
+		assert.NoError(t, keybase.PutUntil(ctx, "namespace", "key2", clock.now.Add(time.Second)))
+		assert.NoError(t, keybase.PutUntil(ctx, "namespace", "key1", clock.now.Add(-time.Second)))
+
+		keys, err := keybase.GetKeys(ctx, "namespace", false, false, OrderBy(KeyAscending))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key0", "key1", "key1", "key2"}, keys)
+		keys, err = keybase.GetKeys(ctx, "namespace", false, true, OrderBy(KeyDescending))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key2", "key1", "key0"}, keys)
+		keys, err = keybase.GetKeys(ctx, "namespace", false, false, OrderBy(ExpirationOrder))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key1", "key2", "key0", "key1"}, keys)
+		keys, err = keybase.GetKeys(ctx, "namespace", false, true, OrderBy(ExpirationOrder))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key2", "key0", "key1"}, keys)
+		keys, err = keybase.GetKeys(ctx, "namespace", false, false, OrderBy(InsertionOrder))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key1", "key0", "key2", "key1"}, keys)
+		keys, err = keybase.GetKeys(ctx, "namespace", true, true, OrderBy(InsertionOrder))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key1", "key0", "key2"}, keys)
+
+		keys, err = keybase.MatchKey(ctx, "namespace", "key?", true, true, OrderBy(KeyDescending))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key2", "key1", "key0"}, keys)
+
+		_, err = keybase.GetKeys(ctx, "namespace", false, false, OrderBy(Order(-1)))
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+		_, err = keybase.MatchKey(ctx, "namespace", "*", false, false, OrderBy(InsertionOrder+1))
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+		assert.NoError(t, keybase.Close())
+	}
+}
+
+func TestWithExclude(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{
+		{},
+		{WithEncryption(bytes.Repeat([]byte{1}, 32))},
+	} {
+		keybase, err := Open(ctx, opts...)
+		assert.NoError(t, err)
+		for _, key := range []string{"key0", "tmp-key1", "key2", "internal", "key_3"} {
+			assert.NoError(t, keybase.Put(ctx, "namespace", key))
+		}
+
+		keys, err := keybase.GetKeys(ctx, "namespace", true, true, WithExclude("tmp-*", "internal"), OrderBy(KeyAscending))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key0", "key2", "key_3"}, keys)
+		keys, err = keybase.GetKeys(ctx, "namespace", true, true, WithExclude("key?"), WithExclude("tmp-*"), OrderBy(KeyAscending))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"internal", "key_3"}, keys)
+		keys, err = keybase.MatchKey(ctx, "namespace", "*key*", true, true, WithExclude("key_*"), OrderBy(KeyAscending))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key0", "key2", "tmp-key1"}, keys)
+
+		_, err = keybase.MatchKey(ctx, "namespace", "*", true, true, WithExclude(""))
+		assert.ErrorIs(t, err, ErrInvalidPattern)
+		assert.NoError(t, keybase.Close())
+	}
+}
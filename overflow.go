@@ -0,0 +1,48 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// overflowPrefix marks key columns holding a reference to the overflow table,
+// starting with a control character so it cannot collide with ordinary keys
+const overflowPrefix = "\x1fsha256:"
+
+// Store keys longer than threshold bytes in a side table, keeping a fixed
+// size reference in the indexed key columns
+func WithOverflow(threshold int) Option {
+	return Option{
+		key:   "overflow",
+		value: threshold,
+	}
+}
+
+func overflowRef(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return overflowPrefix + hex.EncodeToString(sum[:])
+}
+
+func (k *Keybase) overflows(key string) bool {
+	return k.overflow > 0 && len(key) > k.overflow
+}
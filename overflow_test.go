@@ -0,0 +1,104 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverflowRef(t *testing.T) {
+	ref := overflowRef("key")
+	assert.True(t, strings.HasPrefix(ref, overflowPrefix))
+	assert.Len(t, ref, len(overflowPrefix)+64)
+	assert.Equal(t, ref, overflowRef("key"))
+	assert.NotEqual(t, ref, overflowRef("otherkey"))
+}
+
+func TestOverflow(t *testing.T) {
+	keybase, err := Open(context.Background(), WithOverflow(16), WithTTL(time.Millisecond*50))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	expired := []string{}
+	mu := sync.Mutex{}
+	keybase.OnExpire(func(namespace, key string) {
+		mu.Lock()
+		defer mu.Unlock()
+		expired = append(expired, key)
+	})
+
+	long := "long/" + strings.Repeat("x", 64)
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", long))
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", long))
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "short"))
+	inserted, err := keybase.PutIfAbsent(context.Background(), "namespace", long)
+	assert.NoError(t, err)
+	assert.False(t, inserted)
+
+	stored := ""
+	assert.NoError(t, keybase.db.QueryRow("SELECT MAX(LENGTH(key)) FROM keybase").Scan(&stored))
+	assert.Equal(t, "72", stored)
+
+	keys, err := keybase.GetKeys(context.Background(), "namespace", true, true)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{long, "short"}, keys)
+	keys, err = keybase.MatchKey(context.Background(), "namespace", "long/*", true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{long, long}, keys)
+	count, err := keybase.CountKey(context.Background(), "namespace", long, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.NoError(t, keybase.PutField(context.Background(), "namespace", long, "field", "value"))
+	fields, err := keybase.GetFields(context.Background(), "namespace", long)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"field": "value"}, fields)
+
+	time.Sleep(time.Millisecond * 60)
+	assert.NoError(t, keybase.PruneEntries(context.Background()))
+	assert.NoError(t, keybase.Close())
+	assert.ElementsMatch(t, []string{long, long, "short"}, expired)
+}
+
+func TestOverflowPrune(t *testing.T) {
+	keybase, err := Open(context.Background(), WithOverflow(4), WithTTL(time.Millisecond*10))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	overflowed := func() int {
+		count := 0
+		assert.NoError(t, keybase.db.QueryRow("SELECT COUNT(*) FROM keybase_overflow").Scan(&count))
+		return count
+	}
+
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "longkey"))
+	assert.Equal(t, 1, overflowed())
+	time.Sleep(time.Millisecond * 20)
+	assert.NoError(t, keybase.PruneEntries(context.Background()))
+	assert.Zero(t, overflowed())
+
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "longkey"))
+	assert.NoError(t, keybase.ClearEntries(context.Background()))
+	assert.Zero(t, overflowed())
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// partSeparator joins the parts of a composite key. Separators and escapes
+// within a part are percent encoded, so any part can be stored.
+const partSeparator = "/"
+
+var (
+	partEscaper   = strings.NewReplacer("%", "%25", partSeparator, "%2F")
+	partUnescaper = strings.NewReplacer("%25", "%", "%2F", partSeparator)
+)
+
+// joinParts encodes the parts of a composite key as a single key
+func joinParts(parts []string) string {
+	escaped := make([]string, len(parts))
+	for index, part := range parts {
+		escaped[index] = partEscaper.Replace(part)
+	}
+	return strings.Join(escaped, partSeparator)
+}
+
+// splitParts decodes a composite key, reporting false if the key was not
+// encoded by joinParts
+func splitParts(key string) ([]string, bool) {
+	parts := strings.Split(key, partSeparator)
+	for index, part := range parts {
+		for rest := part; strings.Contains(rest, "%"); {
+			rest = rest[strings.Index(rest, "%"):]
+			if !strings.HasPrefix(rest, "%25") && !strings.HasPrefix(rest, "%2F") {
+				return nil, false
+			}
+			rest = rest[3:]
+		}
+		parts[index] = partUnescaper.Replace(part)
+	}
+	return parts, true
+}
+
+// PutParts inserts new value for a key composed of ordered parts, such as
+// user, device, and session
+func (k *Keybase) PutParts(ctx context.Context, namespace string, parts ...string) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("keybase.PutParts: %w: key must have at least one part", ErrInvalidArgument)
+	}
+	err := k.put(ctx, namespace, joinParts(parts), time.Time{}, nil)
+	if err != nil {
+		return fmt.Errorf("keybase.PutParts: failed to insert key: %w", err)
+	}
+	return nil
+}
+
+// MatchParts collects the composite keys of a namespace that have as many
+// parts as patterns, each part matching its pattern. Wildcards never match
+// across parts.
+func (k *Keybase) MatchParts(ctx context.Context, namespace string, active, unique bool, patterns ...string) ([][]string, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("keybase.MatchParts: %w: key must have at least one part", ErrInvalidArgument)
+	}
+	// escaped characters span several characters of the stored key, so
+	// SQLite matches a superset that is narrowed down part by part
+	coarse := make([]string, len(patterns))
+	matchers := make([]func(string) bool, len(patterns))
+	for index, pattern := range patterns {
+		err := ValidatePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("keybase.MatchParts: %w", err)
+		}
+		coarse[index] = coarsePart(pattern)
+		matchers[index] = likePattern(pattern).MatchString
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	matched := [][]string{}
+	err := k.read(ctx, OpMatchParts, []string{namespace}, func(ctx context.Context) error {
+		keys, err := k.match(ctx, k.conn, k.params(QueryParams{Namespace: namespace, Pattern: strings.Join(coarse, partSeparator), Active: active, Unique: unique, Timestamp: timestamp}))
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			parts, ok := splitParts(key)
+			if ok && len(parts) == len(matchers) && matchesParts(parts, matchers) {
+				matched = append(matched, parts)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.MatchParts: failed to query database: %w", err)
+	}
+	return matched, nil
+}
+
+// coarsePart encodes a part pattern like the part itself, widening each ?
+// wildcard to * since a single character may be escaped as several
+func coarsePart(pattern string) string {
+	coarse := strings.Builder{}
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			coarse.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			coarse.WriteRune(r)
+			escaped = true
+		case r == '?':
+			coarse.WriteRune('*')
+		default:
+			coarse.WriteString(partEscaper.Replace(string(r)))
+		}
+	}
+	return coarse.String()
+}
+
+func matchesParts(parts []string, matchers []func(string) bool) bool {
+	for index, part := range parts {
+		if !matchers[index](part) {
+			return false
+		}
+	}
+	return true
+}
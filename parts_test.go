@@ -0,0 +1,90 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinParts(t *testing.T) {
+	for _, parts := range [][]string{{"user"}, {"user", "device"}, {"a/b", "100%", "%2F"}, {"", ""}} {
+		key := joinParts(parts)
+		split, ok := splitParts(key)
+		assert.True(t, ok)
+		assert.Equal(t, parts, split)
+	}
+	assert.Equal(t, "a%2Fb/100%25", joinParts([]string{"a/b", "100%"}))
+	_, ok := splitParts("50%off/x")
+	assert.False(t, ok)
+	_, ok = splitParts("trailing%2")
+	assert.False(t, ok)
+	assert.Equal(t, `a%2F*\?%25`, coarsePart(`a/?\?%`))
+}
+
+func TestPutParts(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{nil, {WithEncryption(make([]byte, 32))}} {
+		keybase, err := Open(ctx, opts...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		assert.ErrorIs(t, keybase.PutParts(ctx, "sessions"), ErrInvalidArgument)
+		_, err = keybase.MatchParts(ctx, "sessions", true, true)
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+
+		assert.NoError(t, keybase.PutParts(ctx, "sessions", "alice", "phone", "s1"))
+		assert.NoError(t, keybase.PutParts(ctx, "sessions", "alice", "laptop", "s2"))
+		assert.NoError(t, keybase.PutParts(ctx, "sessions", "bob", "phone", "s3"))
+		assert.NoError(t, keybase.PutParts(ctx, "sessions", "a/b", "phone", "s4"))
+		assert.NoError(t, keybase.PutParts(ctx, "sessions", "alice", "phone"))
+		assert.NoError(t, keybase.Put(ctx, "sessions", "alice/50%/s5"))
+
+		matched, err := keybase.MatchParts(ctx, "sessions", true, true, "alice", "*", "*")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, [][]string{{"alice", "phone", "s1"}, {"alice", "laptop", "s2"}}, matched)
+		matched, err = keybase.MatchParts(ctx, "sessions", true, true, "*", "phone", "s?")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, [][]string{{"alice", "phone", "s1"}, {"bob", "phone", "s3"}, {"a/b", "phone", "s4"}}, matched)
+		matched, err = keybase.MatchParts(ctx, "sessions", true, true, "a/b", "*", "*")
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"a/b", "phone", "s4"}}, matched)
+		matched, err = keybase.MatchParts(ctx, "sessions", true, true, "*", "*")
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"alice", "phone"}}, matched)
+		assert.NoError(t, keybase.PutParts(ctx, "sessions", "what?", "100%"))
+		matched, err = keybase.MatchParts(ctx, "sessions", true, true, `what\?`, "100%")
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"what?", "100%"}}, matched)
+		_, err = keybase.MatchParts(ctx, "sessions", true, true, "*", "")
+		assert.ErrorIs(t, err, ErrInvalidPattern)
+		matched, err = keybase.MatchParts(ctx, "sessions", true, true, "carol", "*", "*")
+		assert.NoError(t, err)
+		assert.Empty(t, matched)
+
+		assert.NoError(t, keybase.Close())
+		assert.ErrorIs(t, keybase.PutParts(ctx, "sessions", "alice"), ErrClosed)
+		_, err = keybase.MatchParts(ctx, "sessions", true, true, "*")
+		assert.ErrorIs(t, err, ErrClosed)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"fmt"
+	"strings"
+)
+
+// patternEscaper escapes the wildcards of a glob pattern
+var patternEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`)
+
+// ValidatePattern checks that a glob pattern is well formed. Patterns match
+// any run of characters with * and any single character with ?, and a
+// backslash escapes a literal *, ? or backslash. Every other character,
+// including % and _, matches itself. Empty patterns and dangling or unknown
+// escapes are rejected with ErrInvalidPattern.
+func ValidatePattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("%w: empty pattern", ErrInvalidPattern)
+	}
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped && r != '*' && r != '?' && r != '\\':
+			return fmt.Errorf("%w: %q escapes %q", ErrInvalidPattern, pattern, r)
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		}
+	}
+	if escaped {
+		return fmt.Errorf("%w: %q ends with an escape", ErrInvalidPattern, pattern)
+	}
+	return nil
+}
+
+// EscapePattern escapes the wildcards of a key, so that it can be used as a
+// pattern that matches only the key itself
+func EscapePattern(key string) string {
+	return patternEscaper.Replace(key)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePattern(t *testing.T) {
+	for _, pattern := range []string{"key*", "k?y", `key\*`, `\?`, `a\\b`, "user_1", "100%"} {
+		assert.NoError(t, ValidatePattern(pattern), pattern)
+	}
+	for _, pattern := range []string{"", `key\`, `k\ey`} {
+		assert.ErrorIs(t, ValidatePattern(pattern), ErrInvalidPattern, pattern)
+	}
+}
+
+func TestGlobToLike(t *testing.T) {
+	assert.Equal(t, "key%", globToLike("key*"))
+	assert.Equal(t, "k_y", globToLike("k?y"))
+	assert.Equal(t, "key*?", globToLike(`key\*\?`))
+	assert.Equal(t, `a\\b`, globToLike(`a\\b`))
+	assert.Equal(t, `a\\eb\\`, globToLike(`a\eb\`))
+	assert.Equal(t, `100\%\_%`, globToLike("100%_*"))
+	assert.True(t, likePattern("100%").MatchString("100%"))
+	assert.False(t, likePattern("100%").MatchString("1000"))
+	assert.False(t, likePattern("a_c").MatchString("abc"))
+	assert.True(t, likePattern(`key\*`).MatchString("key*"))
+	assert.False(t, likePattern(`key\*`).MatchString("key0"))
+}
+
+func TestMatchKeyInvalidPattern(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{nil, {WithEncryption(make([]byte, 32))}} {
+		keybase, err := Open(ctx, opts...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		assert.NoError(t, keybase.Put(ctx, "namespace", "key*"))
+		assert.NoError(t, keybase.Put(ctx, "namespace", "key?"))
+		assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+		keys, err := keybase.MatchKey(ctx, "namespace", `key\*`, true, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key*"}, keys)
+		count, err := keybase.CountMatch(ctx, "namespace", `key\?`, true, true)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		_, err = keybase.MatchKey(ctx, "namespace", "", true, true)
+		assert.ErrorIs(t, err, ErrInvalidPattern)
+		_, err = keybase.MatchKeyAcross(ctx, nil, "", true, true)
+		assert.ErrorIs(t, err, ErrInvalidPattern)
+		_, err = keybase.CountMatch(ctx, "namespace", `key\`, true, true)
+		assert.ErrorIs(t, err, ErrInvalidPattern)
+		_, err = keybase.ExpireMatch(ctx, "namespace", `\`, time.Now())
+		assert.ErrorIs(t, err, ErrInvalidPattern)
+		_, err = keybase.MatchNamespaces(ctx, "", true)
+		assert.ErrorIs(t, err, ErrInvalidPattern)
+	}
+}
+
+func TestMatchKeyLiteral(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, `a\*b\?c\\d%_`, EscapePattern(`a*b?c\d%_`))
+	for _, opts := range [][]Option{nil, {WithEncryption(make([]byte, 32))}, {WithOverflow(16)}} {
+		keybase, err := Open(ctx, opts...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		for _, key := range []string{"100%", "1000", "user_1", "userA1", `a*b?c\d`, "a0b1c2d"} {
+			assert.NoError(t, keybase.Put(ctx, "namespace", key))
+		}
+		keys, err := keybase.MatchKey(ctx, "namespace", "100%", true, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"100%"}, keys)
+		keys, err = keybase.MatchKey(ctx, "namespace", "user_*", true, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"user_1"}, keys)
+		keys, err = keybase.MatchKey(ctx, "namespace", EscapePattern(`a*b?c\d`), true, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{`a*b?c\d`}, keys)
+		count, err := keybase.CountMatch(ctx, "namespace", "user?1", true, true)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+	}
+}
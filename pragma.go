@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Apply SQLite pragmas, such as journal_mode or cache_size, to every
+// connection opened by the keybase
+func WithPragmas(pragmas map[string]string) Option {
+	return Option{
+		key:   "pragmas",
+		value: pragmas,
+	}
+}
+
+// Use write-ahead logging for persistent storage
+func WithWAL() Option {
+	return WithPragmas(map[string]string{"journal_mode": "WAL"})
+}
+
+// Set the synchronous mode, one of OFF, NORMAL, FULL or EXTRA
+func WithSynchronous(mode string) Option {
+	return WithPragmas(map[string]string{"synchronous": mode})
+}
+
+// Wait for locks held by other connections instead of failing immediately
+func WithBusyTimeout(timeout time.Duration) Option {
+	return WithPragmas(map[string]string{"busy_timeout": fmt.Sprint(timeout.Milliseconds())})
+}
+
+// dataSource appends the pragmas to the storage path as _pragma parameters,
+// sorted by name so the resulting DSN is deterministic. Transactions always
+// write, so they take the write lock when they begin instead of upgrading to
+// it, which could fail with SQLITE_BUSY under concurrent writers.
+func dataSource(storage string, pragmas map[string]string) string {
+	names := make([]string, 0, len(pragmas))
+	for name := range pragmas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	params := []string{"_txlock=immediate"}
+	for _, name := range names {
+		params = append(params, "_pragma="+url.QueryEscape(fmt.Sprintf("%s(%s)", name, pragmas[name])))
+	}
+	separator := "?"
+	if strings.Contains(storage, "?") {
+		separator = "&"
+	}
+	return storage + separator + strings.Join(params, "&")
+}
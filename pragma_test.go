@@ -0,0 +1,67 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataSource(t *testing.T) {
+	assert.Equal(t, ":memory:?_txlock=immediate", dataSource(":memory:", nil))
+	assert.Equal(t, "keybase.db?_txlock=immediate&_pragma=busy_timeout%285000%29&_pragma=journal_mode%28WAL%29",
+		dataSource("keybase.db", map[string]string{"journal_mode": "WAL", "busy_timeout": "5000"}))
+	assert.Equal(t, "file:keybase.db?mode=rwc&_txlock=immediate&_pragma=synchronous%28NORMAL%29",
+		dataSource("file:keybase.db?mode=rwc", map[string]string{"synchronous": "NORMAL"}))
+}
+
+func TestPragmas(t *testing.T) {
+	storageDirectory, _ := os.MkdirTemp(os.TempDir(), "keybase-*")
+	defer os.RemoveAll(storageDirectory)
+	keybase, err := Open(
+		context.Background(),
+		WithStorage(path.Join(storageDirectory, "keybase.db")),
+		WithWAL(),
+		WithSynchronous("NORMAL"),
+		WithBusyTimeout(time.Second*5),
+		WithPragmas(map[string]string{"cache_size": "-4000"}),
+	)
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	pragma := func(name string) string {
+		value := ""
+		assert.NoError(t, keybase.db.QueryRow("PRAGMA "+name).Scan(&value))
+		return value
+	}
+	assert.Equal(t, "wal", pragma("journal_mode"))
+	assert.Equal(t, "1", pragma("synchronous"))
+	assert.Equal(t, "5000", pragma("busy_timeout"))
+	assert.Equal(t, "-4000", pragma("cache_size"))
+
+	_, err = Open(context.Background(), WithPragmas(map[string]string{"journal_mode": "'"}))
+	assert.ErrorIs(t, err, ErrInvalidStorage)
+}
@@ -23,154 +23,1624 @@ package keybase
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/huandu/go-sqlbuilder"
 )
 
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
 type dbtx struct {
 	query string
 	args  []any
 }
 
+// Op identifies a keybase operation
+type Op string
+
+const (
+	OpCreateTable          Op = "CreateTable"
+	OpPut                  Op = "Put"
+	OpMatchKey             Op = "MatchKey"
+	OpCountKey             Op = "CountKey"
+	OpGetKeys              Op = "GetKeys"
+	OpCountKeys            Op = "CountKeys"
+	OpCountMatch           Op = "CountMatch"
+	OpMatchKeyAcross       Op = "MatchKeyAcross"
+	OpMatchParts           Op = "MatchParts"
+	OpMatchKeyAny          Op = "MatchKeyAny"
+	OpAllow                Op = "Allow"
+	OpSeen                 Op = "Seen"
+	OpExpvar               Op = "Expvar"
+	OpAlarm                Op = "Alarm"
+	OpCompact              Op = "Compact"
+	OpFreePages            Op = "FreePages"
+	OpVacuum               Op = "Vacuum"
+	OpIncrementalVacuum    Op = "IncrementalVacuum"
+	OpGroupCommit          Op = "GroupCommit"
+	OpPollChanges          Op = "PollChanges"
+	OpDataVersion          Op = "DataVersion"
+	OpGetNamespaces        Op = "GetNamespaces"
+	OpCountNamespaces      Op = "CountNamespaces"
+	OpCountEntries         Op = "CountEntries"
+	OpPruneEntries         Op = "PruneEntries"
+	OpClearEntries         Op = "ClearEntries"
+	OpIncrement            Op = "Increment"
+	OpGetCounter           Op = "GetCounter"
+	OpPruneCounters        Op = "PruneCounters"
+	OpReconfigure          Op = "Reconfigure"
+	OpPutIfAbsent          Op = "PutIfAbsent"
+	OpAcquireLease         Op = "AcquireLease"
+	OpRenewLease           Op = "RenewLease"
+	OpReleaseLease         Op = "ReleaseLease"
+	OpPruneLeases          Op = "PruneLeases"
+	OpPutField             Op = "PutField"
+	OpTouchFields          Op = "TouchFields"
+	OpGetFields            Op = "GetFields"
+	OpPruneFields          Op = "PruneFields"
+	OpExpireEntries        Op = "ExpireEntries"
+	OpGetExpiration        Op = "GetExpiration"
+	OpLastExpiration       Op = "LastExpiration"
+	OpExpirationHistogram  Op = "ExpirationHistogram"
+	OpKeyFrequency         Op = "KeyFrequency"
+	OpExpirationDecay      Op = "ExpirationDecay"
+	OpCompactDuplicates    Op = "CompactDuplicates"
+	OpCopyToColdTier       Op = "CopyToColdTier"
+	OpMoveToColdTier       Op = "MoveToColdTier"
+	OpPruneColdTier        Op = "PruneColdTier"
+	OpMatchNamespaces      Op = "MatchNamespaces"
+	OpCountKeysByNamespace Op = "CountKeysByNamespace"
+	OpDescribeNamespaces   Op = "DescribeNamespaces"
+	OpTopKeys              Op = "TopKeys"
+	OpClaim                Op = "Claim"
+	OpExtendClaim          Op = "ExtendClaim"
+	OpReleaseClaim         Op = "ReleaseClaim"
+	OpNextExpiration       Op = "NextExpiration"
+	OpPutOverflow          Op = "PutOverflow"
+	OpPruneOverflow        Op = "PruneOverflow"
+	OpChecksumColumn       Op = "ChecksumColumn"
+	OpAddChecksumColumn    Op = "AddChecksumColumn"
+	OpQuarantineEntries    Op = "QuarantineEntries"
+	OpGetQuarantine        Op = "GetQuarantine"
+	OpGetField             Op = "GetField"
+	OpSnapshot             Op = "Snapshot"
+	OpEvictEntries         Op = "EvictEntries"
+	OpFlushBatch           Op = "FlushBatch"
+	OpPruneNamespace       Op = "PruneNamespace"
+	OpClearNamespace       Op = "ClearNamespace"
+	OpScanULIDs            Op = "ScanULIDs"
+	OpTx                   Op = "Tx"
+	OpDeleteKey            Op = "DeleteKey"
+	OpExportNamespaces     Op = "ExportNamespaces"
+	OpSchemaTables         Op = "SchemaTables"
+	OpSchemaVersion        Op = "SchemaVersion"
+	OpRecordMigration      Op = "RecordMigration"
+	OpCopyNamespace        Op = "CopyNamespace"
+	OpCreateArchiveTable   Op = "CreateArchiveTable"
+	OpArchiveEntries       Op = "ArchiveEntries"
+	OpGetArchivedKeys      Op = "GetArchivedKeys"
+	OpPurgeArchive         Op = "PurgeArchive"
+	OpCreateTombstoneTable Op = "CreateTombstoneTable"
+	OpGetDeletedKeys       Op = "GetDeletedKeys"
+	OpPurgeTombstones      Op = "PurgeTombstones"
+	OpCreateAuditTable     Op = "CreateAuditTable"
+	OpAudit                Op = "Audit"
+	OpQueryAudit           Op = "QueryAudit"
+	OpStats                Op = "Stats"
+	OpNamespaceEntries     Op = "NamespaceEntries"
+	OpPutUntil             Op = "PutUntil"
+	OpAttach               Op = "Attach"
+	OpDetach               Op = "Detach"
+	OpDatabaseList         Op = "DatabaseList"
+	OpExpireMatch          Op = "ExpireMatch"
+	OpExpireKey            Op = "ExpireKey"
+	OpGetEntries           Op = "GetEntries"
+	OpCreateTagsTable      Op = "CreateTagsTable"
+	OpTagKey               Op = "TagKey"
+	OpMatchKeyByTag        Op = "MatchKeyByTag"
+	OpPruneTags            Op = "PruneTags"
+	OpCreateHistoryTable   Op = "CreateHistoryTable"
+	OpRecordHistory        Op = "RecordHistory"
+	OpGetKeyHistory        Op = "GetKeyHistory"
+	OpPruneHistory         Op = "PruneHistory"
+	OpPing                 Op = "Ping"
+	OpHealthCheck          Op = "HealthCheck"
+	OpAddInsertedColumn    Op = "AddInsertedColumn"
+	OpGetKeysInserted      Op = "GetKeysInserted"
+	OpScan                 Op = "Scan"
+	OpCreateImmutableTable Op = "CreateImmutableTable"
+	OpSetImmutable         Op = "SetImmutable"
+	OpCreateFenceTable     Op = "CreateFenceTable"
+	OpFence                Op = "Fence"
+	OpGetFence             Op = "GetFence"
+	OpPutFenced            Op = "PutFenced"
+	OpDeleteMatch          Op = "DeleteMatch"
+	OpSampleKeys           Op = "SampleKeys"
+	OpIntegrityCheck       Op = "IntegrityCheck"
+	OpListTables           Op = "ListTables"
+)
+
+// QueryParams parameters used to build an operation's query
+type QueryParams struct {
+	Namespace  string
+	Key        string
+	Pattern    string
+	Target     string
+	Lower      string
+	Upper      string
+	Expiration int64
+	Timestamp  int64
+	Owner      string
+	Field      string
+	Value      string
+	Delta      int64
+	Buckets    int
+	Width      int64
+	Policy     CompactionPolicy
+	Eviction   EvictionPolicy
+	Limit      int
+	Order      Order
+	Version    int
+	Alias      string
+	Path       string
+	Tag        string
+	Attached   []string
+	Namespaces []string
+	Patterns   []string
+	Exclude    []string
+	Deadlines  []int64
+	Threshold  int64
+	Since      int64
+	Until      int64
+	Row        int64
+	Inserted   int64
+	Cold       bool
+	Overflow   bool
+	Checksums  bool
+	Tombstones bool
+	Active     bool
+	Unique     bool
+	Scoped     bool
+}
+
+var queryBuilders = map[Op]func(QueryParams) *dbtx{
+	OpCreateTable:          func(QueryParams) *dbtx { return newCreateTableQuery() },
+	OpPut:                  newPutQuery,
+	OpMatchKey:             newMatchKeyQuery,
+	OpCountKey:             newCountKeyQuery,
+	OpGetKeys:              newGetKeysQuery,
+	OpCountKeys:            newCountKeysQuery,
+	OpCountMatch:           newCountMatchQuery,
+	OpMatchKeyAcross:       newMatchKeyAcrossQuery,
+	OpMatchKeyAny:          newMatchKeyAnyQuery,
+	OpAllow:                newAllowQuery,
+	OpFreePages:            func(QueryParams) *dbtx { return newFreePagesQuery() },
+	OpVacuum:               func(QueryParams) *dbtx { return newVacuumQuery() },
+	OpIncrementalVacuum:    func(QueryParams) *dbtx { return newIncrementalVacuumQuery() },
+	OpDataVersion:          func(QueryParams) *dbtx { return newDataVersionQuery() },
+	OpAddInsertedColumn:    func(QueryParams) *dbtx { return newAddInsertedColumnQuery() },
+	OpGetKeysInserted:      newGetKeysInsertedQuery,
+	OpGetNamespaces:        newGetNamespacesQuery,
+	OpCountNamespaces:      newCountNamespacesQuery,
+	OpCountEntries:         newCountEntriesQuery,
+	OpPruneEntries:         newPruneEntriesQuery,
+	OpClearEntries:         func(QueryParams) *dbtx { return newClearEntriesQuery() },
+	OpIncrement:            newIncrementQuery,
+	OpGetCounter:           newGetCounterQuery,
+	OpPruneCounters:        newPruneCountersQuery,
+	OpPutIfAbsent:          newPutIfAbsentQuery,
+	OpAcquireLease:         newAcquireLeaseQuery,
+	OpRenewLease:           newRenewLeaseQuery,
+	OpReleaseLease:         newReleaseLeaseQuery,
+	OpPruneLeases:          newPruneLeasesQuery,
+	OpPutField:             newPutFieldQuery,
+	OpTouchFields:          newTouchFieldsQuery,
+	OpGetFields:            newGetFieldsQuery,
+	OpPruneFields:          newPruneFieldsQuery,
+	OpExpireEntries:        newExpireEntriesQuery,
+	OpGetExpiration:        newGetExpirationQuery,
+	OpLastExpiration:       newLastExpirationQuery,
+	OpNextExpiration:       newNextExpirationQuery,
+	OpPutOverflow:          newPutOverflowQuery,
+	OpPruneOverflow:        newPruneOverflowQuery,
+	OpChecksumColumn:       func(QueryParams) *dbtx { return newChecksumColumnQuery() },
+	OpAddChecksumColumn:    func(QueryParams) *dbtx { return newAddChecksumColumnQuery() },
+	OpQuarantineEntries:    newQuarantineEntriesQuery,
+	OpGetQuarantine:        func(QueryParams) *dbtx { return newGetQuarantineQuery() },
+	OpGetField:             newGetFieldQuery,
+	OpSnapshot:             newSnapshotQuery,
+	OpEvictEntries:         newEvictEntriesQuery,
+	OpExpirationHistogram:  newExpirationHistogramQuery,
+	OpKeyFrequency:         newKeyFrequencyQuery,
+	OpExpirationDecay:      newExpirationDecayQuery,
+	OpCompactDuplicates:    newCompactDuplicatesQuery,
+	OpCopyToColdTier:       newCopyToColdTierQuery,
+	OpMoveToColdTier:       newMoveToColdTierQuery,
+	OpPruneColdTier:        newPruneColdTierQuery,
+	OpMatchNamespaces:      newMatchNamespacesQuery,
+	OpCountKeysByNamespace: newCountKeysByNamespaceQuery,
+	OpDescribeNamespaces:   newDescribeNamespacesQuery,
+	OpTopKeys:              newTopKeysQuery,
+	OpClaim:                newClaimQuery,
+	OpExtendClaim:          newExtendClaimQuery,
+	OpReleaseClaim:         newReleaseClaimQuery,
+	OpPruneNamespace:       func(params QueryParams) *dbtx { return newPruneEntriesQuery(params.scoped()) },
+	OpClearNamespace:       newClearNamespaceQuery,
+	OpScanULIDs:            newScanRangeQuery,
+	OpScan:                 newScanQuery,
+	OpSampleKeys:           newSampleKeysQuery,
+	OpDeleteKey:            newDeleteKeyQuery,
+	OpExportNamespaces:     newExportNamespacesQuery,
+	OpSchemaTables:         func(QueryParams) *dbtx { return newSchemaTablesQuery() },
+	OpIntegrityCheck:       func(QueryParams) *dbtx { return newIntegrityCheckQuery() },
+	OpListTables:           func(QueryParams) *dbtx { return newListTablesQuery() },
+	OpSchemaVersion:        func(QueryParams) *dbtx { return newSchemaVersionQuery() },
+	OpRecordMigration:      newRecordMigrationQuery,
+	OpCopyNamespace:        newCopyNamespaceQuery,
+	OpCreateArchiveTable:   func(QueryParams) *dbtx { return newCreateArchiveTableQuery() },
+	OpArchiveEntries:       newArchiveEntriesQuery,
+	OpGetArchivedKeys:      newGetArchivedKeysQuery,
+	OpPurgeArchive:         newPurgeArchiveQuery,
+	OpCreateTombstoneTable: func(QueryParams) *dbtx { return newCreateTombstoneTableQuery() },
+	OpGetDeletedKeys:       newGetDeletedKeysQuery,
+	OpPurgeTombstones:      newPurgeTombstonesQuery,
+	OpCreateImmutableTable: func(QueryParams) *dbtx { return newCreateImmutableTableQuery() },
+	OpSetImmutable:         newSetImmutableQuery,
+	OpCreateFenceTable:     func(QueryParams) *dbtx { return newCreateFenceTableQuery() },
+	OpFence:                newFenceQuery,
+	OpGetFence:             newGetFenceQuery,
+	OpCreateAuditTable:     func(QueryParams) *dbtx { return newCreateAuditTableQuery() },
+	OpStats:                newStatsQuery,
+	OpNamespaceEntries:     newNamespaceEntriesQuery,
+	OpAttach:               newAttachQuery,
+	OpDetach:               newDetachQuery,
+	OpDatabaseList:         func(QueryParams) *dbtx { return newDatabaseListQuery() },
+	OpExpireMatch:          newExpireMatchQuery,
+	OpExpireKey:            newExpireKeyQuery,
+	OpGetEntries:           newGetEntriesQuery,
+	OpCreateTagsTable:      func(QueryParams) *dbtx { return newCreateTagsTableQuery() },
+	OpTagKey:               newTagKeyQuery,
+	OpMatchKeyByTag:        newMatchKeyByTagQuery,
+	OpPruneTags:            newPruneTagsQuery,
+	OpCreateHistoryTable:   func(QueryParams) *dbtx { return newCreateHistoryTableQuery() },
+	OpRecordHistory:        newRecordHistoryQuery,
+	OpGetKeyHistory:        newGetKeyHistoryQuery,
+	OpPruneHistory:         newPruneHistoryQuery,
+	OpPing:                 func(QueryParams) *dbtx { return newPingQuery() },
+	OpAudit: func(params QueryParams) *dbtx {
+		return newAuditQuery(AuditRecord{Time: time.UnixMilli(params.Timestamp), Op: OpPut, Namespace: params.Namespace, Key: params.Key})
+	},
+	OpQueryAudit: func(params QueryParams) *dbtx {
+		return newQueryAuditQuery(AuditFilter{Namespace: params.Namespace, Key: params.Key, Since: time.UnixMilli(params.Timestamp)})
+	},
+}
+
+// pruneQueries remove expired rows from each side table during PruneEntries
+// and PruneNamespace
+var pruneQueries = []func(QueryParams) *dbtx{
+	newPruneCountersQuery,
+	newPruneLeasesQuery,
+	newPruneFieldsQuery,
+	newPruneTagsQuery,
+	newPruneHistoryQuery,
+	newPruneColdTierQuery,
+	newPruneOverflowQuery,
+}
+
+// BuildQuery builds the SQL statement and arguments executed for an operation,
+// returning an empty statement if the operation is unknown
+func BuildQuery(op Op, params QueryParams) (string, []any) {
+	build, ok := queryBuilders[op]
+	if !ok {
+		return "", nil
+	}
+	tx := build(params)
+	return tx.query, tx.args
+}
+
+// table selects the hot table, or the union of both tiers when cold entries
+// are included in a query that covers inactive entries
+func (params QueryParams) table() string {
+	if params.Cold && !params.Active {
+		return "(" + params.verified() + " UNION ALL SELECT " + params.columns() + " FROM keybase_cold) AS keybase"
+	}
+	return params.entries()
+}
+
+// entries selects the main table, skipping rows that fail checksum
+// verification when checksums are enabled
+func (params QueryParams) entries() string {
+	if params.Checksums || len(params.Attached) > 0 {
+		return "(" + params.verified() + ") AS keybase"
+	}
+	return "keybase"
+}
+
+// verified selects the entries of the main table, followed by the entries of
+// each attached database when the operation is federated
+func (params QueryParams) verified() string {
+	query := "SELECT " + params.columns() + " FROM keybase"
+	if params.Checksums {
+		query += " WHERE NOT (" + corruptChecksum + ")"
+	}
+	for _, alias := range params.Attached {
+		query += " UNION ALL SELECT " + params.columns() + " FROM \"" + alias + "\".keybase"
+	}
+	return query
+}
+
+// columns selected by the subqueries of table, including the rowid when keys
+// are ordered by insertion
+func (params QueryParams) columns() string {
+	if params.Order == InsertionOrder {
+		return "rowid, namespace, key, expiration"
+	}
+	return "namespace, key, expiration"
+}
+
+// sort orders the selected keys. Unique keys are grouped rather than relying
+// on DISTINCT alone, so they can be ordered by their latest expiration or
+// first insertion.
+func (params QueryParams) sort(builder *sqlbuilder.SelectBuilder) {
+	column := params.keyColumn()
+	switch params.Order {
+	case KeyAscending:
+		_ = builder.OrderBy(column).Asc()
+	case KeyDescending:
+		_ = builder.OrderBy(column).Desc()
+	case ExpirationOrder:
+		if params.Unique {
+			_ = builder.GroupBy(column).OrderBy("MAX(expiration)", column)
+		} else {
+			_ = builder.OrderBy("expiration", column)
+		}
+	case InsertionOrder:
+		if params.Unique {
+			_ = builder.GroupBy(column).OrderBy("MIN(rowid)")
+		} else {
+			_ = builder.OrderBy("rowid")
+		}
+	}
+}
+
+// expired matches rows that have expired, limited to the namespace when the
+// params are scoped
+func (params QueryParams) expired(cond *sqlbuilder.Cond) []string {
+	conditions := []string{cond.LessEqualThan("expiration", params.Timestamp)}
+	if params.Scoped {
+		conditions = append(conditions, cond.Equal("namespace", params.Namespace))
+	}
+	return conditions
+}
+
+// pruned selects the stale entries of the keybase table, limited to the
+// first Limit of them when set, so that entries can be pruned in batches
+func (params QueryParams) pruned(cond *sqlbuilder.Cond) []string {
+	if params.Limit <= 0 {
+		return params.expired(cond)
+	}
+	batch := sqlbuilder.NewSelectBuilder()
+	_ = batch.Select("rowid").From("keybase").Where(params.expired(&batch.Cond)...).OrderBy("rowid").Limit(params.Limit)
+	return []string{cond.In("rowid", batch)}
+}
+
+func (params QueryParams) scoped() QueryParams {
+	params.Scoped = true
+	return params
+}
+
+// keyColumn selects the full key, resolving overflowed keys when enabled
+func (params QueryParams) keyColumn() string {
+	if params.Overflow {
+		return "COALESCE((SELECT value FROM keybase_overflow WHERE ref = keybase.key), keybase.key)"
+	}
+	return "key"
+}
+
+// likeEscape escapes the literal characters of a LIKE pattern
+const likeEscape = '\\'
+
+// globToLike translates the * and ? wildcards of a glob pattern to LIKE syntax.
+// Escaped wildcards, and characters that LIKE would treat as wildcards, are
+// kept as literal characters.
+func globToLike(pattern string) string {
+	like := strings.Builder{}
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			if r != '*' && r != '?' && r != '\\' {
+				like.WriteString(`\\`)
+			}
+			writeLikeLiteral(&like, r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			like.WriteRune('%')
+		case r == '?':
+			like.WriteRune('_')
+		default:
+			writeLikeLiteral(&like, r)
+		}
+	}
+	if escaped {
+		like.WriteString(`\\`)
+	}
+	return like.String()
+}
+
+func writeLikeLiteral(like *strings.Builder, r rune) {
+	if r == '%' || r == '_' || r == likeEscape {
+		like.WriteRune(likeEscape)
+	}
+	like.WriteRune(r)
+}
+
+// likeGlob matches the field against a glob pattern
+func likeGlob(cond *sqlbuilder.Cond, field, pattern string) string {
+	return cond.Like(field, globToLike(pattern)) + ` ESCAPE '\'`
+}
+
+// exclude appends a constraint leaving out each excluded pattern
+func (params QueryParams) exclude(cond *sqlbuilder.Cond, constraints []string) []string {
+	for _, pattern := range params.Exclude {
+		constraints = append(constraints, cond.NotLike(params.keyColumn(), globToLike(pattern))+` ESCAPE '\'`)
+	}
+	return constraints
+}
+
 func newCreateTableQuery() *dbtx {
 	return &dbtx{
 		query: `CREATE TABLE IF NOT EXISTS keybase(namespace TEXT, key TEXT, expiration INTEGER);
 		 CREATE INDEX IF NOT EXISTS namespace_index ON keybase(namespace);
-		 CREATE INDEX IF NOT EXISTS key_index ON keybase(key);`,
+		 CREATE INDEX IF NOT EXISTS key_index ON keybase(key);
+		 CREATE TABLE IF NOT EXISTS keybase_counters(namespace TEXT, key TEXT, value INTEGER, expiration INTEGER, PRIMARY KEY(namespace, key));
+		 CREATE TABLE IF NOT EXISTS keybase_leases(namespace TEXT, key TEXT, owner TEXT, expiration INTEGER, PRIMARY KEY(namespace, key));
+		 CREATE TABLE IF NOT EXISTS keybase_fields(namespace TEXT, key TEXT, field TEXT, value TEXT, expiration INTEGER, PRIMARY KEY(namespace, key, field));
+		 CREATE TABLE IF NOT EXISTS keybase_cold(namespace TEXT, key TEXT, expiration INTEGER);
+		 CREATE TABLE IF NOT EXISTS keybase_overflow(ref TEXT PRIMARY KEY, value TEXT);
+		 CREATE TABLE IF NOT EXISTS keybase_quarantine(namespace TEXT, key TEXT, expiration INTEGER, checksum INTEGER, detected INTEGER);`,
+	}
+}
+
+func newPingQuery() *dbtx {
+	return &dbtx{
+		query: "SELECT 1",
+	}
+}
+
+func newSchemaTablesQuery() *dbtx {
+	return &dbtx{
+		query: "SELECT name FROM sqlite_master WHERE type = 'table' AND name IN ('keybase', 'keybase_schema')",
 	}
 }
 
-func newPutQuery(namespace, key string, expiration int64) *dbtx {
+func newIntegrityCheckQuery() *dbtx {
+	return &dbtx{
+		query: "PRAGMA integrity_check",
+	}
+}
+
+func newListTablesQuery() *dbtx {
+	return &dbtx{
+		query: "SELECT name FROM sqlite_master WHERE type = 'table'",
+	}
+}
+
+func newAttachQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: "ATTACH DATABASE ? AS \"" + params.Alias + "\"",
+		args:  []any{params.Path},
+	}
+}
+
+func newDetachQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: "DETACH DATABASE \"" + params.Alias + "\"",
+	}
+}
+
+func newDatabaseListQuery() *dbtx {
+	return &dbtx{
+		query: "SELECT name, file FROM pragma_database_list WHERE name NOT IN ('main', 'temp')",
+	}
+}
+
+func newSchemaVersionQuery() *dbtx {
+	return &dbtx{
+		query: "SELECT COALESCE(MAX(version), 0) FROM keybase_schema",
+	}
+}
+
+func newRecordMigrationQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: `CREATE TABLE IF NOT EXISTS keybase_schema(version INTEGER PRIMARY KEY, applied INTEGER);
+		 INSERT INTO keybase_schema(version, applied) VALUES (?, ?);`,
+		args: []any{params.Version, params.Timestamp},
+	}
+}
+
+func newCreateArchiveTableQuery() *dbtx {
+	return &dbtx{
+		query: `CREATE TABLE IF NOT EXISTS keybase_archive(namespace TEXT, key TEXT, expiration INTEGER, pruned_at INTEGER);
+		 CREATE INDEX IF NOT EXISTS archive_namespace_index ON keybase_archive(namespace);`,
+	}
+}
+
+func newCreateTombstoneTableQuery() *dbtx {
+	return &dbtx{
+		query: `CREATE TABLE IF NOT EXISTS keybase_tombstones(namespace TEXT, key TEXT, expiration INTEGER, deleted_at INTEGER);
+		 CREATE INDEX IF NOT EXISTS tombstone_namespace_index ON keybase_tombstones(namespace);`,
+	}
+}
+
+// newCreateImmutableTableQuery creates the table of immutable namespaces and
+// a trigger aborting inserts of keys they already hold
+func newCreateImmutableTableQuery() *dbtx {
+	return &dbtx{
+		query: `CREATE TABLE IF NOT EXISTS keybase_immutable(namespace TEXT PRIMARY KEY);
+		 CREATE TRIGGER IF NOT EXISTS keybase_immutable_insert BEFORE INSERT ON keybase
+		 WHEN EXISTS (SELECT 1 FROM keybase_immutable WHERE namespace = NEW.namespace)
+		 AND EXISTS (SELECT 1 FROM keybase WHERE namespace = NEW.namespace AND key = NEW.key)
+		 BEGIN SELECT RAISE(ABORT, 'namespace is immutable'); END;`,
+	}
+}
+
+func newCreateFenceTableQuery() *dbtx {
+	return &dbtx{
+		query: "CREATE TABLE IF NOT EXISTS keybase_fences(namespace TEXT PRIMARY KEY, generation INTEGER)",
+	}
+}
+
+func newCreateAuditTableQuery() *dbtx {
+	return &dbtx{
+		query: `CREATE TABLE IF NOT EXISTS keybase_audit(id INTEGER PRIMARY KEY AUTOINCREMENT, time INTEGER, actor TEXT, op TEXT, namespace TEXT, key TEXT, field TEXT, target TEXT);
+		 CREATE INDEX IF NOT EXISTS audit_time_index ON keybase_audit(time);`,
+	}
+}
+
+func newCreateTagsTableQuery() *dbtx {
+	return &dbtx{
+		query: `CREATE TABLE IF NOT EXISTS keybase_tags(namespace TEXT, key TEXT, tag TEXT, PRIMARY KEY(namespace, key, tag));
+		 CREATE INDEX IF NOT EXISTS tag_index ON keybase_tags(namespace, tag);`,
+	}
+}
+
+func newTagKeyQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: "INSERT OR IGNORE INTO keybase_tags(namespace, key, tag) VALUES (?, ?, ?)",
+		args:  []any{params.Namespace, params.Key, params.Tag},
+	}
+}
+
+// newMatchKeyByTagQuery selects the keys carrying a tag that still have an
+// active entry
+func newMatchKeyByTagQuery(params QueryParams) *dbtx {
+	key := "tags.key"
+	if params.Overflow {
+		key = "COALESCE((SELECT value FROM keybase_overflow WHERE ref = tags.key), tags.key)"
+	}
+	return &dbtx{
+		query: "SELECT DISTINCT " + key + " FROM keybase_tags AS tags WHERE tags.namespace = ? AND tags.tag = ?" +
+			" AND EXISTS (SELECT 1 FROM " + params.entries() + " WHERE keybase.namespace = tags.namespace AND keybase.key = tags.key AND keybase.expiration > ?)",
+		args: []any{params.Namespace, params.Tag, params.Timestamp},
+	}
+}
+
+// newPruneTagsQuery removes the tags of keys left without entries
+func newPruneTagsQuery(params QueryParams) *dbtx {
+	tx := &dbtx{
+		query: "DELETE FROM keybase_tags WHERE NOT EXISTS (SELECT 1 FROM keybase WHERE keybase.namespace = keybase_tags.namespace AND keybase.key = keybase_tags.key)",
+	}
+	if params.Scoped {
+		tx.query += " AND namespace = ?"
+		tx.args = []any{params.Namespace}
+	}
+	return tx
+}
+
+func newCreateHistoryTableQuery() *dbtx {
+	return &dbtx{
+		query: "CREATE TABLE IF NOT EXISTS keybase_history(namespace TEXT, key TEXT, version INTEGER, time INTEGER, expiration INTEGER, PRIMARY KEY(namespace, key, version));",
+	}
+}
+
+// newRecordHistoryQuery records the next version of a key, keeping only the
+// latest versions up to the limit. The parameters are numbered, since each
+// statement binds its parameters from the first argument.
+func newRecordHistoryQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: `INSERT INTO keybase_history(namespace, key, version, time, expiration)
+		 SELECT ?1, ?2, COALESCE(MAX(version), 0) + 1, ?3, ?4 FROM keybase_history WHERE namespace = ?1 AND key = ?2;
+		 DELETE FROM keybase_history WHERE namespace = ?1 AND key = ?2
+		 AND version <= (SELECT MAX(version) FROM keybase_history WHERE namespace = ?1 AND key = ?2) - ?5;`,
+		args: []any{params.Namespace, params.Key, params.Timestamp, params.Expiration, params.Limit},
+	}
+}
+
+func newGetKeyHistoryQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: "SELECT version, time, expiration FROM keybase_history WHERE namespace = ? AND key = ? ORDER BY version",
+		args:  []any{params.Namespace, params.Key},
+	}
+}
+
+// newPruneHistoryQuery removes the history of keys left without entries
+func newPruneHistoryQuery(params QueryParams) *dbtx {
+	tx := &dbtx{
+		query: "DELETE FROM keybase_history WHERE NOT EXISTS (SELECT 1 FROM keybase WHERE keybase.namespace = keybase_history.namespace AND keybase.key = keybase_history.key)",
+	}
+	if params.Scoped {
+		tx.query += " AND namespace = ?"
+		tx.args = []any{params.Namespace}
+	}
+	return tx
+}
+
+func newAuditQuery(record AuditRecord) *dbtx {
+	return &dbtx{
+		query: "INSERT INTO keybase_audit(time, actor, op, namespace, key, field, target) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		args:  []any{record.Time.UnixMilli(), record.Actor, string(record.Op), record.Namespace, record.Key, record.Field, record.Target},
+	}
+}
+
+func newQueryAuditQuery(filter AuditFilter) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	_ = builder.Select("id", "time", "actor", "op", "namespace", "key", "field", "target").From("keybase_audit")
+	constraints := []string{}
+	if filter.Actor != "" {
+		constraints = append(constraints, builder.Equal("actor", filter.Actor))
+	}
+	if filter.Op != "" {
+		constraints = append(constraints, builder.Equal("op", string(filter.Op)))
+	}
+	if filter.Namespace != "" {
+		constraints = append(constraints, builder.Equal("namespace", filter.Namespace))
+	}
+	if filter.Key != "" {
+		constraints = append(constraints, builder.Equal("key", filter.Key))
+	}
+	if !filter.Since.IsZero() {
+		constraints = append(constraints, builder.GreaterEqualThan("time", filter.Since.UnixMilli()))
+	}
+	if !filter.Until.IsZero() {
+		constraints = append(constraints, builder.LessThan("time", filter.Until.UnixMilli()))
+	}
+	if len(constraints) > 0 {
+		_ = builder.Where(constraints...)
+	}
+	if filter.Limit > 0 {
+		_ = builder.Limit(filter.Limit)
+	}
+	tx.query, tx.args = builder.OrderBy("id").Build()
+	return tx
+}
+
+func newPutQuery(params QueryParams) *dbtx {
 	tx := new(dbtx)
 	builder := sqlbuilder.NewInsertBuilder()
-	tx.query, tx.args = builder.InsertInto("keybase").Cols("namespace", "key", "expiration").Values(namespace, key, expiration).Build()
+	_ = builder.InsertInto("keybase")
+	if params.Checksums {
+		_ = builder.Cols("namespace", "key", "expiration", "inserted_at", "checksum").Values(params.Namespace, params.Key, params.Expiration, params.Timestamp, checksum(params.Namespace, params.Key, params.Expiration))
+	} else {
+		_ = builder.Cols("namespace", "key", "expiration", "inserted_at").Values(params.Namespace, params.Key, params.Expiration, params.Timestamp)
+	}
+	tx.query, tx.args = builder.Build()
+	return tx
+}
+
+func newPutIfAbsentQuery(params QueryParams) *dbtx {
+	if params.Checksums {
+		return &dbtx{
+			query: `INSERT INTO keybase(namespace, key, expiration, inserted_at, checksum) SELECT ?, ?, ?, ?, ?
+			 WHERE NOT EXISTS (SELECT 1 FROM keybase WHERE namespace = ? AND key = ? AND expiration > ?)`,
+			args: []any{params.Namespace, params.Key, params.Expiration, params.Timestamp, checksum(params.Namespace, params.Key, params.Expiration), params.Namespace, params.Key, params.Timestamp},
+		}
+	}
+	return &dbtx{
+		query: `INSERT INTO keybase(namespace, key, expiration, inserted_at) SELECT ?, ?, ?, ?
+		 WHERE NOT EXISTS (SELECT 1 FROM keybase WHERE namespace = ? AND key = ? AND expiration > ?)`,
+		args: []any{params.Namespace, params.Key, params.Expiration, params.Timestamp, params.Namespace, params.Key, params.Timestamp},
+	}
+}
+
+// newClaimQuery inserts an entry unless the key has active entries, returning
+// the rowid of the inserted entry
+func newClaimQuery(params QueryParams) *dbtx {
+	tx := newPutIfAbsentQuery(params)
+	tx.query += " RETURNING rowid"
+	return tx
+}
+
+// newExtendClaimQuery sets the expiration of a claimed entry, identified by its
+// rowid and insertion time, unless it already expired
+func newExtendClaimQuery(params QueryParams) *dbtx {
+	builder := sqlbuilder.NewUpdateBuilder()
+	return newSetExpirationQuery(params, builder, builder.And(
+		builder.Equal("key", params.Key),
+		builder.Equal("rowid", params.Row),
+		builder.Equal("inserted_at", params.Inserted),
+		builder.GreaterThan("expiration", params.Timestamp)))
+}
+
+func newReleaseClaimQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewDeleteBuilder().DeleteFrom("keybase")
+	tx.query, tx.args = builder.Where(
+		builder.Equal("namespace", params.Namespace),
+		builder.Equal("key", params.Key),
+		builder.Equal("rowid", params.Row),
+		builder.Equal("inserted_at", params.Inserted)).Build()
+	return tx
+}
+
+func newAllowQuery(params QueryParams) *dbtx {
+	if params.Checksums {
+		return &dbtx{
+			query: `INSERT INTO keybase(namespace, key, expiration, inserted_at, checksum) SELECT ?, ?, ?, ?, ?
+			 WHERE (SELECT COUNT(key) FROM keybase WHERE namespace = ? AND key = ? AND expiration > ?) < ?`,
+			args: []any{params.Namespace, params.Key, params.Expiration, params.Timestamp, checksum(params.Namespace, params.Key, params.Expiration), params.Namespace, params.Key, params.Timestamp, params.Limit},
+		}
+	}
+	return &dbtx{
+		query: `INSERT INTO keybase(namespace, key, expiration, inserted_at) SELECT ?, ?, ?, ?
+		 WHERE (SELECT COUNT(key) FROM keybase WHERE namespace = ? AND key = ? AND expiration > ?) < ?`,
+		args: []any{params.Namespace, params.Key, params.Expiration, params.Timestamp, params.Namespace, params.Key, params.Timestamp, params.Limit},
+	}
+}
+
+func newMatchKeyQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	if params.Unique {
+		_ = builder.Distinct()
+	}
+	_ = builder.Select(params.keyColumn()).From(params.table())
+	constraints := []string{
+		builder.Equal("namespace", params.Namespace),
+		likeGlob(&builder.Cond, params.keyColumn(), params.Pattern)}
+	if params.Active {
+		constraints = append(constraints, builder.GreaterThan("expiration", params.Timestamp))
+	}
+	_ = builder.Where(params.exclude(&builder.Cond, constraints)...)
+	params.sort(builder)
+	tx.query, tx.args = builder.Build()
+	return tx
+}
+
+func newMatchKeyAnyQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	if params.Unique {
+		_ = builder.Distinct()
+	}
+	_ = builder.Select(params.keyColumn()).From(params.table())
+	patterns := make([]string, len(params.Patterns))
+	for i, pattern := range params.Patterns {
+		patterns[i] = likeGlob(&builder.Cond, params.keyColumn(), pattern)
+	}
+	constraints := []string{
+		builder.Equal("namespace", params.Namespace),
+		builder.Or(patterns...)}
+	if params.Active {
+		constraints = append(constraints, builder.GreaterThan("expiration", params.Timestamp))
+	}
+	tx.query, tx.args = builder.Where(constraints...).Build()
+	return tx
+}
+
+func newMatchKeyAcrossQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	if params.Unique {
+		_ = builder.Distinct()
+	}
+	_ = builder.Select("namespace", params.keyColumn()).From(params.table())
+	constraints := []string{}
+	if len(params.Namespaces) > 0 {
+		namespaces := make([]any, len(params.Namespaces))
+		for i, namespace := range params.Namespaces {
+			namespaces[i] = namespace
+		}
+		constraints = append(constraints, builder.In("namespace", namespaces...))
+	}
+	if params.Pattern != "" {
+		constraints = append(constraints, likeGlob(&builder.Cond, params.keyColumn(), params.Pattern))
+	}
+	if params.Active {
+		constraints = append(constraints, builder.GreaterThan("expiration", params.Timestamp))
+	}
+	if len(constraints) > 0 {
+		_ = builder.Where(constraints...)
+	}
+	tx.query, tx.args = builder.OrderBy("namespace").Build()
+	return tx
+}
+
+func newCountKeyQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	_ = builder.Select("COUNT(key)").From(params.table())
+	constraints := []string{
+		builder.Equal("namespace", params.Namespace),
+		builder.Equal("key", params.Key)}
+	if params.Active {
+		constraints = append(constraints, builder.GreaterThan("expiration", params.Timestamp))
+	}
+	tx.query, tx.args = builder.Where(constraints...).Build()
+	return tx
+}
+
+func newGetExpirationQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	_ = builder.Select("MAX(expiration)").From(params.entries())
+	tx.query, tx.args = builder.Where(
+		builder.Equal("namespace", params.Namespace),
+		builder.Equal("key", params.Key),
+		builder.GreaterThan("expiration", params.Timestamp)).Build()
+	return tx
+}
+
+func newNextExpirationQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	_ = builder.Select("MIN(expiration)").From(params.entries())
+	_ = builder.Where(builder.Equal("namespace", params.Namespace))
+	if params.Key != "" {
+		_ = builder.Where(builder.Equal("key", params.Key))
+	}
+	tx.query, tx.args = builder.Where(builder.GreaterThan("expiration", params.Timestamp)).Build()
+	return tx
+}
+
+func newLastExpirationQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	_ = builder.Select("MAX(expiration)").From(params.entries())
+	tx.query, tx.args = builder.Where(
+		builder.Equal("namespace", params.Namespace),
+		builder.GreaterThan("expiration", params.Timestamp)).Build()
+	return tx
+}
+
+func newExpirationHistogramQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	bucket := fmt.Sprintf("MIN((expiration - %s) / %s, %s)",
+		builder.Var(params.Timestamp), builder.Var(params.Width), builder.Var(params.Buckets-1))
+	_ = builder.Select(builder.As(bucket, "bucket"), "COUNT(*)").From(params.entries())
+	tx.query, tx.args = builder.Where(
+		builder.Equal("namespace", params.Namespace),
+		builder.GreaterThan("expiration", params.Timestamp)).GroupBy("bucket").Build()
+	return tx
+}
+
+// newExpirationDecayQuery counts the active entries of every namespace that
+// expire by each of the deadlines
+func newExpirationDecayQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	columns := []string{}
+	for _, deadline := range params.Deadlines {
+		columns = append(columns, fmt.Sprintf("COUNT(CASE WHEN expiration <= %s THEN 1 END)", builder.Var(deadline)))
+	}
+	_ = builder.Select(columns...).From(params.entries())
+	tx.query, tx.args = builder.Where(builder.GreaterThan("expiration", params.Timestamp)).Build()
+	return tx
+}
+
+// newKeyFrequencyQuery counts the active entries of a key by the bucket of
+// their insertion time, starting from Since
+func newKeyFrequencyQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	bucket := fmt.Sprintf("MIN((inserted_at - %s) / %s, %s)",
+		builder.Var(params.Since), builder.Var(params.Width), builder.Var(params.Buckets-1))
+	_ = builder.Select(builder.As(bucket, "bucket"), "COUNT(*)").From("keybase")
+	constraints := []string{
+		builder.Equal("namespace", params.Namespace),
+		builder.Equal("key", params.Key),
+		builder.GreaterThan("expiration", params.Timestamp),
+		builder.GreaterEqualThan("inserted_at", params.Since)}
+	if params.Checksums {
+		constraints = append(constraints, "NOT ("+corruptChecksum+")")
+	}
+	tx.query, tx.args = builder.Where(constraints...).GroupBy("bucket").Build()
+	return tx
+}
+
+func newGetKeysQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	if params.Unique {
+		_ = builder.Distinct()
+	}
+	_ = builder.Select(params.keyColumn()).From(params.table())
+	constraints := []string{
+		builder.Equal("namespace", params.Namespace)}
+	if params.Active {
+		constraints = append(constraints, builder.GreaterThan("expiration", params.Timestamp))
+	}
+	_ = builder.Where(params.exclude(&builder.Cond, constraints)...)
+	params.sort(builder)
+	tx.query, tx.args = builder.Build()
+	return tx
+}
+
+func newGetEntriesQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	_ = builder.Select(params.keyColumn(), "expiration").From(params.table())
+	constraints := []string{
+		builder.Equal("namespace", params.Namespace)}
+	if params.Pattern != "" {
+		constraints = append(constraints, likeGlob(&builder.Cond, params.keyColumn(), params.Pattern))
+	}
+	if params.Active {
+		constraints = append(constraints, builder.GreaterThan("expiration", params.Timestamp))
+	}
+	_ = builder.Where(constraints...).OrderBy("expiration")
+	if params.Limit > 0 {
+		_ = builder.Limit(params.Limit)
+	}
+	tx.query, tx.args = builder.Build()
+	return tx
+}
+
+func newScanRangeQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	_ = builder.Distinct().Select("key").From(params.table())
+	constraints := []string{
+		builder.Equal("namespace", params.Namespace),
+		builder.GreaterEqualThan("key", params.Lower),
+		builder.LessThan("key", params.Upper)}
+	if params.Active {
+		constraints = append(constraints, builder.GreaterThan("expiration", params.Timestamp))
+	}
+	tx.query, tx.args = builder.Where(constraints...).OrderBy("key").Build()
+	return tx
+}
+
+// newScanQuery selects up to Limit active keys of a namespace, in ascending
+// order, starting from Lower
+func newScanQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	column := params.keyColumn()
+	_ = builder.Distinct().Select(column).From(params.table())
+	constraints := []string{
+		builder.Equal("namespace", params.Namespace),
+		builder.GreaterEqualThan(column, params.Lower),
+		builder.GreaterThan("expiration", params.Timestamp)}
+	tx.query, tx.args = builder.Where(constraints...).OrderBy(column).Limit(params.Limit).Build()
+	return tx
+}
+
+// newSampleKeysQuery selects up to Limit distinct keys of a namespace at
+// random
+func newSampleKeysQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	_ = builder.Distinct().Select(params.keyColumn()).From(params.table())
+	constraints := []string{
+		builder.Equal("namespace", params.Namespace)}
+	if params.Active {
+		constraints = append(constraints, builder.GreaterThan("expiration", params.Timestamp))
+	}
+	tx.query, tx.args = builder.Where(constraints...).OrderBy("RANDOM()").Limit(params.Limit).Build()
+	return tx
+}
+
+// newGetKeysInsertedQuery selects the keys of a namespace inserted at or after
+// Since and before Until, in the order they first arrived
+func newGetKeysInsertedQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	column := params.keyColumn()
+	_ = builder.Select(column).From("keybase")
+	constraints := []string{
+		builder.Equal("namespace", params.Namespace),
+		builder.GreaterEqualThan("inserted_at", params.Since),
+		builder.LessThan("inserted_at", params.Until)}
+	if params.Checksums {
+		constraints = append(constraints, "NOT ("+corruptChecksum+")")
+	}
+	tx.query, tx.args = builder.Where(constraints...).GroupBy(column).OrderBy("MIN(inserted_at)", "MIN(rowid)").Build()
+	return tx
+}
+
+func newCountKeysQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	col := "COUNT(key)"
+	if params.Unique {
+		col = "COUNT(DISTINCT key)"
+	}
+	_ = builder.Select(col).From(params.table())
+	constraints := []string{
+		builder.Equal("namespace", params.Namespace)}
+	if params.Active {
+		constraints = append(constraints, builder.GreaterThan("expiration", params.Timestamp))
+	}
+	tx.query, tx.args = builder.Where(constraints...).Build()
+	return tx
+}
+
+func newCountMatchQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	col := "COUNT(key)"
+	if params.Unique {
+		col = "COUNT(DISTINCT key)"
+	}
+	_ = builder.Select(col).From(params.table())
+	constraints := []string{
+		builder.Equal("namespace", params.Namespace),
+		likeGlob(&builder.Cond, params.keyColumn(), params.Pattern)}
+	if params.Active {
+		constraints = append(constraints, builder.GreaterThan("expiration", params.Timestamp))
+	}
+	tx.query, tx.args = builder.Where(constraints...).Build()
+	return tx
+}
+
+func newCountKeysByNamespaceQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	col := "COUNT(key)"
+	if params.Unique {
+		col = "COUNT(DISTINCT key)"
+	}
+	_ = builder.Select("namespace", col).From(params.table())
+	if params.Active {
+		_ = builder.Where(builder.GreaterThan("expiration", params.Timestamp))
+	}
+	tx.query, tx.args = builder.GroupBy("namespace").Build()
 	return tx
 }
 
-func newMatchKeyQuery(namespace, pattern string, active, unique bool, timestamp int64) *dbtx {
+func newTopKeysQuery(params QueryParams) *dbtx {
 	tx := new(dbtx)
 	builder := sqlbuilder.NewSelectBuilder()
-	if unique {
-		_ = builder.Distinct()
-	}
-	_ = builder.Select("key").From("keybase")
+	column := params.keyColumn()
+	_ = builder.Select(column, builder.As("COUNT(*)", "count")).From(params.table())
 	constraints := []string{
-		builder.Equal("namespace", namespace),
-		builder.Like("key", strings.ReplaceAll(strings.ReplaceAll(pattern, "*", "%"), "?", "_"))}
-	if active {
-		constraints = append(constraints, builder.GreaterThan("expiration", timestamp))
+		builder.Equal("namespace", params.Namespace)}
+	if params.Active {
+		constraints = append(constraints, builder.GreaterThan("expiration", params.Timestamp))
 	}
-	tx.query, tx.args = builder.Where(constraints...).Build()
+	tx.query, tx.args = builder.Where(constraints...).GroupBy(column).OrderBy("count DESC", column).Limit(params.Limit).Build()
 	return tx
 }
 
-func newCountKeyQuery(namespace, key string, active bool, timestamp int64) *dbtx {
+func newGetNamespacesQuery(params QueryParams) *dbtx {
 	tx := new(dbtx)
-	builder := sqlbuilder.NewSelectBuilder()
-	_ = builder.Select("COUNT(key)").From("keybase")
-	constraints := []string{
-		builder.Equal("namespace", namespace),
-		builder.Equal("key", key)}
-	if active {
-		constraints = append(constraints, builder.GreaterThan("expiration", timestamp))
+	builder := sqlbuilder.NewSelectBuilder().Distinct()
+	_ = builder.Select("namespace").From(params.table())
+	if params.Active {
+		_ = builder.Where(builder.GreaterThan("expiration", params.Timestamp))
 	}
-	tx.query, tx.args = builder.Where(constraints...).Build()
+	tx.query, tx.args = builder.Build()
 	return tx
 }
 
-func newGetKeysQuery(namespace string, active, unique bool, timestamp int64) *dbtx {
+func newMatchNamespacesQuery(params QueryParams) *dbtx {
 	tx := new(dbtx)
-	builder := sqlbuilder.NewSelectBuilder()
-	if unique {
-		_ = builder.Distinct()
-	}
-	_ = builder.Select("key").From("keybase")
+	builder := sqlbuilder.NewSelectBuilder().Distinct()
+	_ = builder.Select("namespace").From(params.table())
 	constraints := []string{
-		builder.Equal("namespace", namespace)}
-	if active {
-		constraints = append(constraints, builder.GreaterThan("expiration", timestamp))
+		likeGlob(&builder.Cond, "namespace", params.Pattern)}
+	if params.Active {
+		constraints = append(constraints, builder.GreaterThan("expiration", params.Timestamp))
 	}
 	tx.query, tx.args = builder.Where(constraints...).Build()
 	return tx
 }
 
-func newCountKeysQuery(namespace string, active, unique bool, timestamp int64) *dbtx {
+func newExportNamespacesQuery(params QueryParams) *dbtx {
 	tx := new(dbtx)
 	builder := sqlbuilder.NewSelectBuilder()
-	col := "COUNT(key)"
-	if unique {
-		col = "COUNT(DISTINCT key)"
-	}
-	_ = builder.Select(col).From("keybase")
+	_ = builder.Select("namespace", params.keyColumn(), "MAX(expiration)").From(params.table())
 	constraints := []string{
-		builder.Equal("namespace", namespace)}
-	if active {
-		constraints = append(constraints, builder.GreaterThan("expiration", timestamp))
+		likeGlob(&builder.Cond, "namespace", params.Pattern)}
+	if params.Active {
+		constraints = append(constraints, builder.GreaterThan("expiration", params.Timestamp))
 	}
-	tx.query, tx.args = builder.Where(constraints...).Build()
+	tx.query, tx.args = builder.Where(constraints...).GroupBy("namespace", "keybase.key").OrderBy("namespace", "keybase.key").Build()
 	return tx
 }
 
-func newGetNamespacesQuery(active bool, timestamp int64) *dbtx {
-	tx := new(dbtx)
-	builder := sqlbuilder.NewSelectBuilder().Distinct()
-	_ = builder.Select("namespace").From("keybase")
-	if active {
-		_ = builder.Where(builder.GreaterThan("expiration", timestamp))
+func newStatsQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: `SELECT COUNT(*), COUNT(CASE WHEN expiration > ? THEN 1 END), MIN(expiration), MAX(expiration),
+		 (SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()) FROM ` + params.entries(),
+		args: []any{params.Timestamp},
 	}
-	tx.query, tx.args = builder.Build()
+}
+
+func newDescribeNamespacesQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: `SELECT namespace, COUNT(*), COUNT(DISTINCT key), COUNT(CASE WHEN expiration > ?1 THEN 1 END),
+		 MIN(CASE WHEN expiration > ?1 THEN expiration END) FROM ` + params.entries() + " GROUP BY namespace ORDER BY namespace",
+		args: []any{params.Timestamp},
+	}
+}
+
+func newNamespaceEntriesQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	_ = builder.Select("namespace", "COUNT(*)").From(params.entries())
+	tx.query, tx.args = builder.Where(builder.GreaterThan("expiration", params.Timestamp)).GroupBy("namespace").Build()
 	return tx
 }
 
-func newCountNamespacesQuery(active bool, timestamp int64) *dbtx {
+func newCountNamespacesQuery(params QueryParams) *dbtx {
 	tx := new(dbtx)
-	builder := sqlbuilder.NewSelectBuilder().Select("COUNT(DISTINCT namespace)").From("keybase")
-	if active {
-		_ = builder.Where(builder.GreaterThan("expiration", timestamp))
+	builder := sqlbuilder.NewSelectBuilder().Select("COUNT(DISTINCT namespace)").From(params.table())
+	if params.Active {
+		_ = builder.Where(builder.GreaterThan("expiration", params.Timestamp))
 	}
 	tx.query, tx.args = builder.Build()
 	return tx
 }
 
-func newCountEntriesQuery(active, unique bool, timestamp int64) *dbtx {
+func newCountEntriesQuery(params QueryParams) *dbtx {
 	tx := new(dbtx)
 	builder := sqlbuilder.NewSelectBuilder()
 	col := "COUNT(CONCAT(namespace, key))"
-	if unique {
+	if params.Unique {
 		col = "COUNT(DISTINCT CONCAT(namespace, key))"
 	}
-	_ = builder.Select(col).From("keybase")
-	if active {
-		_ = builder.Where(builder.GreaterThan("expiration", timestamp))
+	_ = builder.Select(col).From(params.table())
+	if params.Active {
+		_ = builder.Where(builder.GreaterThan("expiration", params.Timestamp))
 	}
 	tx.query, tx.args = builder.Build()
 	return tx
 }
 
-func newPruneEntriesQuery(timestamp int64) *dbtx {
+func newEvictEntriesQuery(params QueryParams) *dbtx {
+	order := "expiration, rowid"
+	if params.Eviction == EvictRandom {
+		order = "RANDOM()"
+	}
+	return &dbtx{
+		query: "DELETE FROM keybase WHERE rowid IN (SELECT rowid FROM keybase WHERE expiration > ? ORDER BY " + order + " LIMIT ?)",
+		args:  []any{params.Timestamp, params.Limit},
+	}
+}
+
+func newPruneEntriesQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewDeleteBuilder().DeleteFrom("keybase")
+	tx.query, tx.args = builder.Where(params.pruned(&builder.Cond)...).Build()
+	return tx
+}
+
+func newExpireEntriesQuery(params QueryParams) *dbtx {
+	tx := newPruneEntriesQuery(params)
+	tx.query += " RETURNING namespace, " + params.keyColumn()
+	return tx
+}
+
+// newExpireMatchQuery sets the expiration of every entry of a namespace with
+// a key matching the pattern
+func newExpireMatchQuery(params QueryParams) *dbtx {
+	builder := sqlbuilder.NewUpdateBuilder()
+	return newSetExpirationQuery(params, builder, likeGlob(&builder.Cond, params.keyColumn(), params.Pattern))
+}
+
+// newExpireKeyQuery sets the expiration of every entry of a single key
+func newExpireKeyQuery(params QueryParams) *dbtx {
+	builder := sqlbuilder.NewUpdateBuilder()
+	return newSetExpirationQuery(params, builder, builder.Equal("key", params.Key))
+}
+
+// newSetExpirationQuery updates the expiration of the matched entries,
+// recomputing the checksum of entries that pass verification
+func newSetExpirationQuery(params QueryParams, builder *sqlbuilder.UpdateBuilder, match string) *dbtx {
+	tx := new(dbtx)
+	assignments := []string{builder.Assign("expiration", params.Expiration)}
+	constraints := []string{builder.Equal("namespace", params.Namespace), match}
+	if params.Checksums {
+		assignments = append(assignments, "checksum = CASE WHEN checksum IS NULL THEN NULL ELSE keybase_checksum(namespace, key, "+builder.Var(params.Expiration)+") END")
+		constraints = append(constraints, "NOT ("+corruptChecksum+")")
+	}
+	tx.query, tx.args = builder.Update("keybase").Set(assignments...).Where(constraints...).Build()
+	return tx
+}
+
+func newCompactDuplicatesQuery(params QueryParams) *dbtx {
+	order := ">"
+	if params.Policy == KeepEarliest {
+		order = "<"
+	}
+	return &dbtx{
+		query: fmt.Sprintf(`DELETE FROM keybase WHERE EXISTS (SELECT 1 FROM keybase AS other
+		 WHERE other.namespace = keybase.namespace AND other.key = keybase.key
+		 AND (other.expiration %s keybase.expiration OR (other.expiration = keybase.expiration AND other.rowid > keybase.rowid)))`, order),
+	}
+}
+
+func newCopyToColdTierQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: "INSERT INTO keybase_cold(namespace, key, expiration) SELECT namespace, key, expiration FROM keybase WHERE expiration <= ?",
+		args:  []any{params.Timestamp - params.Threshold},
+	}
+}
+
+// newCopyNamespaceQuery copies the active entries of a namespace to the
+// target, keeping their expiration unless a new one is given. Copies are
+// inserted at the time of the copy.
+func newCopyNamespaceQuery(params QueryParams) *dbtx {
+	expiration, args := "expiration", []any{params.Target}
+	if params.Expiration > 0 {
+		expiration = "?"
+		args = append(args, params.Expiration)
+	}
+	columns, values := "namespace, key, expiration", "?, key, "+expiration
+	if params.Checksums {
+		columns += ", checksum"
+		values += ", keybase_checksum(?, key, " + expiration + ")"
+		args = append(args, args...)
+	}
+	columns += ", inserted_at"
+	values += ", ?"
+	args = append(args, params.Timestamp)
+	return &dbtx{
+		query: "INSERT INTO keybase(" + columns + ") SELECT " + values + " FROM " + params.entries() + " WHERE namespace = ? AND expiration > ?",
+		args:  append(args, params.Namespace, params.Timestamp),
+	}
+}
+
+func newArchiveEntriesQuery(params QueryParams) *dbtx {
+	builder := sqlbuilder.NewSelectBuilder()
+	_ = builder.Select("namespace", "key", "expiration", builder.Var(params.Timestamp)).From("keybase")
+	query, args := builder.Where(params.pruned(&builder.Cond)...).Build()
+	return &dbtx{
+		query: "INSERT INTO keybase_archive(namespace, key, expiration, pruned_at) " + query,
+		args:  args,
+	}
+}
+
+func newGetArchivedKeysQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder().Distinct()
+	_ = builder.Select(params.keyColumn()).From("keybase_archive AS keybase")
+	tx.query, tx.args = builder.Where(builder.Equal("namespace", params.Namespace)).Build()
+	return tx
+}
+
+func newPurgeArchiveQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewDeleteBuilder().DeleteFrom("keybase_archive")
+	tx.query, tx.args = builder.Where(builder.LessEqualThan("pruned_at", params.Timestamp-params.Threshold)).Build()
+	return tx
+}
+
+func newGetDeletedKeysQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder().Distinct()
+	_ = builder.Select(params.keyColumn()).From("keybase_tombstones AS keybase")
+	tx.query, tx.args = builder.Where(builder.Equal("namespace", params.Namespace)).Build()
+	return tx
+}
+
+func newPurgeTombstonesQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewDeleteBuilder().DeleteFrom("keybase_tombstones")
+	tx.query, tx.args = builder.Where(builder.LessEqualThan("deleted_at", params.Timestamp-params.Threshold)).Build()
+	return tx
+}
+
+func newSetImmutableQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: "INSERT OR IGNORE INTO keybase_immutable(namespace) VALUES (?)",
+		args:  []any{params.Namespace},
+	}
+}
+
+func newMoveToColdTierQuery(params QueryParams) *dbtx {
 	tx := new(dbtx)
 	builder := sqlbuilder.NewDeleteBuilder().DeleteFrom("keybase")
-	tx.query, tx.args = builder.Where(builder.LessEqualThan("expiration", timestamp)).Build()
+	tx.query, tx.args = builder.Where(builder.LessEqualThan("expiration", params.Timestamp-params.Threshold)).Build()
+	return tx
+}
+
+func newPruneColdTierQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewDeleteBuilder().DeleteFrom("keybase_cold")
+	tx.query, tx.args = builder.Where(params.expired(&builder.Cond)...).Build()
 	return tx
 }
 
+func newPutOverflowQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: "INSERT OR IGNORE INTO keybase_overflow(ref, value) VALUES (?, ?)",
+		args:  []any{params.Key, params.Value},
+	}
+}
+
+func newPruneOverflowQuery(QueryParams) *dbtx {
+	return &dbtx{
+		query: `DELETE FROM keybase_overflow WHERE ref NOT IN (SELECT key FROM keybase)
+		 AND ref NOT IN (SELECT key FROM keybase_cold) AND ref NOT IN (SELECT key FROM keybase_archive)
+		 AND ref NOT IN (SELECT key FROM keybase_tombstones)`,
+	}
+}
+
+func newChecksumColumnQuery() *dbtx {
+	return &dbtx{
+		query: "SELECT COUNT(*) FROM pragma_table_info('keybase') WHERE name = 'checksum'",
+	}
+}
+
+func newAddChecksumColumnQuery() *dbtx {
+	return &dbtx{
+		query: "ALTER TABLE keybase ADD COLUMN checksum INTEGER",
+	}
+}
+
+func newAddInsertedColumnQuery() *dbtx {
+	return &dbtx{
+		query: `ALTER TABLE keybase ADD COLUMN inserted_at INTEGER;
+		 CREATE INDEX IF NOT EXISTS inserted_index ON keybase(namespace, inserted_at);`,
+	}
+}
+
+func newQuarantineEntriesQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: `INSERT INTO keybase_quarantine(namespace, key, expiration, checksum, detected)
+		 SELECT namespace, key, expiration, checksum, ? FROM keybase WHERE ` + corruptChecksum + `;
+		 DELETE FROM keybase WHERE ` + corruptChecksum + ";",
+		args: []any{params.Timestamp},
+	}
+}
+
+func newGetQuarantineQuery() *dbtx {
+	return &dbtx{
+		query: "SELECT namespace, key, expiration, detected FROM keybase_quarantine ORDER BY detected",
+	}
+}
+
+func newSnapshotQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: "VACUUM INTO ?",
+		args:  []any{params.Value},
+	}
+}
+
+func newFreePagesQuery() *dbtx {
+	return &dbtx{
+		query: "SELECT freelist_count, page_count, auto_vacuum FROM pragma_freelist_count(), pragma_page_count(), pragma_auto_vacuum()",
+	}
+}
+
+func newVacuumQuery() *dbtx {
+	return &dbtx{
+		query: "VACUUM",
+	}
+}
+
+func newIncrementalVacuumQuery() *dbtx {
+	return &dbtx{
+		query: "PRAGMA incremental_vacuum",
+	}
+}
+
+func newDataVersionQuery() *dbtx {
+	return &dbtx{
+		query: "PRAGMA data_version",
+	}
+}
+
 func newClearEntriesQuery() *dbtx {
 	return &dbtx{
-		query: "DELETE FROM keybase;",
+		query: "DELETE FROM keybase; DELETE FROM keybase_counters; DELETE FROM keybase_leases; DELETE FROM keybase_fields; DELETE FROM keybase_cold; DELETE FROM keybase_overflow; DELETE FROM keybase_quarantine; DELETE FROM keybase_archive; DELETE FROM keybase_tags; DELETE FROM keybase_history;",
+	}
+}
+
+func newClearNamespaceQuery(params QueryParams) *dbtx {
+	tx := &dbtx{}
+	for _, table := range []string{"keybase", "keybase_counters", "keybase_leases", "keybase_fields", "keybase_cold", "keybase_quarantine", "keybase_archive", "keybase_tags", "keybase_history"} {
+		tx.query += "DELETE FROM " + table + " WHERE namespace = ?; "
+		tx.args = append(tx.args, params.Namespace)
+	}
+	tx.query += newPruneOverflowQuery(params).query + ";"
+	return tx
+}
+
+// newDeleteKeyQuery removes every entry of a key. With tombstones, the entries
+// are first moved to the tombstone table, marked with the time of deletion.
+func newDeleteKeyQuery(params QueryParams) *dbtx {
+	if params.Tombstones {
+		return &dbtx{
+			query: `INSERT INTO keybase_tombstones(namespace, key, expiration, deleted_at)
+			 SELECT namespace, key, expiration, ?3 FROM keybase WHERE namespace = ?1 AND key = ?2
+			 UNION ALL SELECT namespace, key, expiration, ?3 FROM keybase_cold WHERE namespace = ?1 AND key = ?2;
+			 DELETE FROM keybase WHERE namespace = ?1 AND key = ?2; DELETE FROM keybase_cold WHERE namespace = ?1 AND key = ?2;
+			 DELETE FROM keybase_tags WHERE namespace = ?1 AND key = ?2;`,
+			args: []any{params.Namespace, params.Key, params.Timestamp},
+		}
+	}
+	return &dbtx{
+		query: `DELETE FROM keybase WHERE namespace = ? AND key = ?; DELETE FROM keybase_cold WHERE namespace = ? AND key = ?;
+		 DELETE FROM keybase_tags WHERE namespace = ? AND key = ?;`,
+		args: []any{params.Namespace, params.Key, params.Namespace, params.Key, params.Namespace, params.Key},
+	}
+}
+
+func newIncrementQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: `INSERT INTO keybase_counters(namespace, key, value, expiration) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(namespace, key) DO UPDATE SET
+		 value = CASE WHEN expiration > ? THEN value + excluded.value ELSE excluded.value END,
+		 expiration = CASE WHEN expiration > ? THEN expiration ELSE excluded.expiration END
+		 RETURNING value`,
+		args: []any{params.Namespace, params.Key, params.Delta, params.Expiration, params.Timestamp, params.Timestamp},
+	}
+}
+
+func newFenceQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: `INSERT INTO keybase_fences(namespace, generation) VALUES (?, 1)
+		 ON CONFLICT(namespace) DO UPDATE SET generation = generation + 1
+		 RETURNING generation`,
+		args: []any{params.Namespace},
+	}
+}
+
+func newGetFenceQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	_ = builder.Select("generation").From("keybase_fences")
+	tx.query, tx.args = builder.Where(builder.Equal("namespace", params.Namespace)).Build()
+	return tx
+}
+
+func newGetCounterQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	_ = builder.Select("value").From("keybase_counters")
+	tx.query, tx.args = builder.Where(
+		builder.Equal("namespace", params.Namespace),
+		builder.Equal("key", params.Key),
+		builder.GreaterThan("expiration", params.Timestamp)).Build()
+	return tx
+}
+
+func newPruneCountersQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewDeleteBuilder().DeleteFrom("keybase_counters")
+	tx.query, tx.args = builder.Where(params.expired(&builder.Cond)...).Build()
+	return tx
+}
+
+func newAcquireLeaseQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: `INSERT INTO keybase_leases(namespace, key, owner, expiration) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(namespace, key) DO UPDATE SET owner = excluded.owner, expiration = excluded.expiration
+		 WHERE expiration <= ?`,
+		args: []any{params.Namespace, params.Key, params.Owner, params.Expiration, params.Timestamp},
+	}
+}
+
+func newRenewLeaseQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewUpdateBuilder().Update("keybase_leases")
+	tx.query, tx.args = builder.Set(builder.Assign("expiration", params.Expiration)).Where(
+		builder.Equal("namespace", params.Namespace),
+		builder.Equal("key", params.Key),
+		builder.Equal("owner", params.Owner),
+		builder.GreaterThan("expiration", params.Timestamp)).Build()
+	return tx
+}
+
+func newReleaseLeaseQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewDeleteBuilder().DeleteFrom("keybase_leases")
+	tx.query, tx.args = builder.Where(
+		builder.Equal("namespace", params.Namespace),
+		builder.Equal("key", params.Key),
+		builder.Equal("owner", params.Owner)).Build()
+	return tx
+}
+
+func newPruneLeasesQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewDeleteBuilder().DeleteFrom("keybase_leases")
+	tx.query, tx.args = builder.Where(params.expired(&builder.Cond)...).Build()
+	return tx
+}
+
+func newPutFieldQuery(params QueryParams) *dbtx {
+	return &dbtx{
+		query: `INSERT INTO keybase_fields(namespace, key, field, value, expiration) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT(namespace, key, field) DO UPDATE SET value = excluded.value, expiration = excluded.expiration`,
+		args: []any{params.Namespace, params.Key, params.Field, params.Value, params.Expiration},
 	}
 }
 
-func (tx dbtx) queryExec(ctx context.Context, db *sql.DB) error {
-	_, err := db.ExecContext(ctx, tx.query, tx.args...)
+func newTouchFieldsQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewUpdateBuilder().Update("keybase_fields")
+	tx.query, tx.args = builder.Set(builder.Assign("expiration", params.Expiration)).Where(
+		builder.Equal("namespace", params.Namespace),
+		builder.Equal("key", params.Key),
+		builder.GreaterThan("expiration", params.Timestamp)).Build()
+	return tx
+}
+
+func newGetFieldsQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	_ = builder.Select("field", "value").From("keybase_fields")
+	tx.query, tx.args = builder.Where(
+		builder.Equal("namespace", params.Namespace),
+		builder.Equal("key", params.Key),
+		builder.GreaterThan("expiration", params.Timestamp)).Build()
+	return tx
+}
+
+func newGetFieldQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewSelectBuilder()
+	_ = builder.Select("value").From("keybase_fields")
+	tx.query, tx.args = builder.Where(
+		builder.Equal("namespace", params.Namespace),
+		builder.Equal("key", params.Key),
+		builder.Equal("field", params.Field),
+		builder.GreaterThan("expiration", params.Timestamp)).Build()
+	return tx
+}
+
+func newPruneFieldsQuery(params QueryParams) *dbtx {
+	tx := new(dbtx)
+	builder := sqlbuilder.NewDeleteBuilder().DeleteFrom("keybase_fields")
+	tx.query, tx.args = builder.Where(params.expired(&builder.Cond)...).Build()
+	return tx
+}
+
+func (tx dbtx) queryExec(ctx context.Context, db querier) error {
+	_, err := tx.queryRowsAffected(ctx, db)
+	return err
+}
+
+func (tx dbtx) queryRowsAffected(ctx context.Context, db querier) (rows int64, err error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	start := time.Now()
+	defer func() {
+		observe(ctx, db, start, int(rows), err)
+	}()
+	result, err := db.ExecContext(ctx, tx.query, tx.args...)
 	if err != nil {
-		return err
+		return 0, err
+	}
+	rows, err = result.RowsAffected()
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	return rows, nil
 }
 
-func (tx dbtx) queryCount(ctx context.Context, db *sql.DB) (int, error) {
-	count := 0
+func (tx dbtx) queryCount(ctx context.Context, db querier) (count int, err error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	rows := 0
+	start := time.Now()
+	defer func() {
+		observe(ctx, db, start, rows, err)
+	}()
 	row, err := db.QueryContext(ctx, tx.query, tx.args...)
 	if err != nil {
 		return count, err
@@ -179,6 +1649,7 @@ func (tx dbtx) queryCount(ctx context.Context, db *sql.DB) (int, error) {
 		_ = row.Close()
 	}()
 	if row.Next() {
+		rows++
 		err = row.Scan(&count)
 		if err != nil {
 			return count, err
@@ -187,9 +1658,65 @@ func (tx dbtx) queryCount(ctx context.Context, db *sql.DB) (int, error) {
 	return count, nil
 }
 
-func (tx dbtx) queryValues(ctx context.Context, db *sql.DB) ([]string, error) {
+func (tx dbtx) queryNullInt(ctx context.Context, db querier) (value sql.NullInt64, err error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	rows := 0
+	start := time.Now()
+	defer func() {
+		observe(ctx, db, start, rows, err)
+	}()
+	row, err := db.QueryContext(ctx, tx.query, tx.args...)
+	if err != nil {
+		return value, err
+	}
+	defer func() {
+		_ = row.Close()
+	}()
+	if row.Next() {
+		rows++
+		err = row.Scan(&value)
+		if err != nil {
+			return value, err
+		}
+	}
+	return value, row.Err()
+}
+
+func (tx dbtx) queryRows(ctx context.Context, db querier, scan func(rows *sql.Rows) error) (err error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	count := 0
+	start := time.Now()
+	defer func() {
+		observe(ctx, db, start, count, err)
+	}()
+	rows, err := db.QueryContext(ctx, tx.query, tx.args...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	for rows.Next() {
+		count++
+		err = scan(rows)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (tx dbtx) queryValues(ctx context.Context, db querier) (values []string, err error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	start := time.Now()
+	defer func() {
+		observe(ctx, db, start, len(values), err)
+	}()
 	value := ""
-	values := []string{}
+	values = []string{}
 	rows, err := db.QueryContext(ctx, tx.query, tx.args...)
 	if err != nil {
 		return nil, err
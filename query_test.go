@@ -24,7 +24,12 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -42,6 +47,7 @@ const (
 
 var (
 	timestamp int64 = time.Now().UnixMilli()
+	update          = flag.Bool("update", false, "update golden files")
 )
 
 func newMock() (*sql.DB, sqlmock.Sqlmock) {
@@ -64,7 +70,7 @@ func TestNewTableQuery(t *testing.T) {
 
 func TestNewPutQuery(t *testing.T) {
 	db, mock := newMock()
-	tx := newPutQuery(namespace, key, timestamp)
+	tx := newPutQuery(QueryParams{Namespace: namespace, Key: key, Expiration: timestamp})
 
 	mock.ExpectExec(regexp.QuoteMeta(tx.query)).WillReturnError(errors.New("some error"))
 	err := tx.queryExec(context.Background(), db)
@@ -76,104 +82,370 @@ func TestNewPutQuery(t *testing.T) {
 }
 
 func TestNewMatchKeyQuery(t *testing.T) {
-	tx := newMatchKeyQuery(namespace, pattern, false, false, timestamp)
+	tx := newMatchKeyQuery(QueryParams{Namespace: namespace, Pattern: pattern, Active: false, Unique: false, Timestamp: timestamp})
 	assert.NotContains(t, tx.query, activeCheck)
 	assert.NotContains(t, tx.query, uniqueCheck)
 
-	tx = newMatchKeyQuery(namespace, pattern, false, true, timestamp)
+	tx = newMatchKeyQuery(QueryParams{Namespace: namespace, Pattern: pattern, Active: false, Unique: true, Timestamp: timestamp})
 	assert.NotContains(t, tx.query, activeCheck)
 	assert.Contains(t, tx.query, uniqueCheck)
 
-	tx = newMatchKeyQuery(namespace, pattern, true, false, timestamp)
+	tx = newMatchKeyQuery(QueryParams{Namespace: namespace, Pattern: pattern, Active: true, Unique: false, Timestamp: timestamp})
 	assert.Contains(t, tx.query, activeCheck)
 	assert.NotContains(t, tx.query, uniqueCheck)
 
-	tx = newMatchKeyQuery(namespace, pattern, true, true, timestamp)
+	tx = newMatchKeyQuery(QueryParams{Namespace: namespace, Pattern: pattern, Active: true, Unique: true, Timestamp: timestamp})
 	assert.Contains(t, tx.query, activeCheck)
 	assert.Contains(t, tx.query, uniqueCheck)
 }
 
 func TestNewCountKeyQuery(t *testing.T) {
-	tx := newCountKeyQuery(namespace, key, false, timestamp)
+	tx := newCountKeyQuery(QueryParams{Namespace: namespace, Key: key, Active: false, Timestamp: timestamp})
 	assert.NotContains(t, tx.query, activeCheck)
 
-	tx = newCountKeyQuery(namespace, key, true, timestamp)
+	tx = newCountKeyQuery(QueryParams{Namespace: namespace, Key: key, Active: true, Timestamp: timestamp})
 	assert.Contains(t, tx.query, activeCheck)
 }
 
 func TestNewGetKeysQuery(t *testing.T) {
-	tx := newGetKeysQuery(namespace, false, false, timestamp)
+	tx := newGetKeysQuery(QueryParams{Namespace: namespace, Active: false, Unique: false, Timestamp: timestamp})
 	assert.NotContains(t, tx.query, activeCheck)
 	assert.NotContains(t, tx.query, uniqueCheck)
 
-	tx = newGetKeysQuery(namespace, false, true, timestamp)
+	tx = newGetKeysQuery(QueryParams{Namespace: namespace, Active: false, Unique: true, Timestamp: timestamp})
 	assert.NotContains(t, tx.query, activeCheck)
 	assert.Contains(t, tx.query, uniqueCheck)
 
-	tx = newGetKeysQuery(namespace, true, false, timestamp)
+	tx = newGetKeysQuery(QueryParams{Namespace: namespace, Active: true, Unique: false, Timestamp: timestamp})
 	assert.Contains(t, tx.query, activeCheck)
 	assert.NotContains(t, tx.query, uniqueCheck)
 
-	tx = newGetKeysQuery(namespace, true, true, timestamp)
+	tx = newGetKeysQuery(QueryParams{Namespace: namespace, Active: true, Unique: true, Timestamp: timestamp})
 	assert.Contains(t, tx.query, activeCheck)
 	assert.Contains(t, tx.query, uniqueCheck)
 }
 
 func TestNewCountKeysQuery(t *testing.T) {
-	tx := newCountKeysQuery(namespace, false, false, timestamp)
+	tx := newCountKeysQuery(QueryParams{Namespace: namespace, Active: false, Unique: false, Timestamp: timestamp})
 	assert.NotContains(t, tx.query, activeCheck)
 	assert.NotContains(t, tx.query, uniqueCheck)
 
-	tx = newCountKeysQuery(namespace, false, true, timestamp)
+	tx = newCountKeysQuery(QueryParams{Namespace: namespace, Active: false, Unique: true, Timestamp: timestamp})
 	assert.NotContains(t, tx.query, activeCheck)
 	assert.Contains(t, tx.query, uniqueCheck)
 
-	tx = newCountKeysQuery(namespace, true, false, timestamp)
+	tx = newCountKeysQuery(QueryParams{Namespace: namespace, Active: true, Unique: false, Timestamp: timestamp})
 	assert.Contains(t, tx.query, activeCheck)
 	assert.NotContains(t, tx.query, uniqueCheck)
 
-	tx = newCountKeysQuery(namespace, true, true, timestamp)
+	tx = newCountKeysQuery(QueryParams{Namespace: namespace, Active: true, Unique: true, Timestamp: timestamp})
+	assert.Contains(t, tx.query, activeCheck)
+	assert.Contains(t, tx.query, uniqueCheck)
+}
+
+func TestNewMatchKeyAcrossQuery(t *testing.T) {
+	tx := newMatchKeyAcrossQuery(QueryParams{Pattern: pattern, Timestamp: timestamp})
+	assert.NotContains(t, tx.query, "namespace IN")
+	assert.NotContains(t, tx.query, uniqueCheck)
+	assert.Equal(t, []any{globToLike(pattern)}, tx.args)
+
+	tx = newMatchKeyAcrossQuery(QueryParams{Namespaces: []string{"a", "b"}, Active: true, Unique: true, Timestamp: timestamp})
+	assert.Contains(t, tx.query, "namespace IN (?, ?)")
+	assert.NotContains(t, tx.query, "LIKE")
+	assert.Contains(t, tx.query, uniqueCheck)
+	assert.Equal(t, []any{"a", "b", timestamp}, tx.args)
+}
+
+func TestExcludeQuery(t *testing.T) {
+	tx := newGetKeysQuery(QueryParams{Namespace: namespace, Exclude: []string{"tmp-*", "internal"}, Timestamp: timestamp})
+	assert.Contains(t, tx.query, `key NOT LIKE ? ESCAPE '\' AND key NOT LIKE ? ESCAPE '\'`)
+	assert.Equal(t, []any{namespace, globToLike("tmp-*"), globToLike("internal")}, tx.args)
+
+	tx = newMatchKeyQuery(QueryParams{Namespace: namespace, Pattern: pattern, Exclude: []string{"tmp-*"}, Active: true, Timestamp: timestamp})
+	assert.Contains(t, tx.query, "NOT LIKE")
+	assert.Equal(t, []any{namespace, globToLike(pattern), timestamp, globToLike("tmp-*")}, tx.args)
+}
+
+func TestNewMatchKeyAnyQuery(t *testing.T) {
+	tx := newMatchKeyAnyQuery(QueryParams{Namespace: namespace, Patterns: []string{pattern, "other"}, Timestamp: timestamp})
+	assert.NotContains(t, tx.query, activeCheck)
+	assert.NotContains(t, tx.query, uniqueCheck)
+	assert.Contains(t, tx.query, " OR ")
+	assert.Equal(t, []any{namespace, globToLike(pattern), globToLike("other")}, tx.args)
+
+	tx = newMatchKeyAnyQuery(QueryParams{Namespace: namespace, Patterns: []string{pattern}, Active: true, Unique: true, Timestamp: timestamp})
+	assert.Contains(t, tx.query, activeCheck)
+	assert.Contains(t, tx.query, uniqueCheck)
+	assert.Equal(t, []any{namespace, globToLike(pattern), timestamp}, tx.args)
+}
+
+func TestNewCountMatchQuery(t *testing.T) {
+	tx := newCountMatchQuery(QueryParams{Namespace: namespace, Pattern: pattern, Active: false, Unique: false, Timestamp: timestamp})
+	assert.NotContains(t, tx.query, activeCheck)
+	assert.NotContains(t, tx.query, uniqueCheck)
+	assert.Contains(t, tx.query, "LIKE")
+
+	tx = newCountMatchQuery(QueryParams{Namespace: namespace, Pattern: pattern, Active: true, Unique: true, Timestamp: timestamp})
+	assert.Contains(t, tx.query, activeCheck)
+	assert.Contains(t, tx.query, uniqueCheck)
+}
+
+func TestNewCountKeysByNamespaceQuery(t *testing.T) {
+	tx := newCountKeysByNamespaceQuery(QueryParams{Timestamp: timestamp})
+	assert.NotContains(t, tx.query, activeCheck)
+	assert.NotContains(t, tx.query, uniqueCheck)
+	assert.Contains(t, tx.query, "GROUP BY namespace")
+
+	tx = newCountKeysByNamespaceQuery(QueryParams{Active: true, Unique: true, Timestamp: timestamp})
 	assert.Contains(t, tx.query, activeCheck)
 	assert.Contains(t, tx.query, uniqueCheck)
 }
 
 func TestGetNamespacesQuery(t *testing.T) {
-	tx := newGetNamespacesQuery(false, timestamp)
+	tx := newGetNamespacesQuery(QueryParams{Active: false, Timestamp: timestamp})
 	assert.NotContains(t, tx.query, activeCheck)
 
-	tx = newGetNamespacesQuery(true, timestamp)
+	tx = newGetNamespacesQuery(QueryParams{Active: true, Timestamp: timestamp})
 	assert.Contains(t, tx.query, activeCheck)
 }
 
+func TestMatchNamespacesQuery(t *testing.T) {
+	tx := newMatchNamespacesQuery(QueryParams{Pattern: pattern, Timestamp: timestamp})
+	assert.NotContains(t, tx.query, activeCheck)
+
+	tx = newMatchNamespacesQuery(QueryParams{Pattern: "tenant-*", Active: true, Timestamp: timestamp})
+	assert.Contains(t, tx.query, activeCheck)
+	assert.Equal(t, []any{"tenant-%", timestamp}, tx.args)
+}
+
 func TestCountNamespacesQuery(t *testing.T) {
-	tx := newCountNamespacesQuery(false, timestamp)
+	tx := newCountNamespacesQuery(QueryParams{Active: false, Timestamp: timestamp})
 	assert.NotContains(t, tx.query, activeCheck)
 
-	tx = newCountNamespacesQuery(true, timestamp)
+	tx = newCountNamespacesQuery(QueryParams{Active: true, Timestamp: timestamp})
 	assert.Contains(t, tx.query, activeCheck)
 }
 
 func TestNewCountEntriesQuery(t *testing.T) {
-	tx := newCountEntriesQuery(false, false, timestamp)
+	tx := newCountEntriesQuery(QueryParams{Active: false, Unique: false, Timestamp: timestamp})
 	assert.NotContains(t, tx.query, activeCheck)
 	assert.NotContains(t, tx.query, uniqueCheck)
 
-	tx = newCountEntriesQuery(false, true, timestamp)
+	tx = newCountEntriesQuery(QueryParams{Active: false, Unique: true, Timestamp: timestamp})
 	assert.NotContains(t, tx.query, activeCheck)
 	assert.Contains(t, tx.query, uniqueCheck)
 
-	tx = newCountEntriesQuery(true, false, timestamp)
+	tx = newCountEntriesQuery(QueryParams{Active: true, Unique: false, Timestamp: timestamp})
 	assert.Contains(t, tx.query, activeCheck)
 	assert.NotContains(t, tx.query, uniqueCheck)
 
-	tx = newCountEntriesQuery(true, true, timestamp)
+	tx = newCountEntriesQuery(QueryParams{Active: true, Unique: true, Timestamp: timestamp})
 	assert.Contains(t, tx.query, activeCheck)
 	assert.Contains(t, tx.query, uniqueCheck)
 }
 
 func TestNewPruneEntriesQuery(t *testing.T) {
 	db, mock := newMock()
-	tx := newPruneEntriesQuery(timestamp)
+	tx := newPruneEntriesQuery(QueryParams{Timestamp: timestamp})
+
+	mock.ExpectExec(regexp.QuoteMeta(tx.query)).WillReturnError(errors.New("some error"))
+	err := tx.queryExec(context.Background(), db)
+	assert.Error(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta(tx.query)).WillReturnResult(sqlmock.NewResult(1, 1))
+	err = tx.queryExec(context.Background(), db)
+	assert.NoError(t, err)
+}
+
+func TestNewScanRangeQuery(t *testing.T) {
+	tx := newScanRangeQuery(QueryParams{Namespace: "namespace", Lower: "A", Upper: "B", Active: true, Timestamp: timestamp})
+	assert.Contains(t, tx.query, "ORDER BY key")
+	assert.Equal(t, []any{"namespace", "A", "B", timestamp}, tx.args)
+}
+
+func TestNewDeleteKeyQuery(t *testing.T) {
+	db, mock := newMock()
+	tx := newDeleteKeyQuery(QueryParams{Namespace: "namespace", Key: "key"})
+	assert.Equal(t, []any{"namespace", "key", "namespace", "key", "namespace", "key"}, tx.args)
+
+	mock.ExpectExec(regexp.QuoteMeta(tx.query)).WillReturnError(errors.New("some error"))
+	err := tx.queryExec(context.Background(), db)
+	assert.Error(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta(tx.query)).WillReturnResult(sqlmock.NewResult(1, 1))
+	err = tx.queryExec(context.Background(), db)
+	assert.NoError(t, err)
+}
+
+func TestNewExportNamespacesQuery(t *testing.T) {
+	tx := newExportNamespacesQuery(QueryParams{Pattern: "tenant*", Active: true, Timestamp: timestamp})
+	assert.Contains(t, tx.query, "GROUP BY namespace, keybase.key")
+	assert.Equal(t, []any{"tenant%", timestamp}, tx.args)
+}
+
+func TestNewRecordMigrationQuery(t *testing.T) {
+	db, mock := newMock()
+	tx := newRecordMigrationQuery(QueryParams{Version: 1, Timestamp: timestamp})
+	assert.Equal(t, []any{1, timestamp}, tx.args)
+
+	mock.ExpectExec(regexp.QuoteMeta(tx.query)).WillReturnError(errors.New("some error"))
+	err := tx.queryExec(context.Background(), db)
+	assert.Error(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta(tx.query)).WillReturnResult(sqlmock.NewResult(1, 1))
+	err = tx.queryExec(context.Background(), db)
+	assert.NoError(t, err)
+}
+
+func TestNewCopyNamespaceQuery(t *testing.T) {
+	tx := newCopyNamespaceQuery(QueryParams{Namespace: "src", Target: "dst", Timestamp: timestamp})
+	assert.Equal(t, []any{"dst", timestamp, "src", timestamp}, tx.args)
+
+	tx = newCopyNamespaceQuery(QueryParams{Namespace: "src", Target: "dst", Expiration: timestamp + 1, Timestamp: timestamp, Checksums: true})
+	assert.Contains(t, tx.query, "keybase_checksum(?, key, ?)")
+	assert.Equal(t, []any{"dst", timestamp + 1, "dst", timestamp + 1, timestamp, "src", timestamp}, tx.args)
+}
+
+func TestNewArchiveEntriesQuery(t *testing.T) {
+	tx := newArchiveEntriesQuery(QueryParams{Namespace: "namespace", Timestamp: timestamp}.scoped())
+	assert.True(t, strings.HasPrefix(tx.query, "INSERT INTO keybase_archive"))
+	assert.Equal(t, []any{timestamp, timestamp, "namespace"}, tx.args)
+
+	tx = newPurgeArchiveQuery(QueryParams{Timestamp: timestamp, Threshold: 1000})
+	assert.Equal(t, []any{timestamp - 1000}, tx.args)
+}
+
+func TestNewQueryAuditQuery(t *testing.T) {
+	tx := newQueryAuditQuery(AuditFilter{})
+	assert.NotContains(t, tx.query, "WHERE")
+	assert.Empty(t, tx.args)
+
+	tx = newQueryAuditQuery(AuditFilter{Actor: "actor", Op: OpPut, Since: time.UnixMilli(timestamp), Limit: 10})
+	assert.Contains(t, tx.query, "LIMIT")
+	assert.Equal(t, []any{"actor", "Put", timestamp}, tx.args)
+}
+
+func TestNewStatsQuery(t *testing.T) {
+	tx := newStatsQuery(QueryParams{Timestamp: timestamp})
+	assert.Contains(t, tx.query, "pragma_page_count()")
+	assert.Equal(t, []any{timestamp}, tx.args)
+
+	tx = newNamespaceEntriesQuery(QueryParams{Timestamp: timestamp})
+	assert.Contains(t, tx.query, "GROUP BY namespace")
+	assert.Equal(t, []any{timestamp}, tx.args)
+}
+
+func TestFederatedEntries(t *testing.T) {
+	params := QueryParams{Attached: []string{"east", "west"}}
+	assert.Equal(t, "(SELECT namespace, key, expiration FROM keybase UNION ALL SELECT namespace, key, expiration FROM \"east\".keybase UNION ALL SELECT namespace, key, expiration FROM \"west\".keybase) AS keybase", params.entries())
+	params.Checksums = true
+	assert.Contains(t, params.entries(), "WHERE NOT ("+corruptChecksum+") UNION ALL")
+	assert.Equal(t, "keybase", QueryParams{}.entries())
+
+	tx := newAttachQuery(QueryParams{Alias: "east", Path: "east.db"})
+	assert.Equal(t, `ATTACH DATABASE ? AS "east"`, tx.query)
+	assert.Equal(t, []any{"east.db"}, tx.args)
+	assert.Equal(t, `DETACH DATABASE "east"`, newDetachQuery(QueryParams{Alias: "east"}).query)
+}
+
+func TestNewExpireMatchQuery(t *testing.T) {
+	tx := newExpireMatchQuery(QueryParams{Namespace: namespace, Pattern: "key*", Expiration: timestamp})
+	assert.Equal(t, `UPDATE keybase SET expiration = ? WHERE namespace = ? AND key LIKE ? ESCAPE '\'`, tx.query)
+	assert.Equal(t, []any{timestamp, namespace, "key%"}, tx.args)
+
+	tx = newExpireKeyQuery(QueryParams{Namespace: namespace, Key: key, Expiration: timestamp, Checksums: true})
+	assert.Contains(t, tx.query, "keybase_checksum(namespace, key, ?)")
+	assert.Contains(t, tx.query, "NOT ("+corruptChecksum+")")
+	assert.Equal(t, []any{timestamp, timestamp, namespace, key}, tx.args)
+}
+
+func TestNewGetEntriesQuery(t *testing.T) {
+	tx := newGetEntriesQuery(QueryParams{Namespace: namespace, Timestamp: timestamp})
+	assert.Equal(t, "SELECT key, expiration FROM keybase WHERE namespace = ? ORDER BY expiration", tx.query)
+	tx = newGetEntriesQuery(QueryParams{Namespace: namespace, Pattern: "key*", Active: true, Limit: 2, Timestamp: timestamp})
+	assert.Contains(t, tx.query, "key LIKE ?")
+	assert.Contains(t, tx.query, activeCheck)
+	assert.Contains(t, tx.query, "LIMIT 2")
+	assert.Equal(t, []any{namespace, "key%", timestamp}, tx.args)
+}
+
+func TestNewGetKeysInsertedQuery(t *testing.T) {
+	tx := newGetKeysInsertedQuery(QueryParams{Namespace: namespace, Since: timestamp, Until: timestamp + 1000})
+	assert.Equal(t, "SELECT key FROM keybase WHERE namespace = ? AND inserted_at >= ? AND inserted_at < ? GROUP BY key ORDER BY MIN(inserted_at), MIN(rowid)", tx.query)
+	assert.Equal(t, []any{namespace, timestamp, timestamp + 1000}, tx.args)
+	tx = newGetKeysInsertedQuery(QueryParams{Namespace: namespace, Checksums: true})
+	assert.Contains(t, tx.query, "NOT ("+corruptChecksum+")")
+}
+
+func TestNewMatchKeyByTagQuery(t *testing.T) {
+	tx := newMatchKeyByTagQuery(QueryParams{Namespace: namespace, Tag: "source=api", Timestamp: timestamp})
+	assert.Contains(t, tx.query, "SELECT DISTINCT tags.key FROM keybase_tags AS tags")
+	assert.Contains(t, tx.query, "FROM keybase WHERE")
+	assert.Equal(t, []any{namespace, "source=api", timestamp}, tx.args)
+	tx = newMatchKeyByTagQuery(QueryParams{Namespace: namespace, Tag: "source=api", Timestamp: timestamp, Overflow: true, Checksums: true})
+	assert.Contains(t, tx.query, "ref = tags.key")
+	assert.Contains(t, tx.query, corruptChecksum)
+
+	tx = newPruneTagsQuery(QueryParams{Namespace: namespace})
+	assert.NotContains(t, tx.query, "namespace = ?")
+	assert.Empty(t, tx.args)
+	tx = newPruneTagsQuery(QueryParams{Namespace: namespace}.scoped())
+	assert.Contains(t, tx.query, "AND namespace = ?")
+	assert.Equal(t, []any{namespace}, tx.args)
+}
+
+func TestNewRecordHistoryQuery(t *testing.T) {
+	tx := newRecordHistoryQuery(QueryParams{Namespace: namespace, Key: key, Timestamp: timestamp, Expiration: timestamp, Limit: 3})
+	assert.Contains(t, tx.query, "COALESCE(MAX(version), 0) + 1")
+	assert.Equal(t, []any{namespace, key, timestamp, timestamp, 3}, tx.args)
+
+	tx = newPruneHistoryQuery(QueryParams{Namespace: namespace}.scoped())
+	assert.Contains(t, tx.query, "AND namespace = ?")
+	assert.Equal(t, []any{namespace}, tx.args)
+}
+
+func TestQueryParamsSort(t *testing.T) {
+	tx := newGetKeysQuery(QueryParams{Namespace: namespace, Order: KeyDescending})
+	assert.Contains(t, tx.query, "ORDER BY key DESC")
+	tx = newGetKeysQuery(QueryParams{Namespace: namespace, Unique: true, Order: ExpirationOrder})
+	assert.Contains(t, tx.query, "GROUP BY key ORDER BY MAX(expiration), key")
+	tx = newMatchKeyQuery(QueryParams{Namespace: namespace, Pattern: "*", Order: InsertionOrder, Checksums: true, Cold: true})
+	assert.Contains(t, tx.query, "SELECT rowid, namespace, key, expiration FROM keybase WHERE")
+	assert.Contains(t, tx.query, "UNION ALL SELECT rowid, namespace, key, expiration FROM keybase_cold")
+	assert.Contains(t, tx.query, "ORDER BY rowid")
+	tx = newGetKeysQuery(QueryParams{Namespace: namespace, Checksums: true})
+	assert.NotContains(t, tx.query, "rowid")
+	assert.NotContains(t, tx.query, "ORDER BY")
+}
+
+func TestPrunedBatch(t *testing.T) {
+	tx := newPruneEntriesQuery(QueryParams{Limit: 3, Timestamp: timestamp})
+	assert.Contains(t, tx.query, "rowid IN (SELECT rowid FROM keybase WHERE expiration <= ? ORDER BY rowid LIMIT 3)")
+	assert.Equal(t, []any{timestamp}, tx.args)
+
+	tx = newArchiveEntriesQuery(QueryParams{Namespace: "namespace", Limit: 3, Timestamp: timestamp}.scoped())
+	assert.Contains(t, tx.query, "namespace = ? ORDER BY rowid LIMIT 3")
+	assert.Equal(t, []any{timestamp, timestamp, "namespace"}, tx.args)
+
+	// the other tables are never limited
+	tx = newPruneCountersQuery(QueryParams{Limit: 3, Timestamp: timestamp})
+	assert.NotContains(t, tx.query, "LIMIT")
+}
+
+func TestNewPruneNamespaceQuery(t *testing.T) {
+	tx := newPruneEntriesQuery(QueryParams{Namespace: "namespace", Timestamp: timestamp}.scoped())
+	assert.Contains(t, tx.query, "namespace = ?")
+	assert.Equal(t, []any{timestamp, "namespace"}, tx.args)
+
+	tx = newPruneCountersQuery(QueryParams{Namespace: "namespace", Timestamp: timestamp})
+	assert.NotContains(t, tx.query, "namespace = ?")
+	assert.Equal(t, []any{timestamp}, tx.args)
+}
+
+func TestNewClearNamespaceQuery(t *testing.T) {
+	db, mock := newMock()
+	tx := newClearNamespaceQuery(QueryParams{Namespace: "namespace"})
+	assert.Len(t, tx.args, 9)
 
 	mock.ExpectExec(regexp.QuoteMeta(tx.query)).WillReturnError(errors.New("some error"))
 	err := tx.queryExec(context.Background(), db)
@@ -230,3 +502,276 @@ func TestQueryValues(t *testing.T) {
 	_, err = tx.queryValues(context.Background(), db)
 	assert.NoError(t, err)
 }
+
+func TestBuildQuery(t *testing.T) {
+	query, args := BuildQuery(Op("unknown"), QueryParams{})
+	assert.Empty(t, query)
+	assert.Nil(t, args)
+
+	query, args = BuildQuery(OpCountKey, QueryParams{Namespace: namespace, Key: key, Active: true, Timestamp: timestamp})
+	tx := newCountKeyQuery(QueryParams{Namespace: namespace, Key: key, Active: true, Timestamp: timestamp})
+	assert.Equal(t, tx.query, query)
+	assert.Equal(t, tx.args, args)
+}
+
+// TestBuildQueryGolden compares generated queries against the golden files
+// in testdata/<dialect>, run with -update to regenerate them
+func TestBuildQueryGolden(t *testing.T) {
+	paramSets := []QueryParams{
+		{Namespace: namespace, Key: key, Pattern: "test*?", Patterns: []string{"test*?", "other"}, Deadlines: []int64{1700000001000, 1700000060000}, Expiration: 1700000000000, Timestamp: 1700000000000, Buckets: 4, Width: 1000},
+		{Namespace: namespace, Key: key, Pattern: "test*?", Patterns: []string{"test*?", "other"}, Exclude: []string{"tmp-*"}, Expiration: 1700000000000, Timestamp: 1700000000000, Buckets: 4, Width: 1000, Active: true, Unique: true},
+		{Namespace: namespace, Key: key, Pattern: "test*?", Patterns: []string{"test*?", "other"}, Deadlines: []int64{1700000001000, 1700000060000}, Expiration: 1700000000000, Timestamp: 1700000000000, Buckets: 4, Width: 1000, Threshold: 60000, Cold: true},
+	}
+	for op := range queryBuilders {
+		var actual strings.Builder
+		for _, params := range paramSets {
+			query, args := BuildQuery(op, params)
+			fmt.Fprintf(&actual, "-- active=%t unique=%t cold=%t\n%s\n-- args: %v\n", params.Active, params.Unique, params.Cold, query, args)
+		}
+		golden := filepath.Join("testdata", "sqlite", string(op)+".golden")
+		if *update {
+			assert.NoError(t, os.MkdirAll(filepath.Dir(golden), 0755))
+			assert.NoError(t, os.WriteFile(golden, []byte(actual.String()), 0644))
+		}
+		expected, err := os.ReadFile(golden)
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), actual.String(), string(op))
+	}
+}
+
+func TestNewIncrementQuery(t *testing.T) {
+	db, mock := newMock()
+	tx := newIncrementQuery(QueryParams{Namespace: namespace, Key: key, Delta: 1, Expiration: timestamp, Timestamp: timestamp})
+	assert.Contains(t, tx.query, "ON CONFLICT")
+
+	mock.ExpectQuery(regexp.QuoteMeta(tx.query)).WillReturnError(errors.New("some error"))
+	_, err := tx.queryNullInt(context.Background(), db)
+	assert.Error(t, err)
+
+	mock.ExpectQuery(regexp.QuoteMeta(tx.query)).WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(1))
+	value, err := tx.queryNullInt(context.Background(), db)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), value.Int64)
+}
+
+func TestNewGetCounterQuery(t *testing.T) {
+	tx := newGetCounterQuery(QueryParams{Namespace: namespace, Key: key, Timestamp: timestamp})
+	assert.Contains(t, tx.query, activeCheck)
+}
+
+func TestNewPruneCountersQuery(t *testing.T) {
+	tx := newPruneCountersQuery(QueryParams{Timestamp: timestamp})
+	assert.Contains(t, tx.query, activeCheck)
+}
+
+func TestQueryNullInt(t *testing.T) {
+	db, mock := newMock()
+	tx := &dbtx{query: ""}
+
+	mock.ExpectQuery(tx.query).WillReturnRows(sqlmock.NewRows([]string{"col0"}).AddRow("col"))
+	_, err := tx.queryNullInt(context.Background(), db)
+	assert.Error(t, err)
+
+	mock.ExpectQuery(tx.query).WillReturnRows(sqlmock.NewRows([]string{"col0"}))
+	value, err := tx.queryNullInt(context.Background(), db)
+	assert.NoError(t, err)
+	assert.False(t, value.Valid)
+}
+
+func TestNewPutIfAbsentQuery(t *testing.T) {
+	db, mock := newMock()
+	tx := newPutIfAbsentQuery(QueryParams{Namespace: namespace, Key: key, Expiration: timestamp, Timestamp: timestamp})
+
+	mock.ExpectExec(regexp.QuoteMeta(tx.query)).WillReturnError(errors.New("some error"))
+	_, err := tx.queryRowsAffected(context.Background(), db)
+	assert.Error(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta(tx.query)).WillReturnResult(sqlmock.NewErrorResult(errors.New("some error")))
+	_, err = tx.queryRowsAffected(context.Background(), db)
+	assert.Error(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta(tx.query)).WillReturnResult(sqlmock.NewResult(1, 1))
+	rows, err := tx.queryRowsAffected(context.Background(), db)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), rows)
+}
+
+func TestNewLeaseQueries(t *testing.T) {
+	params := QueryParams{Namespace: namespace, Key: key, Owner: "owner", Expiration: timestamp, Timestamp: timestamp}
+	assert.Contains(t, newAcquireLeaseQuery(params).query, "ON CONFLICT")
+	assert.Contains(t, newRenewLeaseQuery(params).query, activeCheck)
+	assert.Contains(t, newReleaseLeaseQuery(params).query, "owner")
+	assert.Contains(t, newPruneLeasesQuery(params).query, activeCheck)
+}
+
+func TestNewFieldQueries(t *testing.T) {
+	params := QueryParams{Namespace: namespace, Key: key, Field: "field", Value: "value", Expiration: timestamp, Timestamp: timestamp}
+	assert.Contains(t, newPutFieldQuery(params).query, "ON CONFLICT")
+	assert.Contains(t, newTouchFieldsQuery(params).query, activeCheck)
+	assert.Contains(t, newGetFieldsQuery(params).query, activeCheck)
+	assert.Contains(t, newPruneFieldsQuery(params).query, activeCheck)
+}
+
+func TestQueryRows(t *testing.T) {
+	db, mock := newMock()
+	tx := &dbtx{query: ""}
+	scan := func(rows *sql.Rows) error {
+		value := ""
+		return rows.Scan(&value)
+	}
+
+	mock.ExpectQuery(tx.query).WillReturnError(errors.New("some error"))
+	assert.Error(t, tx.queryRows(context.Background(), db, scan))
+
+	mock.ExpectQuery(tx.query).WillReturnRows(sqlmock.NewRows([]string{"col0", "col1"}).AddRow("col0", "col1"))
+	assert.Error(t, tx.queryRows(context.Background(), db, scan))
+
+	mock.ExpectQuery(tx.query).WillReturnRows(sqlmock.NewRows([]string{"col0"}).AddRow("value"))
+	assert.NoError(t, tx.queryRows(context.Background(), db, scan))
+}
+
+func TestNewGetExpirationQuery(t *testing.T) {
+	tx := newGetExpirationQuery(QueryParams{Namespace: namespace, Key: key, Timestamp: timestamp})
+	assert.Contains(t, tx.query, "MAX(expiration)")
+}
+
+func TestChecksumQueries(t *testing.T) {
+	assert.Equal(t, "keybase", QueryParams{}.entries())
+	assert.Contains(t, QueryParams{Checksums: true}.entries(), corruptChecksum)
+	assert.Contains(t, QueryParams{Checksums: true, Cold: true}.table(), corruptChecksum)
+	assert.Contains(t, QueryParams{Checksums: true, Cold: true}.table(), "keybase_cold")
+
+	tx := newPutQuery(QueryParams{Namespace: namespace, Key: key, Expiration: timestamp, Timestamp: timestamp, Checksums: true})
+	assert.Contains(t, tx.query, "checksum")
+	assert.Equal(t, []any{namespace, key, timestamp, timestamp, checksum(namespace, key, timestamp)}, tx.args)
+	tx = newPutIfAbsentQuery(QueryParams{Namespace: namespace, Key: key, Expiration: timestamp, Timestamp: timestamp, Checksums: true})
+	assert.Contains(t, tx.query, "checksum")
+	tx = newQuarantineEntriesQuery(QueryParams{Timestamp: timestamp})
+	assert.Equal(t, []any{timestamp}, tx.args)
+}
+
+func TestNewEvictEntriesQuery(t *testing.T) {
+	tx := newEvictEntriesQuery(QueryParams{Eviction: EvictOldest, Limit: 2, Timestamp: timestamp})
+	assert.Contains(t, tx.query, "ORDER BY expiration, rowid")
+	assert.Equal(t, []any{timestamp, 2}, tx.args)
+	tx = newEvictEntriesQuery(QueryParams{Eviction: EvictRandom, Limit: 2, Timestamp: timestamp})
+	assert.Contains(t, tx.query, "ORDER BY RANDOM()")
+}
+
+func TestOverflowQueries(t *testing.T) {
+	assert.Equal(t, "key", QueryParams{}.keyColumn())
+	assert.Contains(t, QueryParams{Overflow: true}.keyColumn(), "keybase_overflow")
+	tx := newMatchKeyQuery(QueryParams{Namespace: namespace, Pattern: "*", Overflow: true})
+	assert.Contains(t, tx.query, "SELECT COALESCE(")
+	assert.Contains(t, tx.query, "keybase.key) LIKE ?")
+	tx = newExpireEntriesQuery(QueryParams{Timestamp: timestamp, Overflow: true})
+	assert.Contains(t, tx.query, "RETURNING namespace, COALESCE(")
+	tx = newPutOverflowQuery(QueryParams{Key: "ref", Value: "value"})
+	assert.Equal(t, []any{"ref", "value"}, tx.args)
+	assert.Contains(t, newPruneOverflowQuery(QueryParams{}).query, "keybase_cold")
+}
+
+func TestNewNextExpirationQuery(t *testing.T) {
+	tx := newNextExpirationQuery(QueryParams{Namespace: namespace, Key: key, Timestamp: timestamp})
+	assert.Contains(t, tx.query, "MIN(expiration)")
+	assert.Equal(t, []any{namespace, key, timestamp}, tx.args)
+	tx = newNextExpirationQuery(QueryParams{Namespace: namespace, Timestamp: timestamp})
+	assert.Equal(t, []any{namespace, timestamp}, tx.args)
+}
+
+func TestNewExpirationHistogramQuery(t *testing.T) {
+	tx := newLastExpirationQuery(QueryParams{Namespace: namespace, Timestamp: timestamp})
+	assert.Contains(t, tx.query, "MAX(expiration)")
+	tx = newExpirationHistogramQuery(QueryParams{Namespace: namespace, Timestamp: timestamp, Buckets: 4, Width: 1000})
+	assert.Contains(t, tx.query, "GROUP BY bucket")
+	assert.Equal(t, []any{timestamp, int64(1000), 3, namespace, timestamp}, tx.args)
+}
+
+func TestNewKeyFrequencyQuery(t *testing.T) {
+	tx := newKeyFrequencyQuery(QueryParams{Namespace: namespace, Key: key, Timestamp: timestamp, Since: timestamp - 1000, Buckets: 4, Width: 250})
+	assert.Contains(t, tx.query, "MIN((inserted_at - ?) / ?, ?) AS bucket")
+	assert.Contains(t, tx.query, activeCheck)
+	assert.Equal(t, []any{timestamp - 1000, int64(250), 3, namespace, key, timestamp, timestamp - 1000}, tx.args)
+}
+
+func TestNewTopKeysQuery(t *testing.T) {
+	tx := newTopKeysQuery(QueryParams{Namespace: namespace, Limit: 3, Timestamp: timestamp})
+	assert.Equal(t, "SELECT key, COUNT(*) AS count FROM keybase WHERE namespace = ? GROUP BY key ORDER BY count DESC, key LIMIT 3", tx.query)
+	tx = newTopKeysQuery(QueryParams{Namespace: namespace, Limit: 3, Active: true, Timestamp: timestamp})
+	assert.Contains(t, tx.query, activeCheck)
+	assert.Equal(t, []any{namespace, timestamp}, tx.args)
+}
+
+func TestNewClaimQueries(t *testing.T) {
+	params := QueryParams{Namespace: namespace, Key: key, Row: 7, Inserted: timestamp - 1, Expiration: timestamp + 1, Timestamp: timestamp}
+	assert.True(t, strings.HasSuffix(newClaimQuery(params).query, "RETURNING rowid"))
+	tx := newExtendClaimQuery(params)
+	assert.Equal(t, "UPDATE keybase SET expiration = ? WHERE namespace = ? AND (key = ? AND rowid = ? AND inserted_at = ? AND expiration > ?)", tx.query)
+	assert.Equal(t, []any{timestamp + 1, namespace, key, int64(7), timestamp - 1, timestamp}, tx.args)
+	tx = newReleaseClaimQuery(params)
+	assert.Equal(t, "DELETE FROM keybase WHERE namespace = ? AND key = ? AND rowid = ? AND inserted_at = ?", tx.query)
+	assert.Equal(t, []any{namespace, key, int64(7), timestamp - 1}, tx.args)
+}
+
+func TestTombstoneQueries(t *testing.T) {
+	tx := newDeleteKeyQuery(QueryParams{Namespace: namespace, Key: key, Timestamp: timestamp, Tombstones: true})
+	assert.True(t, strings.HasPrefix(tx.query, "INSERT INTO keybase_tombstones"))
+	assert.Equal(t, []any{namespace, key, timestamp}, tx.args)
+	tx = newPurgeTombstonesQuery(QueryParams{Timestamp: timestamp, Threshold: 1000})
+	assert.Equal(t, "DELETE FROM keybase_tombstones WHERE deleted_at <= ?", tx.query)
+	assert.Equal(t, []any{timestamp - 1000}, tx.args)
+	assert.Contains(t, newPruneOverflowQuery(QueryParams{}).query, "keybase_tombstones")
+}
+
+func TestNewDescribeNamespacesQuery(t *testing.T) {
+	tx := newDescribeNamespacesQuery(QueryParams{Timestamp: timestamp})
+	assert.Contains(t, tx.query, "COUNT(DISTINCT key)")
+	assert.True(t, strings.HasSuffix(tx.query, "FROM keybase GROUP BY namespace ORDER BY namespace"))
+	assert.Equal(t, []any{timestamp}, tx.args)
+	assert.Contains(t, newDescribeNamespacesQuery(QueryParams{Checksums: true}).query, corruptChecksum)
+}
+
+func TestNewScanQuery(t *testing.T) {
+	tx := newScanQuery(QueryParams{Namespace: namespace, Lower: key, Limit: 11, Timestamp: timestamp})
+	assert.Contains(t, tx.query, "key >= ?")
+	assert.Contains(t, tx.query, activeCheck)
+	assert.Contains(t, tx.query, "ORDER BY key LIMIT 11")
+	assert.Equal(t, []any{namespace, key, timestamp}, tx.args)
+}
+
+func TestNewExpirationDecayQuery(t *testing.T) {
+	tx := newExpirationDecayQuery(QueryParams{Timestamp: timestamp, Deadlines: []int64{timestamp + 1000, timestamp + 60000}})
+	assert.Contains(t, tx.query, activeCheck)
+	assert.NotContains(t, tx.query, "namespace")
+	assert.Equal(t, 2, strings.Count(tx.query, "COUNT(CASE WHEN expiration <= ?"))
+	assert.Equal(t, []any{timestamp + 1000, timestamp + 60000, timestamp}, tx.args)
+}
+
+func TestNewSampleKeysQuery(t *testing.T) {
+	tx := newSampleKeysQuery(QueryParams{Namespace: namespace, Limit: 5, Timestamp: timestamp})
+	assert.NotContains(t, tx.query, activeCheck)
+	assert.Contains(t, tx.query, uniqueCheck)
+	assert.Contains(t, tx.query, "ORDER BY RANDOM() LIMIT 5")
+
+	tx = newSampleKeysQuery(QueryParams{Namespace: namespace, Limit: 5, Active: true, Timestamp: timestamp})
+	assert.Contains(t, tx.query, activeCheck)
+	assert.Equal(t, []any{namespace, timestamp}, tx.args)
+}
+
+func TestNewCompactDuplicatesQuery(t *testing.T) {
+	tx := newCompactDuplicatesQuery(QueryParams{Policy: KeepLatest})
+	assert.Contains(t, tx.query, "other.expiration > keybase.expiration")
+	tx = newCompactDuplicatesQuery(QueryParams{Policy: KeepEarliest})
+	assert.Contains(t, tx.query, "other.expiration < keybase.expiration")
+}
+
+func TestColdTierQueries(t *testing.T) {
+	assert.Equal(t, "keybase", QueryParams{}.table())
+	assert.Equal(t, "keybase", QueryParams{Active: true, Cold: true}.table())
+	assert.Contains(t, QueryParams{Cold: true}.table(), "keybase_cold")
+
+	params := QueryParams{Timestamp: timestamp, Threshold: 1000}
+	assert.Equal(t, []any{timestamp - 1000}, newCopyToColdTierQuery(params).args)
+	assert.Equal(t, []any{timestamp - 1000}, newMoveToColdTierQuery(params).args)
+	assert.Contains(t, newPruneColdTierQuery(params).query, "keybase_cold")
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+)
+
+// EvictionPolicy selects how a keybase at its entry quota makes room
+type EvictionPolicy int
+
+const (
+	// RejectWrites fails writes with ErrQuotaExceeded
+	RejectWrites EvictionPolicy = iota
+	// EvictOldest removes the entries closest to expiring
+	EvictOldest
+	// EvictRandom removes randomly chosen entries
+	EvictRandom
+)
+
+type quotaOption struct {
+	entries int
+	policy  EvictionPolicy
+}
+
+// Limit the number of active entries, applying the policy to writes that
+// would exceed it
+func WithMaxEntries(n int, policy EvictionPolicy) Option {
+	return Option{
+		key: "maxentries",
+		value: quotaOption{
+			entries: n,
+			policy:  policy,
+		},
+	}
+}
+
+// enforceQuota runs after an insert, within its transaction, and either
+// rejects the insert or evicts entries until the quota is met again
+func (k *Keybase) enforceQuota(ctx context.Context, db querier) error {
+	if k.maxEntries == 0 {
+		return nil
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	count, err := newCountEntriesQuery(k.params(QueryParams{Active: true, Timestamp: timestamp})).queryCount(withOperation(ctx, OpCountEntries), db)
+	if err != nil || count <= k.maxEntries {
+		return err
+	}
+	if k.eviction == RejectWrites {
+		return ErrQuotaExceeded
+	}
+	return newEvictEntriesQuery(k.params(QueryParams{
+		Eviction:  k.eviction,
+		Limit:     count - k.maxEntries,
+		Timestamp: timestamp,
+	})).queryExec(withOperation(ctx, OpEvictEntries), db)
+}
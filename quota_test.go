@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxEntries(t *testing.T) {
+	put := func(keybase *Keybase, keys ...string) {
+		for _, key := range keys {
+			assert.NoError(t, keybase.Put(context.Background(), "namespace", key))
+			time.Sleep(time.Millisecond * 2)
+		}
+	}
+
+	keybase, err := Open(context.Background(), WithMaxEntries(2, RejectWrites))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	put(keybase, "key0", "key1")
+	assert.ErrorIs(t, keybase.Put(context.Background(), "namespace", "key2"), ErrQuotaExceeded)
+	_, err = keybase.PutIfAbsent(context.Background(), "namespace", "key2")
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	inserted, err := keybase.PutIfAbsent(context.Background(), "namespace", "key0")
+	assert.NoError(t, err)
+	assert.False(t, inserted)
+	count, err := keybase.CountEntries(context.Background(), true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	keybase, err = Open(context.Background(), WithMaxEntries(2, EvictOldest))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	put(keybase, "key0", "key1", "key2", "key3")
+	keys, err := keybase.GetKeys(context.Background(), "namespace", true, false)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"key2", "key3"}, keys)
+
+	keybase, err = Open(context.Background(), WithMaxEntries(3, EvictRandom), WithOverflow(4))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	for key := 0; key < 10; key++ {
+		put(keybase, fmt.Sprintf("key%d", key))
+	}
+	count, err = keybase.CountEntries(context.Background(), true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
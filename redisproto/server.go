@@ -0,0 +1,437 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package redisproto serves a subset of the Redis RESP protocol backed by a
+// keybase, so Redis clients can use it for presence and TTL tracking. Values
+// given to SET are discarded, since a keybase only records that a key is
+// present.
+package redisproto
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maxtek6/keybase-go"
+)
+
+// ErrServerClosed returned by Serve after the server is closed
+var ErrServerClosed = errors.New("redisproto: server closed")
+
+// scanCount default number of keys returned by one SCAN call
+const scanCount = 10
+
+// Server serves RESP connections from the keys of a single namespace
+type Server struct {
+	keybase   *keybase.Keybase
+	namespace string
+	mu        *sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	group     sync.WaitGroup
+	closed    bool
+}
+
+type command func(ctx context.Context, args []string) (any, error)
+
+// simpleString reply written as a RESP simple string rather than a bulk string
+type simpleString string
+
+// NewServer creates a server for the keys of a namespace
+func NewServer(kb *keybase.Keybase, namespace string) *Server {
+	return &Server{
+		keybase:   kb,
+		namespace: namespace,
+		mu:        new(sync.Mutex),
+		listeners: map[net.Listener]struct{}{},
+		conns:     map[net.Conn]struct{}{},
+	}
+}
+
+// ListenAndServe listens on the TCP address and serves connections until the
+// server is closed
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("redisproto.ListenAndServe: %w", err)
+	}
+	return s.Serve(listener)
+}
+
+// Serve accepts connections from the listener until the server is closed,
+// always returning a non-nil error
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = listener.Close()
+		return ErrServerClosed
+	}
+	s.listeners[listener] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, listener)
+		s.mu.Unlock()
+	}()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return fmt.Errorf("redisproto.Serve: %w", err)
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.group.Add(1)
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops the listeners, closes open connections and waits for their
+// commands to finish. The keybase is left open.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for listener := range s.listeners {
+		_ = listener.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.group.Wait()
+	return nil
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.group.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+	reader, writer := bufio.NewReader(conn), bufio.NewWriter(conn)
+	ctx := context.Background()
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				_ = writeReply(writer, fmt.Errorf("ERR protocol error: %w", err))
+				_ = writer.Flush()
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		name := strings.ToUpper(args[0])
+		if name == "QUIT" {
+			_ = writeReply(writer, simpleString("OK"))
+			_ = writer.Flush()
+			return
+		}
+		reply, err := s.execute(ctx, name, args[1:])
+		if err != nil {
+			reply = err
+		}
+		if writeReply(writer, reply) != nil || writer.Flush() != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) execute(ctx context.Context, name string, args []string) (any, error) {
+	commands := map[string]command{
+		"PING":    s.ping,
+		"SET":     s.set,
+		"EXISTS":  s.exists,
+		"KEYS":    s.keys,
+		"SCAN":    s.scan,
+		"DEL":     s.del,
+		"TTL":     s.ttl,
+		"COMMAND": func(context.Context, []string) (any, error) { return []any{}, nil },
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		return nil, fmt.Errorf("ERR unknown command '%s'", strings.ToLower(name))
+	}
+	reply, err := cmd(ctx, args)
+	if err != nil && !strings.HasPrefix(err.Error(), "ERR ") {
+		return nil, fmt.Errorf("ERR %w", err)
+	}
+	return reply, err
+}
+
+func arity(name string) error {
+	return fmt.Errorf("ERR wrong number of arguments for '%s' command", name)
+}
+
+func (s *Server) ping(_ context.Context, args []string) (any, error) {
+	switch len(args) {
+	case 0:
+		return simpleString("PONG"), nil
+	case 1:
+		return args[0], nil
+	}
+	return nil, arity("ping")
+}
+
+// set handles SET key value [EX seconds | PX milliseconds], replacing any
+// entries of the key so its TTL is the one given
+func (s *Server) set(ctx context.Context, args []string) (any, error) {
+	if len(args) != 2 && len(args) != 4 {
+		return nil, arity("set")
+	}
+	key, until := args[0], time.Time{}
+	if len(args) == 4 {
+		amount, err := strconv.ParseInt(args[3], 10, 64)
+		if err != nil || amount <= 0 {
+			return nil, errors.New("ERR invalid expire time in 'set' command")
+		}
+		switch strings.ToUpper(args[2]) {
+		case "EX":
+			until = time.Now().Add(time.Duration(amount) * time.Second)
+		case "PX":
+			until = time.Now().Add(time.Duration(amount) * time.Millisecond)
+		default:
+			return nil, errors.New("ERR syntax error")
+		}
+	}
+	err := s.keybase.Tx(ctx, func(tx *keybase.KeybaseTx) error {
+		err := tx.Delete(ctx, s.namespace, key)
+		if err != nil {
+			return err
+		}
+		if until.IsZero() {
+			return tx.Put(ctx, s.namespace, key)
+		}
+		return tx.PutUntil(ctx, s.namespace, key, until)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return simpleString("OK"), nil
+}
+
+func (s *Server) exists(ctx context.Context, args []string) (any, error) {
+	if len(args) == 0 {
+		return nil, arity("exists")
+	}
+	found := int64(0)
+	for _, key := range args {
+		count, err := s.keybase.CountKey(ctx, s.namespace, key, true)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			found++
+		}
+	}
+	return found, nil
+}
+
+func (s *Server) keys(ctx context.Context, args []string) (any, error) {
+	if len(args) != 1 {
+		return nil, arity("keys")
+	}
+	keys, err := s.keybase.MatchKey(ctx, s.namespace, args[0], true, true)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+// scan handles SCAN cursor [MATCH pattern] [COUNT count], where the cursor is
+// the offset into the sorted matching keys
+func (s *Server) scan(ctx context.Context, args []string) (any, error) {
+	if len(args) == 0 || len(args)%2 == 0 {
+		return nil, arity("scan")
+	}
+	cursor, err := strconv.Atoi(args[0])
+	if err != nil || cursor < 0 {
+		return nil, errors.New("ERR invalid cursor")
+	}
+	pattern, count := "*", scanCount
+	for index := 1; index < len(args); index += 2 {
+		switch strings.ToUpper(args[index]) {
+		case "MATCH":
+			pattern = args[index+1]
+		case "COUNT":
+			count, err = strconv.Atoi(args[index+1])
+			if err != nil || count <= 0 {
+				return nil, errors.New("ERR value is not an integer or out of range")
+			}
+		default:
+			return nil, errors.New("ERR syntax error")
+		}
+	}
+	keys, err := s.keybase.MatchKey(ctx, s.namespace, pattern, true, true)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(keys)
+	start := min(cursor, len(keys))
+	end := min(start+count, len(keys))
+	next := end
+	if end == len(keys) {
+		next = 0
+	}
+	return []any{strconv.Itoa(next), keys[start:end]}, nil
+}
+
+func (s *Server) del(ctx context.Context, args []string) (any, error) {
+	if len(args) == 0 {
+		return nil, arity("del")
+	}
+	deleted := int64(0)
+	err := s.keybase.Tx(ctx, func(tx *keybase.KeybaseTx) error {
+		for _, key := range args {
+			// Match with the key as the pattern would also match wildcards,
+			// so existence is checked exactly before deleting
+			keys, err := tx.Match(ctx, s.namespace, key, true, true)
+			if err != nil {
+				return err
+			}
+			if slices.Contains(keys, key) {
+				deleted++
+			}
+			err = tx.Delete(ctx, s.namespace, key)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// ttl handles TTL key, replying -2 if the key is not present
+func (s *Server) ttl(ctx context.Context, args []string) (any, error) {
+	if len(args) != 1 {
+		return nil, arity("ttl")
+	}
+	remaining, err := s.keybase.GetTTL(ctx, s.namespace, args[0])
+	if errors.Is(err, keybase.ErrNotFound) {
+		return int64(-2), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return int64((remaining + time.Second/2) / time.Second), nil
+}
+
+// readCommand reads an array of bulk strings, or an inline command as typed
+// into a telnet session
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := readLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+	count, err := strconv.Atoi(line[1:])
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("invalid multibulk length %q", line[1:])
+	}
+	args := make([]string, count)
+	for index := range args {
+		line, err = readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("expected '$', got %q", line)
+		}
+		length, err := strconv.Atoi(line[1:])
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("invalid bulk length %q", line[1:])
+		}
+		data := make([]byte, length+2)
+		_, err = io.ReadFull(reader, data)
+		if err != nil {
+			return nil, err
+		}
+		args[index] = string(data[:length])
+	}
+	return args, nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeReply(writer *bufio.Writer, reply any) (err error) {
+	switch reply := reply.(type) {
+	case simpleString:
+		_, err = fmt.Fprintf(writer, "+%s\r\n", reply)
+	case error:
+		_, err = fmt.Fprintf(writer, "-%s\r\n", strings.ReplaceAll(reply.Error(), "\r\n", " "))
+	case int64:
+		_, err = fmt.Fprintf(writer, ":%d\r\n", reply)
+	case string:
+		_, err = fmt.Fprintf(writer, "$%d\r\n%s\r\n", len(reply), reply)
+	case []string:
+		_, err = fmt.Fprintf(writer, "*%d\r\n", len(reply))
+		for _, item := range reply {
+			if err == nil {
+				err = writeReply(writer, item)
+			}
+		}
+	case []any:
+		_, err = fmt.Fprintf(writer, "*%d\r\n", len(reply))
+		for _, item := range reply {
+			if err == nil {
+				err = writeReply(writer, item)
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported reply %T", reply)
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package redisproto
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxtek6/keybase-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func serve(t *testing.T) (*Server, *client) {
+	kb, err := keybase.Open(context.Background(), keybase.WithTTL(time.Minute))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = kb.Close() })
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := NewServer(kb, "redis")
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.NoError(t, err)
+	return server, &client{conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// do sends a command and reads its reply, flattened to a single line
+func (c *client) do(t *testing.T, args ...string) string {
+	request := fmt.Sprintf("*%d\r\n", len(args))
+	for _, arg := range args {
+		request += fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := c.conn.Write([]byte(request))
+	assert.NoError(t, err)
+	return c.reply(t)
+}
+
+func (c *client) reply(t *testing.T) string {
+	line, err := readLine(c.reader)
+	assert.NoError(t, err)
+	switch line[0] {
+	case '$':
+		value, err := readLine(c.reader)
+		assert.NoError(t, err)
+		return value
+	case '*':
+		count := 0
+		_, _ = fmt.Sscanf(line, "*%d", &count)
+		items := make([]string, count)
+		for index := range items {
+			items[index] = c.reply(t)
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	}
+	return line
+}
+
+func TestServer(t *testing.T) {
+	_, c := serve(t)
+	assert.Equal(t, "+PONG", c.do(t, "PING"))
+	assert.Equal(t, "hello", c.do(t, "ping", "hello"))
+	assert.Equal(t, "+OK", c.do(t, "SET", "session:1", "value"))
+	assert.Equal(t, "+OK", c.do(t, "SET", "session:2", "value", "EX", "100"))
+	assert.Equal(t, "+OK", c.do(t, "SET", "other", "value", "PX", "100000"))
+	assert.Equal(t, ":2", c.do(t, "EXISTS", "session:1", "session:2", "missing"))
+	assert.Equal(t, "[other session:1 session:2]", c.do(t, "KEYS", "*"))
+	assert.Equal(t, "[session:1 session:2]", c.do(t, "KEYS", "session:*"))
+
+	assert.Equal(t, ":60", c.do(t, "TTL", "session:1"))
+	assert.Equal(t, ":100", c.do(t, "TTL", "session:2"))
+	assert.Equal(t, ":-2", c.do(t, "TTL", "missing"))
+	assert.Equal(t, "+OK", c.do(t, "SET", "session:2", "value", "EX", "10"))
+	assert.Equal(t, ":10", c.do(t, "TTL", "session:2"))
+
+	assert.Equal(t, "[2 [other session:1]]", c.do(t, "SCAN", "0", "COUNT", "2"))
+	assert.Equal(t, "[0 [session:2]]", c.do(t, "SCAN", "2", "COUNT", "2"))
+	assert.Equal(t, "[0 [session:1 session:2]]", c.do(t, "SCAN", "0", "MATCH", "session:*"))
+
+	assert.Equal(t, ":2", c.do(t, "DEL", "session:1", "session:1", "other", "missing"))
+	assert.Equal(t, "[session:2]", c.do(t, "KEYS", "*"))
+
+	assert.Equal(t, "[]", c.do(t, "COMMAND", "DOCS"))
+	assert.Equal(t, "-ERR unknown command 'get'", c.do(t, "GET", "session:2"))
+	assert.Equal(t, "-ERR wrong number of arguments for 'set' command", c.do(t, "SET", "key"))
+	assert.Equal(t, "-ERR syntax error", c.do(t, "SET", "key", "value", "KEEPTTL", "1"))
+	assert.Equal(t, "-ERR invalid expire time in 'set' command", c.do(t, "SET", "key", "value", "EX", "-1"))
+	assert.Equal(t, "-ERR invalid cursor", c.do(t, "SCAN", "x"))
+
+	_, err := c.conn.Write([]byte("EXISTS session:2\r\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, ":1", c.reply(t))
+	assert.Equal(t, "+OK", c.do(t, "QUIT"))
+}
+
+func TestServerClose(t *testing.T) {
+	server, c := serve(t)
+	assert.Equal(t, "+PONG", c.do(t, "PING"))
+	assert.NoError(t, server.Close())
+	_, err := c.reader.ReadString('\n')
+	assert.Error(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, server.Serve(listener), ErrServerClosed)
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Registry hosts independent keybases, each in its own tables, over a single
+// database opened by the application
+type Registry struct {
+	mu       *sync.Mutex
+	db       *sql.DB
+	keybases map[string]*Keybase
+	closed   bool
+}
+
+// OpenShared creates a registry of keybases sharing db, which must use the
+// sqlite driver. The database remains owned by the application, which closes
+// it once the registry is closed.
+func OpenShared(ctx context.Context, db *sql.DB) (*Registry, error) {
+	err := db.PingContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("keybase.OpenShared: failed to open database: %w: %w", ErrInvalidStorage, err)
+	}
+	return &Registry{
+		mu:       new(sync.Mutex),
+		db:       db,
+		keybases: map[string]*Keybase{},
+	}, nil
+}
+
+// Keybase returns the keybase stored in the tables named after name, opening
+// it with the options unless it is already open. Options that configure the
+// database itself are not supported, as it is shared by every keybase.
+func (r *Registry) Keybase(ctx context.Context, name string, opts ...Option) (*Keybase, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, fmt.Errorf("keybase.Registry.Keybase: %w", ErrClosed)
+	}
+	keybase, ok := r.keybases[name]
+	if ok && !keybase.closed.Load() {
+		return keybase, nil
+	}
+	for _, opt := range opts {
+		switch opt.key {
+		case "storage", "pragmas", "createdirs", "changepolling", "table":
+			return nil, fmt.Errorf("keybase.Registry.Keybase: %w: %s cannot be set on a shared database", ErrUnsupportedOption, opt.key)
+		}
+	}
+	config := parseOptions(append(opts, WithTableName(name))...)
+	config.db = r.db
+	keybase, err := open(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("keybase.Registry.Keybase: %w", err)
+	}
+	r.keybases[name] = keybase
+	return keybase, nil
+}
+
+// Close closes every keybase of the registry, leaving the database open, and
+// returns the errors of those that failed to close. Calling Close more than
+// once has no effect.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	errs := []error{}
+	for name, keybase := range r.keybases {
+		err := keybase.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("keybase.Registry.Close: %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenShared(t *testing.T) {
+	ctx := context.Background()
+	storageDirectory, _ := os.MkdirTemp(os.TempDir(), "keybase-*")
+	defer os.RemoveAll(storageDirectory)
+	db, err := sql.Open("sqlite", path.Join(storageDirectory, "keybase.db"))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	registry, err := OpenShared(ctx, db)
+	assert.NoError(t, err)
+	sessions, err := registry.Keybase(ctx, "sessions")
+	assert.NoError(t, err)
+	again, err := registry.Keybase(ctx, "sessions")
+	assert.NoError(t, err)
+	assert.Same(t, sessions, again)
+	limits, err := registry.Keybase(ctx, "limits", WithChecksums())
+	assert.NoError(t, err)
+
+	assert.NoError(t, sessions.Put(ctx, "namespace", "key0"))
+	assert.NoError(t, limits.Put(ctx, "namespace", "key1"))
+	keys, err := sessions.GetKeys(ctx, "namespace", true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key0"}, keys)
+	keys, err = limits.GetKeys(ctx, "namespace", true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key1"}, keys)
+
+	assert.NoError(t, sessions.Close())
+	assert.NoError(t, db.PingContext(ctx))
+	reopened, err := registry.Keybase(ctx, "sessions")
+	assert.NoError(t, err)
+	assert.NotSame(t, sessions, reopened)
+	keys, err = reopened.GetKeys(ctx, "namespace", true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key0"}, keys)
+
+	_, err = registry.Keybase(ctx, "other", WithStorage(":memory:"))
+	assert.ErrorIs(t, err, ErrUnsupportedOption)
+	_, err = registry.Keybase(ctx, "not a table")
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	assert.NoError(t, registry.Close())
+	assert.NoError(t, registry.Close())
+	assert.ErrorIs(t, limits.Put(ctx, "namespace", "key2"), ErrClosed)
+	_, err = registry.Keybase(ctx, "limits")
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.NoError(t, db.PingContext(ctx))
+
+	assert.NoError(t, db.Close())
+	_, err = OpenShared(ctx, db)
+	assert.ErrorIs(t, err, ErrInvalidStorage)
+}
+
+func TestRegistryClose(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", path.Join(t.TempDir(), "keybase.db"))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	registry, err := OpenShared(ctx, db)
+	assert.NoError(t, err)
+	failed := errors.New("failed")
+	keybases := []*Keybase{}
+	for _, name := range []string{"sessions", "limits", "counters"} {
+		keybase, err := registry.Keybase(ctx, name)
+		assert.NoError(t, err)
+		keybase.cleanup = func() error {
+			return failed
+		}
+		keybases = append(keybases, keybase)
+	}
+
+	// every keybase is closed even though each of them fails
+	err = registry.Close()
+	assert.ErrorIs(t, err, failed)
+	assert.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 3)
+	for _, keybase := range keybases {
+		assert.ErrorIs(t, keybase.Put(ctx, "namespace", "key"), ErrClosed)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+)
+
+// SampleKeys collects up to n distinct keys of a namespace chosen at random,
+// so that huge namespaces can be inspected without listing every key
+func (k *Keybase) SampleKeys(ctx context.Context, namespace string, n int, active bool) ([]string, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("keybase.SampleKeys: %w: n must be positive", ErrInvalidArgument)
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	var keys []string
+	err := k.read(ctx, OpSampleKeys, []string{namespace}, func(ctx context.Context) error {
+		values, err := newSampleKeysQuery(k.params(QueryParams{Namespace: namespace, Active: active, Limit: n, Timestamp: timestamp})).queryValues(ctx, k.conn)
+		if err != nil {
+			return err
+		}
+		keys, err = k.decodeAll(values)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.SampleKeys: failed to query database: %w", err)
+	}
+	return keys, nil
+}
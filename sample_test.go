@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleKeys(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{nil, {WithChecksums()}, {WithEncryption(make([]byte, 32))}} {
+		clock := &fixedClock{now: time.Now()}
+		keybase, err := Open(ctx, append(opts, WithClock(clock))...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		_, err = keybase.SampleKeys(ctx, "namespace", 0, true)
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+		keys, err := keybase.SampleKeys(ctx, "namespace", 3, true)
+		assert.NoError(t, err)
+		assert.Empty(t, keys)
+
+		all := []string{}
+		for i := range 20 {
+			key := fmt.Sprintf("key%d", i)
+			assert.NoError(t, keybase.Put(ctx, "namespace", key))
+			assert.NoError(t, keybase.Put(ctx, "namespace", key))
+			all = append(all, key)
+		}
+		assert.NoError(t, keybase.PutUntil(ctx, "namespace", "expired", clock.now.Add(-time.Second)))
+
+		keys, err = keybase.SampleKeys(ctx, "namespace", 5, true)
+		assert.NoError(t, err)
+		assert.Len(t, keys, 5)
+		assert.Subset(t, all, keys)
+		sorted := slices.Clone(keys)
+		slices.Sort(sorted)
+		assert.Len(t, slices.Compact(sorted), 5)
+
+		keys, err = keybase.SampleKeys(ctx, "namespace", 50, true)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, all, keys)
+		keys, err = keybase.SampleKeys(ctx, "namespace", 50, false)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, append(all, "expired"), keys)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+)
+
+// Scan collects up to count active keys of a namespace, starting from the
+// cursor returned by the previous call, or from the beginning when the cursor
+// is empty. The returned cursor is empty once every key has been scanned.
+// Each call seeks directly to its cursor, so scanning a large namespace does
+// not slow down as it progresses. Keys are returned in ascending order, or
+// in the order of their encrypted form when keys are encrypted.
+func (k *Keybase) Scan(ctx context.Context, namespace, cursor string, count int) ([]string, string, error) {
+	if count <= 0 {
+		return nil, "", fmt.Errorf("keybase.Scan: %w: count must be positive", ErrInvalidArgument)
+	}
+	lower, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, "", fmt.Errorf("keybase.Scan: %w: invalid cursor %q", ErrInvalidArgument, cursor)
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	var keys []string
+	next := ""
+	err = k.read(ctx, OpScan, []string{namespace}, func(ctx context.Context) error {
+		// one extra key is selected, which starts the next scan if present
+		values, err := newScanQuery(k.params(QueryParams{Namespace: namespace, Lower: string(lower), Limit: count + 1, Active: true, Unique: true, Timestamp: timestamp})).queryValues(ctx, k.conn)
+		if err != nil {
+			return err
+		}
+		if len(values) > count {
+			next = base64.RawURLEncoding.EncodeToString([]byte(values[count]))
+			values = values[:count]
+		}
+		keys, err = k.decodeAll(values)
+		return err
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("keybase.Scan: failed to query database: %w", err)
+	}
+	return keys, next, nil
+}
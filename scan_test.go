@@ -0,0 +1,88 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScan(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{
+		{},
+		{WithChecksums()},
+		{WithOverflow(32)},
+		{WithEncryption(make([]byte, 32))},
+	} {
+		clock := &fixedClock{now: time.Now()}
+		keybase, err := Open(ctx, append(opts, WithClock(clock))...)
+		assert.NoError(t, err)
+		expected := []string{}
+		for i := range 10 {
+			key := fmt.Sprintf("key%d", i)
+			if i == 7 {
+				key += strings.Repeat("x", 64)
+			}
+			assert.NoError(t, keybase.Put(ctx, "namespace", key))
+			assert.NoError(t, keybase.Put(ctx, "namespace", key))
+			expected = append(expected, key)
+		}
+		assert.NoError(t, keybase.PutUntil(ctx, "namespace", "expired", clock.now.Add(-time.Second)))
+		assert.NoError(t, keybase.Put(ctx, "other", "key"))
+
+		keys := []string{}
+		cursor := ""
+		for pages := 1; ; pages++ {
+			page, next, err := keybase.Scan(ctx, "namespace", cursor, 4)
+			assert.NoError(t, err)
+			keys = append(keys, page...)
+			if next == "" {
+				assert.Len(t, page, 2)
+				assert.Equal(t, 3, pages)
+				break
+			}
+			assert.Len(t, page, 4)
+			cursor = next
+		}
+		assert.ElementsMatch(t, expected, keys)
+
+		keys, cursor, err = keybase.Scan(ctx, "namespace", "", 10)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, expected, keys)
+		assert.Empty(t, cursor)
+		keys, cursor, err = keybase.Scan(ctx, "empty", "", 10)
+		assert.NoError(t, err)
+		assert.Empty(t, keys)
+		assert.Empty(t, cursor)
+
+		_, _, err = keybase.Scan(ctx, "namespace", "", 0)
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+		_, _, err = keybase.Scan(ctx, "namespace", "not a cursor!", 1)
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+		assert.NoError(t, keybase.Close())
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// MigrationPolicy selects how Open handles storage created with an older schema
+type MigrationPolicy int
+
+const (
+	// MigrateAuto applies pending migrations when opening
+	MigrateAuto MigrationPolicy = iota
+	// MigrateFail fails to open with ErrMigrationRequired
+	MigrateFail
+	// MigrateDryRun opens read-only without applying anything, reporting the
+	// pending migrations with PendingMigrations
+	MigrateDryRun
+)
+
+// Migration step that upgrades the schema to its version
+type Migration struct {
+	Version     int
+	Description string
+}
+
+type migration struct {
+	Migration
+	op    Op
+	build func() *dbtx
+}
+
+// migrations every schema change in order, where storage created before
+// versioning was introduced is at version 0
+var migrations = []migration{
+	{Migration{1, "create entry, counter, lease, field, cold tier, overflow, and quarantine tables"}, OpCreateTable, newCreateTableQuery},
+	{Migration{2, "create archive table"}, OpCreateArchiveTable, newCreateArchiveTableQuery},
+	{Migration{3, "create audit table"}, OpCreateAuditTable, newCreateAuditTableQuery},
+	{Migration{4, "create tag table"}, OpCreateTagsTable, newCreateTagsTableQuery},
+	{Migration{5, "create history table"}, OpCreateHistoryTable, newCreateHistoryTableQuery},
+	{Migration{6, "add insertion time to entries"}, OpAddInsertedColumn, newAddInsertedColumnQuery},
+	{Migration{7, "create tombstone table"}, OpCreateTombstoneTable, newCreateTombstoneTableQuery},
+	{Migration{8, "create immutable namespace table"}, OpCreateImmutableTable, newCreateImmutableTableQuery},
+	{Migration{9, "create fence table"}, OpCreateFenceTable, newCreateFenceTableQuery},
+}
+
+// schemaTables tables created by the migrations, named after the default
+// table
+var schemaTables = []string{
+	"keybase", "keybase_counters", "keybase_leases", "keybase_fields", "keybase_cold",
+	"keybase_overflow", "keybase_quarantine", "keybase_archive", "keybase_audit",
+	"keybase_tags", "keybase_history", "keybase_tombstones", "keybase_immutable",
+	"keybase_fences", "keybase_schema",
+}
+
+// Choose how Open handles storage created with an older schema
+func WithMigrationPolicy(policy MigrationPolicy) Option {
+	return Option{
+		key:   "migration",
+		value: policy,
+	}
+}
+
+// migrate brings the schema up to date according to the policy, returning
+// the migrations that were left pending. A new database is always created,
+// unless the policy is a dry run.
+func migrate(ctx context.Context, db *sql.DB, stats *queryStats, table string, policy MigrationPolicy) ([]Migration, error) {
+	conn := &instrumentedDB{querier: db, stats: stats, table: table}
+	tables, err := newSchemaTablesQuery().queryValues(withOperation(ctx, OpSchemaTables), conn)
+	if err != nil {
+		return nil, err
+	}
+	version := 0
+	if slices.Contains(tables, renameTables("keybase_schema", table)) {
+		version, err = newSchemaVersionQuery().queryCount(withOperation(ctx, OpSchemaVersion), conn)
+		if err != nil {
+			return nil, err
+		}
+	}
+	latest := migrations[len(migrations)-1].Version
+	if version > latest {
+		return nil, fmt.Errorf("%w: storage is at version %d, latest known is %d", ErrSchemaVersion, version, latest)
+	}
+	pending := []migration{}
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	fresh := len(tables) == 0
+	switch {
+	case policy == MigrateDryRun:
+		pendingMigrations := make([]Migration, len(pending))
+		for index, m := range pending {
+			pendingMigrations[index] = m.Migration
+		}
+		return pendingMigrations, nil
+	case policy == MigrateFail && !fresh:
+		return nil, fmt.Errorf("%w: storage is at version %d, latest is %d", ErrMigrationRequired, version, latest)
+	}
+	for _, m := range pending {
+		err = applyMigration(ctx, db, stats, table, m)
+		if err != nil {
+			return nil, fmt.Errorf("migration %d: %w", m.Version, err)
+		}
+	}
+	return nil, nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, stats *queryStats, table string, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	conn := &instrumentedDB{querier: tx, stats: stats, table: table}
+	err = m.build().queryExec(withOperation(ctx, m.op), conn)
+	if err == nil {
+		err = newRecordMigrationQuery(QueryParams{Version: m.Version, Timestamp: time.Now().UnixMilli()}).queryExec(withOperation(ctx, OpRecordMigration), conn)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// PendingMigrations lists the migrations a keybase opened with MigrateDryRun
+// would apply
+func (k *Keybase) PendingMigrations() []Migration {
+	return slices.Clone(k.pending)
+}
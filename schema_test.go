@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	storage := filepath.Join(t.TempDir(), "keybase.db")
+	db, err := sql.Open("sqlite", storage)
+	assert.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE keybase(namespace TEXT, key TEXT, expiration INTEGER); INSERT INTO keybase VALUES ('namespace', 'key', 0)")
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	_, err = Open(ctx, WithStorage(storage), WithMigrationPolicy(MigrateFail))
+	assert.ErrorIs(t, err, ErrMigrationRequired)
+
+	keybase, err := Open(ctx, WithStorage(storage), WithMigrationPolicy(MigrateDryRun), WithAutoPrune(time.Millisecond))
+	assert.NoError(t, err)
+	pending := []Migration{}
+	for _, m := range migrations {
+		pending = append(pending, m.Migration)
+	}
+	assert.Equal(t, pending, keybase.PendingMigrations())
+	assert.False(t, keybase.AutoPrune().Status().Running)
+	assert.ErrorIs(t, keybase.Put(ctx, "namespace", "key"), ErrReadOnly)
+	count, err := keybase.CountEntries(ctx, false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NoError(t, keybase.Close())
+
+	keybase, err = Open(ctx, WithStorage(storage))
+	assert.NoError(t, err)
+	assert.Empty(t, keybase.PendingMigrations())
+	_, err = keybase.Increment(ctx, "namespace", "counter", 1)
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.Close())
+
+	keybase, err = Open(ctx, WithStorage(storage), WithMigrationPolicy(MigrateFail))
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.Close())
+
+	db, err = sql.Open("sqlite", storage)
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO keybase_schema(version, applied) VALUES (99, 0)")
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+	_, err = Open(ctx, WithStorage(storage))
+	assert.ErrorIs(t, err, ErrSchemaVersion)
+}
+
+func TestMigrationsFresh(t *testing.T) {
+	ctx := context.Background()
+	keybase, err := Open(ctx, WithMigrationPolicy(MigrateFail))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.Empty(t, keybase.PendingMigrations())
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+	version, err := newSchemaVersionQuery().queryCount(ctx, keybase.conn)
+	assert.NoError(t, err)
+	assert.Equal(t, migrations[len(migrations)-1].Version, version)
+}
@@ -0,0 +1,295 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// ScopedKeybase handle to a keybase that prefixes every namespace with a
+// tenant prefix, so that multi-tenant services can hand out handles without
+// repeating the prefix. Namespaces are returned without the prefix.
+// MatchKeyAcross, which spans namespaces, and SetNamespaceImmutable, which is
+// left to the operator, are not scoped and have to be called on the keybase.
+type ScopedKeybase struct {
+	keybase *Keybase
+	prefix  string
+}
+
+// Scoped creates a handle that prefixes every namespace with tenantPrefix,
+// which is used as is, so it should end with a separator such as "tenant:"
+func (k *Keybase) Scoped(tenantPrefix string) *ScopedKeybase {
+	return &ScopedKeybase{
+		keybase: k,
+		prefix:  tenantPrefix,
+	}
+}
+
+func (s *ScopedKeybase) scope(namespace string) string {
+	return s.prefix + namespace
+}
+
+// Put inserts new value
+func (s *ScopedKeybase) Put(ctx context.Context, namespace, key string, opts ...PutOption) error {
+	return s.keybase.Put(ctx, s.scope(namespace), key, opts...)
+}
+
+// PutUntil inserts new value that expires at the given time
+func (s *ScopedKeybase) PutUntil(ctx context.Context, namespace, key string, until time.Time) error {
+	return s.keybase.PutUntil(ctx, s.scope(namespace), key, until)
+}
+
+// Fence advances the generation of a namespace and returns it as a fencing
+// token
+func (s *ScopedKeybase) Fence(ctx context.Context, namespace string) (uint64, error) {
+	return s.keybase.Fence(ctx, s.scope(namespace))
+}
+
+// PutFenced inserts new value if the token is the latest of the namespace
+func (s *ScopedKeybase) PutFenced(ctx context.Context, namespace, key string, token uint64) error {
+	return s.keybase.PutFenced(ctx, s.scope(namespace), key, token)
+}
+
+// PutUntilFenced inserts new value that expires at the given time if the
+// token is the latest of the namespace
+func (s *ScopedKeybase) PutUntilFenced(ctx context.Context, namespace, key string, until time.Time, token uint64) error {
+	return s.keybase.PutUntilFenced(ctx, s.scope(namespace), key, until, token)
+}
+
+// PutIfAbsent inserts new value unless the key has an active entry
+func (s *ScopedKeybase) PutIfAbsent(ctx context.Context, namespace, key string) (bool, error) {
+	return s.keybase.PutIfAbsent(ctx, s.scope(namespace), key)
+}
+
+// PutParts inserts new value for a key composed of ordered parts
+func (s *ScopedKeybase) PutParts(ctx context.Context, namespace string, parts ...string) error {
+	return s.keybase.PutParts(ctx, s.scope(namespace), parts...)
+}
+
+// PutAsync queues new value for the group commit
+func (s *ScopedKeybase) PutAsync(ctx context.Context, namespace, key string) <-chan error {
+	return s.keybase.PutAsync(ctx, s.scope(namespace), key)
+}
+
+// PutNew inserts a newly generated ULID key and returns it
+func (s *ScopedKeybase) PutNew(ctx context.Context, namespace string) (string, error) {
+	return s.keybase.PutNew(ctx, s.scope(namespace))
+}
+
+// Seen reports whether the key already has an active entry, inserting one if
+// it does not
+func (s *ScopedKeybase) Seen(ctx context.Context, namespace, key string) (bool, error) {
+	return s.keybase.Seen(ctx, s.scope(namespace), key)
+}
+
+// MatchKey collects a list of keys that match a specific pattern
+func (s *ScopedKeybase) MatchKey(ctx context.Context, namespace, pattern string, active, unique bool, opts ...QueryOption) ([]string, error) {
+	return s.keybase.MatchKey(ctx, s.scope(namespace), pattern, active, unique, opts...)
+}
+
+// MatchKeyAny collects a list of keys that match any of the patterns
+func (s *ScopedKeybase) MatchKeyAny(ctx context.Context, namespace string, patterns []string, active, unique bool) ([]string, error) {
+	return s.keybase.MatchKeyAny(ctx, s.scope(namespace), patterns, active, unique)
+}
+
+// MatchParts collects the composite keys whose parts match the patterns
+func (s *ScopedKeybase) MatchParts(ctx context.Context, namespace string, active, unique bool, patterns ...string) ([][]string, error) {
+	return s.keybase.MatchParts(ctx, s.scope(namespace), active, unique, patterns...)
+}
+
+// MatchKeyByTag collects the keys with active entries that were put with the
+// tag
+func (s *ScopedKeybase) MatchKeyByTag(ctx context.Context, namespace, tag string) ([]string, error) {
+	return s.keybase.MatchKeyByTag(ctx, s.scope(namespace), tag)
+}
+
+// CountKey counts the entries of a key
+func (s *ScopedKeybase) CountKey(ctx context.Context, namespace, key string, active bool) (int, error) {
+	return s.keybase.CountKey(ctx, s.scope(namespace), key, active)
+}
+
+// CountMatch counts the keys that match a specific pattern
+func (s *ScopedKeybase) CountMatch(ctx context.Context, namespace, pattern string, active, unique bool) (int, error) {
+	return s.keybase.CountMatch(ctx, s.scope(namespace), pattern, active, unique)
+}
+
+// GetExpiration gets the latest expiration of a key
+func (s *ScopedKeybase) GetExpiration(ctx context.Context, namespace, key string) (time.Time, error) {
+	return s.keybase.GetExpiration(ctx, s.scope(namespace), key)
+}
+
+// GetTTL gets the time left until a key expires
+func (s *ScopedKeybase) GetTTL(ctx context.Context, namespace, key string) (time.Duration, error) {
+	return s.keybase.GetTTL(ctx, s.scope(namespace), key)
+}
+
+// GetKeyHistory lists the recorded insertions of a key, oldest first
+func (s *ScopedKeybase) GetKeyHistory(ctx context.Context, namespace, key string) ([]KeyVersion, error) {
+	return s.keybase.GetKeyHistory(ctx, s.scope(namespace), key)
+}
+
+// KeyFrequency counts the active entries of a key inserted during the window
+// leading up to now
+func (s *ScopedKeybase) KeyFrequency(ctx context.Context, namespace, key string, buckets int, window time.Duration) ([]int, error) {
+	return s.keybase.KeyFrequency(ctx, s.scope(namespace), key, buckets, window)
+}
+
+// ExpirationHistogram groups the active entries of a namespace by when they
+// expire
+func (s *ScopedKeybase) ExpirationHistogram(ctx context.Context, namespace string, buckets int) ([]Bucket, error) {
+	return s.keybase.ExpirationHistogram(ctx, s.scope(namespace), buckets)
+}
+
+// ExpireMatch sets the expiration of the keys that match a specific pattern
+func (s *ScopedKeybase) ExpireMatch(ctx context.Context, namespace, pattern string, at time.Time) (int, error) {
+	return s.keybase.ExpireMatch(ctx, s.scope(namespace), pattern, at)
+}
+
+// DeleteMatch removes the entries of the keys that match a specific pattern
+func (s *ScopedKeybase) DeleteMatch(ctx context.Context, namespace, pattern string, dryRun bool) (int, error) {
+	return s.keybase.DeleteMatch(ctx, s.scope(namespace), pattern, dryRun)
+}
+
+// GetKeys collects a list of keys of a namespace
+func (s *ScopedKeybase) GetKeys(ctx context.Context, namespace string, active, unique bool, opts ...QueryOption) ([]string, error) {
+	return s.keybase.GetKeys(ctx, s.scope(namespace), active, unique, opts...)
+}
+
+// CountKeys counts the keys of a namespace
+func (s *ScopedKeybase) CountKeys(ctx context.Context, namespace string, active, unique bool) (int, error) {
+	return s.keybase.CountKeys(ctx, s.scope(namespace), active, unique)
+}
+
+// GetEntries collects the entries of a namespace
+func (s *ScopedKeybase) GetEntries(ctx context.Context, namespace string, opts ...EntryOption) ([]Entry, error) {
+	entries, err := s.keybase.GetEntries(ctx, s.scope(namespace), opts...)
+	for index := range entries {
+		entries[index].Namespace = namespace
+	}
+	return entries, err
+}
+
+// Scan pages through the active keys of a namespace
+func (s *ScopedKeybase) Scan(ctx context.Context, namespace, cursor string, count int) ([]string, string, error) {
+	return s.keybase.Scan(ctx, s.scope(namespace), cursor, count)
+}
+
+// TopKeys collects the n keys of a namespace with the most entries
+func (s *ScopedKeybase) TopKeys(ctx context.Context, namespace string, n int, active bool) ([]KeyCount, error) {
+	return s.keybase.TopKeys(ctx, s.scope(namespace), n, active)
+}
+
+// SampleKeys collects up to n distinct keys of a namespace chosen at random
+func (s *ScopedKeybase) SampleKeys(ctx context.Context, namespace string, n int, active bool) ([]string, error) {
+	return s.keybase.SampleKeys(ctx, s.scope(namespace), n, active)
+}
+
+// ScanULIDs collects the active ULID keys of a namespace that were generated
+// between from and to
+func (s *ScopedKeybase) ScanULIDs(ctx context.Context, namespace string, from, to time.Time) ([]string, error) {
+	return s.keybase.ScanULIDs(ctx, s.scope(namespace), from, to)
+}
+
+// GetKeysInsertedBetween collects the keys of a namespace that were inserted
+// between from and to
+func (s *ScopedKeybase) GetKeysInsertedBetween(ctx context.Context, namespace string, from, to time.Time) ([]string, error) {
+	return s.keybase.GetKeysInsertedBetween(ctx, s.scope(namespace), from, to)
+}
+
+// GetArchivedKeys collects the keys of a namespace that were archived when
+// pruned
+func (s *ScopedKeybase) GetArchivedKeys(ctx context.Context, namespace string) ([]string, error) {
+	return s.keybase.GetArchivedKeys(ctx, s.scope(namespace))
+}
+
+// GetDeletedKeys collects the keys of a namespace that were deleted while
+// tombstones were kept
+func (s *ScopedKeybase) GetDeletedKeys(ctx context.Context, namespace string) ([]string, error) {
+	return s.keybase.GetDeletedKeys(ctx, s.scope(namespace))
+}
+
+// GetNamespaces collects the namespaces of the tenant
+func (s *ScopedKeybase) GetNamespaces(ctx context.Context, active bool) ([]string, error) {
+	return s.MatchNamespaces(ctx, "*", active)
+}
+
+// MatchNamespaces collects the namespaces of the tenant that match a specific
+// pattern
+func (s *ScopedKeybase) MatchNamespaces(ctx context.Context, pattern string, active bool) ([]string, error) {
+	namespaces, err := s.keybase.MatchNamespaces(ctx, EscapePattern(s.prefix)+pattern, active)
+	for index, namespace := range namespaces {
+		namespaces[index] = strings.TrimPrefix(namespace, s.prefix)
+	}
+	return namespaces, err
+}
+
+// PruneNamespace removes stale entries of a namespace
+func (s *ScopedKeybase) PruneNamespace(ctx context.Context, namespace string) error {
+	return s.keybase.PruneNamespace(ctx, s.scope(namespace))
+}
+
+// ClearNamespace removes all entries of a namespace
+func (s *ScopedKeybase) ClearNamespace(ctx context.Context, namespace string) error {
+	return s.keybase.ClearNamespace(ctx, s.scope(namespace))
+}
+
+// CopyNamespace copies the entries of a namespace to another namespace of the
+// tenant
+func (s *ScopedKeybase) CopyNamespace(ctx context.Context, src, dst string, overwriteExpiration bool) (int, error) {
+	return s.keybase.CopyNamespace(ctx, s.scope(src), s.scope(dst), overwriteExpiration)
+}
+
+// Increment adds delta to a counter
+func (s *ScopedKeybase) Increment(ctx context.Context, namespace, key string, delta int64) (int64, error) {
+	return s.keybase.Increment(ctx, s.scope(namespace), key, delta)
+}
+
+// GetCounter gets the value of a counter
+func (s *ScopedKeybase) GetCounter(ctx context.Context, namespace, key string) (int64, error) {
+	return s.keybase.GetCounter(ctx, s.scope(namespace), key)
+}
+
+// PutField sets a field of a key
+func (s *ScopedKeybase) PutField(ctx context.Context, namespace, key, field, value string) error {
+	return s.keybase.PutField(ctx, s.scope(namespace), key, field, value)
+}
+
+// GetField gets a field of a key
+func (s *ScopedKeybase) GetField(ctx context.Context, namespace, key, field string) (string, error) {
+	return s.keybase.GetField(ctx, s.scope(namespace), key, field)
+}
+
+// GetFields gets every field of a key
+func (s *ScopedKeybase) GetFields(ctx context.Context, namespace, key string) (map[string]string, error) {
+	return s.keybase.GetFields(ctx, s.scope(namespace), key)
+}
+
+// AcquireLease takes exclusive ownership of a key for the given duration
+func (s *ScopedKeybase) AcquireLease(ctx context.Context, namespace, key string, ttl time.Duration) (*Lease, error) {
+	return s.keybase.AcquireLease(ctx, s.scope(namespace), key, ttl)
+}
+
+// Claim claims a key that has no active entries for the given duration
+func (s *ScopedKeybase) Claim(ctx context.Context, namespace, key string, ttl time.Duration) (*Claim, error) {
+	return s.keybase.Claim(ctx, s.scope(namespace), key, ttl)
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScoped(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{nil, {WithChecksums()}, {WithEncryption(make([]byte, 32))}} {
+		keybase, err := Open(ctx, opts...)
+		assert.NoError(t, err)
+		alice := keybase.Scoped("alice:")
+		bob := keybase.Scoped("bob:")
+
+		assert.NoError(t, alice.Put(ctx, "sessions", "key1"))
+		assert.NoError(t, alice.Put(ctx, "sessions", "key2"))
+		assert.NoError(t, bob.Put(ctx, "sessions", "key3"))
+		keys, err := alice.GetKeys(ctx, "sessions", true, true)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"key1", "key2"}, keys)
+		keys, err = keybase.GetKeys(ctx, "bob:sessions", true, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key3"}, keys)
+
+		entries, err := alice.GetEntries(ctx, "sessions")
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, "sessions", entries[0].Namespace)
+
+		_, err = alice.CopyNamespace(ctx, "sessions", "copy", false)
+		assert.NoError(t, err)
+		namespaces, err := alice.GetNamespaces(ctx, true)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"sessions", "copy"}, namespaces)
+		namespaces, err = bob.GetNamespaces(ctx, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"sessions"}, namespaces)
+
+		seen, err := alice.Seen(ctx, "seen", "key")
+		assert.NoError(t, err)
+		assert.False(t, seen)
+		seen, err = bob.Seen(ctx, "seen", "key")
+		assert.NoError(t, err)
+		assert.False(t, seen)
+		assert.NoError(t, alice.PutParts(ctx, "parts", "user", "device"))
+		parts, err := alice.MatchParts(ctx, "parts", true, true, "user", "*")
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"user", "device"}}, parts)
+		parts, err = bob.MatchParts(ctx, "parts", true, true, "user", "*")
+		assert.NoError(t, err)
+		assert.Empty(t, parts)
+		keys, err = alice.SampleKeys(ctx, "sessions", 10, true)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"key1", "key2"}, keys)
+		keys, err = keybase.GetKeys(ctx, "alice:parts", true, true)
+		assert.NoError(t, err)
+		assert.Len(t, keys, 1)
+
+		token, err := alice.Fence(ctx, "fenced")
+		assert.NoError(t, err)
+		assert.NoError(t, alice.PutFenced(ctx, "fenced", "key", token))
+		assert.ErrorIs(t, bob.PutFenced(ctx, "fenced", "key", token), ErrStaleFence)
+		assert.NoError(t, bob.PutFenced(ctx, "fenced", "key", 0))
+		frequency, err := alice.KeyFrequency(ctx, "fenced", "key", 1, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1}, frequency)
+
+		assert.NoError(t, alice.ClearNamespace(ctx, "sessions"))
+		count, err := bob.CountKey(ctx, "sessions", "key3", true)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.NoError(t, keybase.Close())
+	}
+}
+
+func TestScopedArchive(t *testing.T) {
+	ctx := context.Background()
+	clock := &fixedClock{now: time.Now()}
+	keybase, err := Open(ctx, WithClock(clock), WithTTL(time.Minute), WithArchiveExpired(), WithTombstones())
+	assert.NoError(t, err)
+	defer keybase.Close()
+	alice := keybase.Scoped("alice:")
+	bob := keybase.Scoped("bob:")
+
+	assert.NoError(t, alice.Put(ctx, "sessions", "key0"))
+	assert.NoError(t, bob.Put(ctx, "sessions", "key1"))
+	assert.NoError(t, keybase.Tx(ctx, func(tx *KeybaseTx) error {
+		return tx.Delete(ctx, "alice:sessions", "key0")
+	}))
+	keys, err := alice.GetDeletedKeys(ctx, "sessions")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key0"}, keys)
+	keys, err = bob.GetDeletedKeys(ctx, "sessions")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	clock.now = clock.now.Add(time.Hour)
+	assert.NoError(t, keybase.PruneEntries(ctx))
+	keys, err = bob.GetArchivedKeys(ctx, "sessions")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key1"}, keys)
+	keys, err = alice.GetArchivedKeys(ctx, "sessions")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+)
+
+// Prune stale entries one last time when the keybase is shut down
+func WithShutdownPrune() Option {
+	return Option{
+		key:   "shutdownprune",
+		value: true,
+	}
+}
+
+// Shutdown stops background features, runs the final prune if enabled with
+// WithShutdownPrune, and waits for in-flight operations before closing the
+// database. If the context is done before they finish, the database is closed
+// underneath them and the context error is returned. Calling Shutdown or Close
+// more than once has no effect.
+func (k *Keybase) Shutdown(ctx context.Context) error {
+	if k.closed.Load() {
+		return nil
+	}
+	k.stopFeatures()
+	k.group.close()
+	var pruneErr error
+	if k.config.shutdownPrune && !k.readOnly {
+		pruneErr = k.prune(ctx, OpPruneEntries, QueryParams{})
+	}
+	if !k.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	k.expire.close()
+	drained := make(chan struct{})
+	go func() {
+		k.inflight.Lock()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		defer k.inflight.Unlock()
+	case <-ctx.Done():
+		// release the lock once the remaining operations finish so later
+		// calls fail with ErrClosed instead of blocking
+		go func() {
+			<-drained
+			k.inflight.Unlock()
+		}()
+		_ = k.release()
+		return fmt.Errorf("keybase.Shutdown: in-flight operations did not finish: %w", ctx.Err())
+	}
+	err := k.release()
+	if err != nil {
+		return fmt.Errorf("keybase.Shutdown: %w", err)
+	}
+	if pruneErr != nil {
+		return fmt.Errorf("keybase.Shutdown: failed to prune entries: %w", pruneErr)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdown(t *testing.T) {
+	ctx := context.Background()
+	storage := filepath.Join(t.TempDir(), "keybase.db")
+	clock := &fixedClock{now: time.Now()}
+	keybase, err := Open(ctx, WithStorage(storage), WithClock(clock), WithShutdownPrune())
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.PutUntil(ctx, "namespace", "key0", clock.now.Add(-time.Second)))
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key1"))
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	finished := make(chan error)
+	go func() {
+		finished <- keybase.read(ctx, OpCountEntries, nil, func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- keybase.Shutdown(ctx)
+	}()
+	select {
+	case <-shutdown:
+		t.Fatal("shutdown did not wait for the in-flight operation")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	assert.NoError(t, <-finished)
+	assert.NoError(t, <-shutdown)
+	assert.NoError(t, keybase.Shutdown(ctx))
+	assert.NoError(t, keybase.Close())
+	assert.ErrorIs(t, keybase.Put(ctx, "namespace", "key2"), ErrClosed)
+
+	keybase, err = Open(ctx, WithStorage(storage), WithClock(clock))
+	assert.NoError(t, err)
+	count, err := keybase.CountEntries(ctx, false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NoError(t, keybase.Close())
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	ctx := context.Background()
+	keybase, err := Open(ctx)
+	assert.NoError(t, err)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	finished := make(chan error)
+	go func() {
+		finished <- keybase.read(ctx, OpCountEntries, nil, func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, keybase.Shutdown(timeout), context.DeadlineExceeded)
+	close(release)
+	assert.NoError(t, <-finished)
+	assert.ErrorIs(t, keybase.Ping(ctx), ErrClosed)
+}
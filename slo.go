@@ -0,0 +1,172 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sloSamples = 256
+	sloWindow  = time.Second * 10
+	sloRefresh = time.Millisecond * 100
+)
+
+// SLOStatus state of the latency objective set with WithSLO
+type SLOStatus struct {
+	Target time.Duration
+	// P99 observed 99th percentile latency over the rolling window
+	P99      time.Duration
+	Breached bool
+	// Shed number of reads that failed fast with ErrOverloaded
+	Shed int64
+}
+
+type sloOption struct {
+	p99  time.Duration
+	shed func(op string) bool
+}
+
+type sloSample struct {
+	at      time.Time
+	elapsed time.Duration
+}
+
+// sloTracker keeps the latencies of the most recent operations, recomputing
+// the percentile at most once per refresh interval
+type sloTracker struct {
+	mu       *sync.Mutex
+	target   time.Duration
+	shed     func(op string) bool
+	samples  []sloSample
+	next     int
+	p99      time.Duration
+	computed time.Time
+	dropped  int64
+}
+
+// Track the 99th percentile latency of operations over a rolling window and,
+// while it exceeds p99, ask shed whether each read should fail fast with
+// ErrOverloaded. Writes are never shed. A nil shed sheds the Count operations.
+func WithSLO(p99 time.Duration, shed func(op string) bool) Option {
+	return Option{
+		key: "slo",
+		value: sloOption{
+			p99:  p99,
+			shed: shed,
+		},
+	}
+}
+
+func shedCounts(op string) bool {
+	return strings.HasPrefix(op, "Count")
+}
+
+func newSLOTracker(option sloOption) *sloTracker {
+	shed := option.shed
+	if shed == nil {
+		shed = shedCounts
+	}
+	return &sloTracker{
+		mu:      new(sync.Mutex),
+		target:  option.p99,
+		shed:    shed,
+		samples: make([]sloSample, 0, sloSamples),
+	}
+}
+
+func (s *sloTracker) observe(elapsed time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sample := sloSample{at: time.Now(), elapsed: elapsed}
+	if len(s.samples) < sloSamples {
+		s.samples = append(s.samples, sample)
+	} else {
+		s.samples[s.next] = sample
+	}
+	s.next = (s.next + 1) % sloSamples
+}
+
+// admit reports whether a read may run, consulting shed only while the
+// objective is breached
+func (s *sloTracker) admit(op Op) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	breached := s.update(time.Now()) > s.target
+	s.mu.Unlock()
+	if !breached || !s.shed(string(op)) {
+		return true
+	}
+	s.mu.Lock()
+	s.dropped++
+	s.mu.Unlock()
+	return false
+}
+
+// update recomputes the percentile from the samples within the window once
+// the previous value is stale
+func (s *sloTracker) update(now time.Time) time.Duration {
+	if now.Sub(s.computed) < sloRefresh {
+		return s.p99
+	}
+	latencies := make([]time.Duration, 0, len(s.samples))
+	for _, sample := range s.samples {
+		if now.Sub(sample.at) <= sloWindow {
+			latencies = append(latencies, sample.elapsed)
+		}
+	}
+	s.p99 = 0
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		s.p99 = latencies[(len(latencies)*99+99)/100-1]
+	}
+	s.computed = now
+	return s.p99
+}
+
+func (s *sloTracker) status() SLOStatus {
+	if s == nil {
+		return SLOStatus{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p99 := s.update(time.Now())
+	return SLOStatus{
+		Target:   s.target,
+		P99:      p99,
+		Breached: p99 > s.target,
+		Shed:     s.dropped,
+	}
+}
+
+// SLOStatus reports the latency observed against the objective set with
+// WithSLO, or a zero status if it was not set
+func (k *Keybase) SLOStatus() SLOStatus {
+	return k.slo.status()
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSLO(t *testing.T) {
+	ctx := context.Background()
+	keybase, err := Open(ctx, WithSLO(time.Nanosecond, nil))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+	_, err = keybase.CountEntries(ctx, true, false)
+	assert.ErrorIs(t, err, ErrOverloaded)
+	keys, err := keybase.GetKeys(ctx, "namespace", true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key"}, keys)
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+
+	status := keybase.SLOStatus()
+	assert.Equal(t, time.Nanosecond, status.Target)
+	assert.True(t, status.Breached)
+	assert.Equal(t, int64(1), status.Shed)
+
+	keybase, err = Open(ctx, WithSLO(time.Hour, func(string) bool { return true }))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+	_, err = keybase.CountEntries(ctx, true, false)
+	assert.NoError(t, err)
+	assert.False(t, keybase.SLOStatus().Breached)
+
+	keybase, err = Open(ctx)
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.Equal(t, SLOStatus{}, keybase.SLOStatus())
+}
+
+func TestSLOTracker(t *testing.T) {
+	tracker := newSLOTracker(sloOption{p99: time.Millisecond})
+	for i := 1; i <= sloSamples*2; i++ {
+		tracker.observe(time.Duration(i%100) * time.Microsecond)
+	}
+	tracker.observe(time.Second)
+	now := time.Now()
+	assert.Equal(t, 99*time.Microsecond, tracker.update(now))
+	assert.True(t, tracker.admit(OpGetKeys))
+
+	tracker.observe(time.Second)
+	tracker.observe(time.Second)
+	tracker.observe(time.Second)
+	assert.Equal(t, 99*time.Microsecond, tracker.update(now), "refreshed too early")
+	assert.Equal(t, time.Second, tracker.update(now.Add(sloRefresh)))
+	assert.False(t, tracker.admit(OpCountKey))
+	assert.True(t, tracker.admit(OpGetKeys))
+	assert.Zero(t, tracker.update(now.Add(sloWindow*2)))
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// OpenSnapshot copies the current state of the keybase to a temporary file and
+// opens it as a read-only keybase, so long scans neither block nor observe
+// later writes. The copy is removed when the snapshot is closed.
+func (k *Keybase) OpenSnapshot(ctx context.Context) (*Keybase, error) {
+	directory, err := os.MkdirTemp("", "keybase-snapshot-*")
+	if err != nil {
+		return nil, fmt.Errorf("keybase.OpenSnapshot: failed to create directory: %w", err)
+	}
+	path := filepath.Join(directory, "snapshot.db")
+	err = k.read(ctx, OpSnapshot, nil, func(ctx context.Context) error {
+		return newSnapshotQuery(QueryParams{Value: path}).queryExec(ctx, k.conn)
+	})
+	if err != nil {
+		_ = os.RemoveAll(directory)
+		return nil, fmt.Errorf("keybase.OpenSnapshot: failed to copy database: %w", err)
+	}
+	config := *k.config
+	config.storage = path
+	config.db = nil
+	config.pragmas = maps.Clone(k.config.pragmas)
+	config.readOnly = true
+	config.ttl = time.Duration(k.ttl.Load())
+	// the snapshot must not take over the expvar of the keybase or run its
+	// callbacks
+	config.expvar = false
+	config.interceptors = nil
+	config.alarm = nil
+	config.changePolling = false
+	// the keybase already exports into the same directory
+	config.export = nil
+	snapshot, err := open(ctx, &config)
+	if err != nil {
+		_ = os.RemoveAll(directory)
+		return nil, fmt.Errorf("keybase.OpenSnapshot: %w", err)
+	}
+	snapshot.cleanup = func() error {
+		return os.RemoveAll(directory)
+	}
+	return snapshot, nil
+}
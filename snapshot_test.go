@@ -0,0 +1,116 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenSnapshot(t *testing.T) {
+	keybase, err := Open(context.Background(), WithTTL(time.Minute), WithOverflow(8))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "key0"))
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "overflowing"))
+
+	snapshot, err := keybase.OpenSnapshot(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "key1"))
+
+	keys, err := snapshot.GetKeys(context.Background(), "namespace", true, true)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"key0", "overflowing"}, keys)
+	count, err := keybase.CountKeys(context.Background(), "namespace", true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	assert.ErrorIs(t, snapshot.Put(context.Background(), "namespace", "key2"), ErrReadOnly)
+	assert.ErrorIs(t, snapshot.ClearEntries(context.Background()), ErrReadOnly)
+	_, err = snapshot.db.Exec("DELETE FROM keybase")
+	assert.Error(t, err)
+
+	storage := snapshot.config.storage
+	assert.FileExists(t, storage)
+	assert.NoError(t, snapshot.Close())
+	assert.NoFileExists(t, storage)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(0))
+	defer cancel()
+	_, err = keybase.OpenSnapshot(ctx)
+	assert.Error(t, err)
+}
+
+func TestSnapshotOptions(t *testing.T) {
+	ctx := context.Background()
+	intercepted := 0
+	keybase, err := Open(ctx,
+		WithExpvar("keybase_snapshot_test"),
+		WithScheduledExport("*", time.Hour, t.TempDir(), JSONCodec{}),
+		WithAlarm(1, func(int) {}),
+		WithInterceptor(func(ctx context.Context, op OpInfo, next func(ctx context.Context) error) error {
+			intercepted++
+			return next(ctx)
+		}),
+	)
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+
+	snapshot, err := keybase.OpenSnapshot(ctx)
+	assert.NoError(t, err)
+	intercepted = 0
+	_, err = snapshot.CountEntries(ctx, true, false)
+	assert.NoError(t, err)
+	assert.Zero(t, intercepted)
+	assert.Nil(t, snapshot.alarm)
+	assert.False(t, snapshot.ScheduledExport().Status().Running)
+	assert.NoError(t, snapshot.Close())
+	assert.Same(t, keybase, expvars.published["keybase_snapshot_test"].Load())
+}
+
+func TestReadOnly(t *testing.T) {
+	storageDirectory, _ := os.MkdirTemp(os.TempDir(), "keybase-*")
+	defer os.RemoveAll(storageDirectory)
+	storagePath := path.Join(storageDirectory, "keybase.db")
+
+	_, err := Open(context.Background(), WithStorage(storagePath), WithReadOnly())
+	assert.ErrorIs(t, err, ErrInvalidStorage)
+
+	keybase, err := Open(context.Background(), WithStorage(storagePath))
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "key"))
+	assert.NoError(t, keybase.Close())
+
+	keybase, err = Open(context.Background(), WithStorage(storagePath), WithReadOnly(), WithAutoPrune(time.Millisecond))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.False(t, keybase.AutoPrune().Status().Running)
+	count, err := keybase.CountEntries(context.Background(), false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.ErrorIs(t, keybase.PruneEntries(context.Background()), ErrReadOnly)
+}
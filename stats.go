@@ -0,0 +1,252 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var latencyBounds = []time.Duration{
+	time.Millisecond,
+	time.Millisecond * 5,
+	time.Millisecond * 10,
+	time.Millisecond * 50,
+	time.Millisecond * 100,
+	time.Millisecond * 500,
+	time.Second,
+}
+
+// LatencyBucket number of queries that completed within an upper bound,
+// where the final bucket has no upper bound and reports zero
+type LatencyBucket struct {
+	UpperBound time.Duration
+	Count      int64
+}
+
+// QueryStats statistics collected for the queries issued by an operation
+type QueryStats struct {
+	Queries      int64
+	Errors       int64
+	RowsScanned  int64
+	TotalLatency time.Duration
+	Latency      []LatencyBucket
+}
+
+// Stats summary of the contents of a keybase
+type Stats struct {
+	TotalEntries  int
+	ActiveEntries int
+	// Namespaces number of active entries in each namespace
+	Namespaces map[string]int
+	// EarliestExpiration and LatestExpiration span the expirations of every
+	// entry, including expired entries that were not pruned yet
+	EarliestExpiration time.Time
+	LatestExpiration   time.Time
+	// StorageSize size of the database in bytes
+	StorageSize int64
+	Prunes      PruneStats
+}
+
+// NamespaceInfo summary of the entries of a namespace
+type NamespaceInfo struct {
+	Namespace     string
+	TotalEntries  int
+	UniqueKeys    int
+	ActiveEntries int
+	// NextExpiration time at which the next active entry expires, or zero if
+	// the namespace has no active entries
+	NextExpiration time.Time
+}
+
+// PruneStats prunes run since the keybase was opened
+type PruneStats struct {
+	Runs    int64
+	Entries int64
+	LastRun time.Time
+}
+
+type queryStats struct {
+	mu     *sync.Mutex
+	ops    map[Op]*QueryStats
+	writes contentionStats
+	prunes PruneStats
+}
+
+type observer interface {
+	observe(op Op, elapsed time.Duration, rows int, err error)
+}
+
+// instrumentedDB wraps a connection and records statistics for each query,
+// renaming the tables it touches when the keybase has its own table name
+type instrumentedDB struct {
+	querier
+	stats *queryStats
+	table string
+}
+
+type operationKey struct{}
+
+func newQueryStats() *queryStats {
+	return &queryStats{
+		mu:  new(sync.Mutex),
+		ops: map[Op]*QueryStats{},
+	}
+}
+
+func newLatencyBuckets() []LatencyBucket {
+	buckets := make([]LatencyBucket, len(latencyBounds)+1)
+	for index, bound := range latencyBounds {
+		buckets[index].UpperBound = bound
+	}
+	return buckets
+}
+
+func (s *queryStats) record(op Op, elapsed time.Duration, rows int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.ops[op]
+	if !ok {
+		stats = &QueryStats{Latency: newLatencyBuckets()}
+		s.ops[op] = stats
+	}
+	stats.Queries++
+	if err != nil {
+		stats.Errors++
+	}
+	stats.RowsScanned += int64(rows)
+	stats.TotalLatency += elapsed
+	bucket := len(latencyBounds)
+	for index, bound := range latencyBounds {
+		if elapsed <= bound {
+			bucket = index
+			break
+		}
+	}
+	stats.Latency[bucket].Count++
+}
+
+func (s *queryStats) snapshot() map[Op]QueryStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := make(map[Op]QueryStats, len(s.ops))
+	for op, stats := range s.ops {
+		copied := *stats
+		copied.Latency = append([]LatencyBucket(nil), stats.Latency...)
+		snapshot[op] = copied
+	}
+	return snapshot
+}
+
+func (s *queryStats) recordPrune(entries int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prunes.Runs++
+	s.prunes.Entries += entries
+	s.prunes.LastRun = time.Now()
+}
+
+func (db *instrumentedDB) observe(op Op, elapsed time.Duration, rows int, err error) {
+	db.stats.record(op, elapsed, rows, err)
+}
+
+func withOperation(ctx context.Context, op Op) context.Context {
+	return context.WithValue(ctx, operationKey{}, op)
+}
+
+func operation(ctx context.Context) Op {
+	op, ok := ctx.Value(operationKey{}).(Op)
+	if !ok {
+		return Op("unknown")
+	}
+	return op
+}
+
+func observe(ctx context.Context, db querier, start time.Time, rows int, err error) {
+	if o, ok := db.(observer); ok {
+		o.observe(operation(ctx), time.Since(start), rows, err)
+	}
+}
+
+// QueryStats collects the query statistics recorded for each operation
+func (k *Keybase) QueryStats() map[Op]QueryStats {
+	return k.stats.snapshot()
+}
+
+// Stats summarizes the entries, namespaces, storage and prunes of the keybase
+// in a single call
+func (k *Keybase) Stats(ctx context.Context) (Stats, error) {
+	timestamp := k.clock.Now().UnixMilli()
+	stats := Stats{Namespaces: map[string]int{}}
+	err := k.read(ctx, OpStats, nil, func(ctx context.Context) error {
+		params := k.params(QueryParams{Timestamp: timestamp})
+		var earliest, latest sql.NullInt64
+		err := newStatsQuery(params).queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			return rows.Scan(&stats.TotalEntries, &stats.ActiveEntries, &earliest, &latest, &stats.StorageSize)
+		})
+		if err != nil {
+			return err
+		}
+		if earliest.Valid {
+			stats.EarliestExpiration = time.UnixMilli(earliest.Int64)
+			stats.LatestExpiration = time.UnixMilli(latest.Int64)
+		}
+		return newNamespaceEntriesQuery(params).queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			namespace, count := "", 0
+			err := rows.Scan(&namespace, &count)
+			stats.Namespaces[namespace] = count
+			return err
+		})
+	})
+	if err != nil {
+		return Stats{}, fmt.Errorf("keybase.Stats: failed to query database: %w", err)
+	}
+	k.stats.mu.Lock()
+	stats.Prunes = k.stats.prunes
+	k.stats.mu.Unlock()
+	return stats, nil
+}
+
+// DescribeNamespaces summarizes every namespace, in order, with a single
+// aggregated query
+func (k *Keybase) DescribeNamespaces(ctx context.Context) ([]NamespaceInfo, error) {
+	timestamp := k.clock.Now().UnixMilli()
+	namespaces := []NamespaceInfo{}
+	err := k.read(ctx, OpDescribeNamespaces, nil, func(ctx context.Context) error {
+		return newDescribeNamespacesQuery(k.params(QueryParams{Timestamp: timestamp})).queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			var info NamespaceInfo
+			var next sql.NullInt64
+			err := rows.Scan(&info.Namespace, &info.TotalEntries, &info.UniqueKeys, &info.ActiveEntries, &next)
+			if next.Valid {
+				info.NextExpiration = time.UnixMilli(next.Int64)
+			}
+			namespaces = append(namespaces, info)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.DescribeNamespaces: failed to query database: %w", err)
+	}
+	return namespaces, nil
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryStatsRecord(t *testing.T) {
+	stats := newQueryStats()
+	stats.record(OpPut, time.Microsecond, 1, nil)
+	stats.record(OpPut, time.Millisecond*20, 1, errors.New("some error"))
+	stats.record(OpPut, time.Minute, 0, nil)
+
+	snapshot := stats.snapshot()
+	assert.Len(t, snapshot, 1)
+	put := snapshot[OpPut]
+	assert.Equal(t, int64(3), put.Queries)
+	assert.Equal(t, int64(1), put.Errors)
+	assert.Equal(t, int64(2), put.RowsScanned)
+	assert.Equal(t, time.Microsecond+time.Millisecond*20+time.Minute, put.TotalLatency)
+	assert.Len(t, put.Latency, len(latencyBounds)+1)
+	assert.Equal(t, int64(1), put.Latency[0].Count)
+	assert.Equal(t, int64(1), put.Latency[3].Count)
+	assert.Equal(t, int64(1), put.Latency[len(latencyBounds)].Count)
+	assert.Zero(t, put.Latency[len(latencyBounds)].UpperBound)
+
+	snapshot[OpPut].Latency[0].Count = 100
+	assert.Equal(t, int64(1), stats.snapshot()[OpPut].Latency[0].Count)
+}
+
+func TestOperation(t *testing.T) {
+	assert.Equal(t, Op("unknown"), operation(context.Background()))
+	assert.Equal(t, OpGetKeys, operation(withOperation(context.Background(), OpGetKeys)))
+}
+
+func TestKeybaseQueryStats(t *testing.T) {
+	keybase, err := Open(context.Background())
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	err = keybase.Put(context.Background(), "namespace", "key0")
+	assert.NoError(t, err)
+	err = keybase.Put(context.Background(), "namespace", "key1")
+	assert.NoError(t, err)
+	_, err = keybase.GetKeys(context.Background(), "namespace", true, false)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(0))
+	defer cancel()
+	_, err = keybase.GetKeys(ctx, "namespace", true, false)
+	assert.Error(t, err)
+
+	stats := keybase.QueryStats()
+	assert.Equal(t, int64(1), stats[OpCreateTable].Queries)
+	assert.Equal(t, int64(2), stats[OpPut].Queries)
+	assert.Equal(t, int64(2), stats[OpPut].RowsScanned)
+	assert.Equal(t, int64(2), stats[OpGetKeys].Queries)
+	assert.Equal(t, int64(1), stats[OpGetKeys].Errors)
+	assert.Equal(t, int64(2), stats[OpGetKeys].RowsScanned)
+}
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	keybase, err := Open(ctx, WithTTL(time.Millisecond*20))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	stats, err := keybase.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, stats.TotalEntries)
+	assert.Empty(t, stats.Namespaces)
+	assert.True(t, stats.EarliestExpiration.IsZero())
+	assert.Positive(t, stats.StorageSize)
+
+	assert.NoError(t, keybase.Put(ctx, "namespace0", "key"))
+	time.Sleep(time.Millisecond * 20)
+	assert.NoError(t, keybase.Put(ctx, "namespace0", "key"))
+	assert.NoError(t, keybase.Put(ctx, "namespace1", "key"))
+	stats, err = keybase.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats.TotalEntries)
+	assert.Equal(t, 2, stats.ActiveEntries)
+	assert.Equal(t, map[string]int{"namespace0": 1, "namespace1": 1}, stats.Namespaces)
+	assert.True(t, stats.EarliestExpiration.Before(stats.LatestExpiration))
+	assert.Zero(t, stats.Prunes.Runs)
+
+	assert.NoError(t, keybase.PruneEntries(ctx))
+	stats, err = keybase.Stats(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.TotalEntries)
+	assert.Equal(t, int64(1), stats.Prunes.Runs)
+	assert.Equal(t, int64(1), stats.Prunes.Entries)
+	assert.False(t, stats.Prunes.LastRun.IsZero())
+
+	assert.NoError(t, keybase.Close())
+	_, err = keybase.Stats(ctx)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestDescribeNamespaces(t *testing.T) {
+	ctx := context.Background()
+	start := time.UnixMilli(1700000000000)
+	clock := &fixedClock{now: start}
+	keybase, err := Open(ctx, WithClock(clock), WithTTL(time.Minute), WithChecksums())
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	namespaces, err := keybase.DescribeNamespaces(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, namespaces)
+
+	assert.NoError(t, keybase.Put(ctx, "namespace1", "key0"))
+	clock.now = start.Add(30 * time.Second)
+	assert.NoError(t, keybase.Put(ctx, "namespace1", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "namespace1", "key1"))
+	assert.NoError(t, keybase.Put(ctx, "namespace0", "key0"))
+	clock.now = start.Add(time.Minute)
+
+	namespaces, err = keybase.DescribeNamespaces(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []NamespaceInfo{
+		{Namespace: "namespace0", TotalEntries: 1, UniqueKeys: 1, ActiveEntries: 1, NextExpiration: start.Add(90 * time.Second)},
+		{Namespace: "namespace1", TotalEntries: 3, UniqueKeys: 2, ActiveEntries: 2, NextExpiration: start.Add(90 * time.Second)},
+	}, namespaces)
+
+	clock.now = start.Add(2 * time.Minute)
+	namespaces, err = keybase.DescribeNamespaces(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, namespaces, 2)
+	assert.Zero(t, namespaces[1].ActiveEntries)
+	assert.True(t, namespaces[1].NextExpiration.IsZero())
+
+	assert.NoError(t, keybase.Close())
+	_, err = keybase.DescribeNamespaces(ctx)
+	assert.ErrorIs(t, err, ErrClosed)
+}
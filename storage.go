@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Create the parent directories of the storage path if they do not exist
+func WithCreateDirs() Option {
+	return Option{
+		key:   "createdirs",
+		value: true,
+	}
+}
+
+// storagePath extracts the filesystem path from the storage option, which
+// may be a plain path or a file: URI with query parameters
+func storagePath(storage string) string {
+	if strings.HasPrefix(storage, "file:") {
+		storage = strings.TrimPrefix(storage, "file:")
+		storage, _, _ = strings.Cut(storage, "?")
+	}
+	return storage
+}
+
+// validateStorage checks that the storage path can hold a database before
+// handing it to the driver, whose errors do not name the actual problem
+func validateStorage(storage string, createDirs bool) error {
+	if isMemory(storage) {
+		return nil
+	}
+	path := storagePath(storage)
+	if path == "" {
+		return fmt.Errorf("%w: empty path", ErrInvalidStorage)
+	}
+	info, err := os.Stat(path)
+	if err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%w: %s is a directory", ErrInvalidStorage, path)
+		}
+		file, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidStorage, err)
+		}
+		_ = file.Close()
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return fmt.Errorf("%w: %w", ErrInvalidStorage, err)
+	}
+	parent := filepath.Dir(path)
+	info, err = os.Stat(parent)
+	if os.IsNotExist(err) && createDirs {
+		err = os.MkdirAll(parent, 0o755)
+		if err != nil {
+			return fmt.Errorf("%w: failed to create directory: %w", ErrInvalidStorage, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidStorage, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%w: %s is not a directory", ErrInvalidStorage, parent)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoragePath(t *testing.T) {
+	assert.Equal(t, "/tmp/keybase.db", storagePath("/tmp/keybase.db"))
+	assert.Equal(t, "/tmp/keybase.db", storagePath("file:/tmp/keybase.db?mode=rwc"))
+}
+
+func TestValidateStorage(t *testing.T) {
+	storageDirectory, _ := os.MkdirTemp(os.TempDir(), "keybase-*")
+	defer os.RemoveAll(storageDirectory)
+	storagePath := path.Join(storageDirectory, "keybase.db")
+	file := path.Join(storageDirectory, "file")
+	assert.NoError(t, os.WriteFile(file, nil, 0o644))
+
+	assert.NoError(t, validateStorage(":memory:", false))
+	assert.NoError(t, validateStorage(storagePath, false))
+	assert.NoError(t, validateStorage(file, false))
+	assert.ErrorIs(t, validateStorage("", false), ErrInvalidStorage)
+	assert.ErrorIs(t, validateStorage(storageDirectory, false), ErrInvalidStorage)
+	assert.ErrorIs(t, validateStorage(path.Join(file, "keybase.db"), false), ErrInvalidStorage)
+	assert.ErrorIs(t, validateStorage(path.Join(storageDirectory, "missing", "keybase.db"), false), ErrInvalidStorage)
+
+	nested := path.Join(storageDirectory, "a", "b", "keybase.db")
+	assert.NoError(t, validateStorage(nested, true))
+	assert.DirExists(t, path.Dir(nested))
+}
+
+func TestCreateDirs(t *testing.T) {
+	storageDirectory, _ := os.MkdirTemp(os.TempDir(), "keybase-*")
+	defer os.RemoveAll(storageDirectory)
+	storagePath := path.Join(storageDirectory, "nested", "keybase.db")
+
+	_, err := Open(context.Background(), WithStorage(storagePath))
+	assert.ErrorIs(t, err, ErrInvalidStorage)
+
+	keybase, err := Open(context.Background(), WithStorage(storagePath), WithCreateDirs())
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "key"))
+	assert.NoError(t, keybase.Close())
+	assert.FileExists(t, storagePath)
+}
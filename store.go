@@ -0,0 +1,125 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"time"
+)
+
+// Store the methods of a keybase, so code built on it can be tested against a
+// fake such as keybasetest.Fake
+type Store interface {
+	Put(ctx context.Context, namespace, key string, opts ...PutOption) error
+	PutUntil(ctx context.Context, namespace, key string, until time.Time) error
+	PutParts(ctx context.Context, namespace string, parts ...string) error
+	PutAsync(ctx context.Context, namespace, key string) <-chan error
+	PutIfAbsent(ctx context.Context, namespace, key string) (bool, error)
+	Seen(ctx context.Context, namespace, key string) (bool, error)
+	PutNew(ctx context.Context, namespace string) (string, error)
+	MatchKey(ctx context.Context, namespace, pattern string, active, unique bool, opts ...QueryOption) ([]string, error)
+	MatchKeyAcross(ctx context.Context, namespaces []string, pattern string, active, unique bool) ([]NamespacedKey, error)
+	MatchKeyAny(ctx context.Context, namespace string, patterns []string, active, unique bool) ([]string, error)
+	MatchParts(ctx context.Context, namespace string, active, unique bool, patterns ...string) ([][]string, error)
+	MatchKeyByTag(ctx context.Context, namespace, tag string) ([]string, error)
+	CountKey(ctx context.Context, namespace, key string, active bool) (int, error)
+	CountMatch(ctx context.Context, namespace, pattern string, active, unique bool) (int, error)
+	GetExpiration(ctx context.Context, namespace, key string) (time.Time, error)
+	GetKeyHistory(ctx context.Context, namespace, key string) ([]KeyVersion, error)
+	GetTTL(ctx context.Context, namespace, key string) (time.Duration, error)
+	ExpireMatch(ctx context.Context, namespace, pattern string, at time.Time) (int, error)
+	GetKeys(ctx context.Context, namespace string, active, unique bool, opts ...QueryOption) ([]string, error)
+	GetEntries(ctx context.Context, namespace string, opts ...EntryOption) ([]Entry, error)
+	CountKeys(ctx context.Context, namespace string, active, unique bool) (int, error)
+	CountKeysByNamespace(ctx context.Context, active, unique bool) (map[string]int, error)
+	DescribeNamespaces(ctx context.Context) ([]NamespaceInfo, error)
+	TopKeys(ctx context.Context, namespace string, n int, active bool) ([]KeyCount, error)
+	SampleKeys(ctx context.Context, namespace string, n int, active bool) ([]string, error)
+	ScanULIDs(ctx context.Context, namespace string, from, to time.Time) ([]string, error)
+	GetKeysInsertedBetween(ctx context.Context, namespace string, from, to time.Time) ([]string, error)
+	Scan(ctx context.Context, namespace, cursor string, count int) ([]string, string, error)
+	SetNamespaceImmutable(ctx context.Context, namespace string) error
+	Fence(ctx context.Context, namespace string) (uint64, error)
+	PutFenced(ctx context.Context, namespace, key string, token uint64) error
+	PutUntilFenced(ctx context.Context, namespace, key string, until time.Time, token uint64) error
+	DeleteMatch(ctx context.Context, namespace, pattern string, dryRun bool) (int, error)
+	GetNamespaces(ctx context.Context, active bool) ([]string, error)
+	MatchNamespaces(ctx context.Context, pattern string, active bool) ([]string, error)
+	CountNamespaces(ctx context.Context, active bool) (int, error)
+	CountEntries(ctx context.Context, active, unique bool) (int, error)
+	PruneEntries(ctx context.Context) error
+	PruneEntriesBatched(ctx context.Context, batchSize int) error
+	PruneNamespace(ctx context.Context, namespace string) error
+	ClearEntries(ctx context.Context) error
+	ClearNamespace(ctx context.Context, namespace string) error
+	CopyNamespace(ctx context.Context, src, dst string, overwriteExpiration bool) (int, error)
+	Tx(ctx context.Context, fn func(tx *KeybaseTx) error) error
+	WithBatch(ctx context.Context) (context.Context, func() error)
+
+	Increment(ctx context.Context, namespace, key string, delta int64) (int64, error)
+	GetCounter(ctx context.Context, namespace, key string) (int64, error)
+	PutField(ctx context.Context, namespace, key, field, value string) error
+	GetField(ctx context.Context, namespace, key, field string) (string, error)
+	GetFields(ctx context.Context, namespace, key string) (map[string]string, error)
+	AcquireLease(ctx context.Context, namespace, key string, ttl time.Duration) (*Lease, error)
+	Claim(ctx context.Context, namespace, key string, ttl time.Duration) (*Claim, error)
+
+	ExpirationHistogram(ctx context.Context, namespace string, buckets int) ([]Bucket, error)
+	KeyFrequency(ctx context.Context, namespace, key string, buckets int, window time.Duration) ([]int, error)
+	ExpirationDecay(ctx context.Context, horizons []time.Duration) (map[time.Duration]int, error)
+	CompactDuplicates(ctx context.Context, policy CompactionPolicy) (int, error)
+	MoveToColdTier(ctx context.Context) (int, error)
+	VerifyChecksums(ctx context.Context) (int, error)
+	VerifyIntegrity(ctx context.Context) error
+	Quarantine(ctx context.Context) ([]QuarantinedEntry, error)
+	GetArchivedKeys(ctx context.Context, namespace string) ([]string, error)
+	PurgeArchive(ctx context.Context, olderThan time.Duration) (int, error)
+	GetDeletedKeys(ctx context.Context, namespace string) ([]string, error)
+	PurgeTombstones(ctx context.Context, olderThan time.Duration) (int, error)
+	QueryAudit(ctx context.Context, filter AuditFilter) ([]AuditRecord, error)
+	ExportNamespaces(ctx context.Context, pattern, dir string, codec Codec) ([]string, error)
+	OpenSnapshot(ctx context.Context) (*Keybase, error)
+	Attach(ctx context.Context, alias, path string) error
+	Detach(ctx context.Context, alias string) error
+	Attached() []string
+	OnExpire(fn func(namespace, key string))
+	OnChange(fn func())
+
+	Reconfigure(ctx context.Context, opts ...Option) error
+	AutoPrune() *Feature
+	DuplicateCompaction() *Feature
+	ChangePolling() *Feature
+	ColdTiering() *Feature
+	ScheduledExport() *Feature
+	PendingMigrations() []Migration
+	Ping(ctx context.Context) error
+	HealthCheck(ctx context.Context) (Health, error)
+	Stats(ctx context.Context) (Stats, error)
+	Compact(ctx context.Context) error
+	QueryStats() map[Op]QueryStats
+	CacheStats() CacheStats
+	SLOStatus() SLOStatus
+	ContentionReport(ctx context.Context, sample time.Duration) (ContentionReport, error)
+	Close() error
+	Shutdown(ctx context.Context) error
+}
+
+var _ Store = (*Keybase)(nil)
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+)
+
+// defaultTable name of the entry table, which the other tables and indexes
+// are named after
+const defaultTable = "keybase"
+
+var (
+	validTable = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// tableNames matches the tables, indexes and triggers named in queries
+	tableNames = regexp.MustCompile(`\bkeybase(_[a-z_]+)?\b|\b[a-z_]+_index\b`)
+)
+
+// Name the entry table, so that several keybases can share a database. The
+// other tables and indexes of the keybase are named after it, and the name
+// must be a valid SQL identifier.
+func WithTableName(name string) Option {
+	return Option{
+		key:   "table",
+		value: name,
+	}
+}
+
+// renameTables rewrites the table, index and trigger names of a query for a
+// keybase with its own table name
+func renameTables(query, table string) string {
+	if table == "" || table == defaultTable {
+		return query
+	}
+	return tableNames.ReplaceAllStringFunc(query, func(name string) string {
+		switch {
+		case name == "keybase_checksum":
+			// the checksum function is registered once for every keybase
+			return name
+		case strings.HasSuffix(name, "_index"):
+			return table + "_" + name
+		}
+		return table + strings.TrimPrefix(name, defaultTable)
+	})
+}
+
+func (db *instrumentedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.querier.ExecContext(ctx, renameTables(query, db.table), args...)
+}
+
+func (db *instrumentedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.querier.QueryContext(ctx, renameTables(query, db.table), args...)
+}
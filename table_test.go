@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenameTables(t *testing.T) {
+	query := "SELECT COALESCE((SELECT value FROM keybase_overflow WHERE ref = keybase.key), keybase.key) FROM keybase WHERE NOT keybase_checksum(namespace, key, expiration)"
+	assert.Equal(t, query, renameTables(query, defaultTable))
+	assert.Equal(t, "SELECT COALESCE((SELECT value FROM sessions_overflow WHERE ref = sessions.key), sessions.key) FROM sessions WHERE NOT keybase_checksum(namespace, key, expiration)", renameTables(query, "sessions"))
+	assert.Equal(t, "CREATE INDEX IF NOT EXISTS sessions_tombstone_namespace_index ON sessions_tombstones(namespace)",
+		renameTables("CREATE INDEX IF NOT EXISTS tombstone_namespace_index ON keybase_tombstones(namespace)", "sessions"))
+	assert.Equal(t, "CREATE TRIGGER IF NOT EXISTS sessions_immutable_insert BEFORE INSERT ON sessions",
+		renameTables("CREATE TRIGGER IF NOT EXISTS keybase_immutable_insert BEFORE INSERT ON keybase", "sessions"))
+}
+
+func TestWithTableName(t *testing.T) {
+	ctx := context.Background()
+	storageDirectory, _ := os.MkdirTemp(os.TempDir(), "keybase-*")
+	defer os.RemoveAll(storageDirectory)
+	storagePath := path.Join(storageDirectory, "keybase.db")
+
+	for _, name := range []string{"", "1st", "keybase-sessions", "sessions; DROP TABLE keybase"} {
+		_, err := Open(ctx, WithStorage(storagePath), WithTableName(name))
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+	}
+
+	keybase, err := Open(ctx, WithStorage(storagePath))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	sessions, err := Open(ctx, WithStorage(storagePath), WithTableName("sessions"), WithChecksums(), WithOverflow(32))
+	assert.NoError(t, err)
+	defer sessions.Close()
+
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+	assert.NoError(t, sessions.Put(ctx, "namespace", "key1"))
+	assert.NoError(t, sessions.SetNamespaceImmutable(ctx, "namespace"))
+	assert.ErrorIs(t, sessions.Put(ctx, "namespace", "key1"), ErrImmutable)
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key1"))
+
+	keys, err := keybase.GetKeys(ctx, "namespace", true, true, OrderBy(KeyAscending))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key0", "key1"}, keys)
+	keys, err = sessions.GetKeys(ctx, "namespace", true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key1"}, keys)
+	assert.NoError(t, sessions.ClearEntries(ctx))
+	count, err := keybase.CountEntries(ctx, true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	tables, err := (&dbtx{query: "SELECT name FROM sqlite_master WHERE name LIKE 'sessions%' ORDER BY name"}).queryValues(ctx, keybase.db)
+	assert.NoError(t, err)
+	assert.Contains(t, tables, "sessions")
+	assert.Contains(t, tables, "sessions_schema")
+	assert.Contains(t, tables, "sessions_namespace_index")
+	assert.Contains(t, tables, "sessions_immutable_insert")
+	assert.NoError(t, sessions.Close())
+
+	sessions, err = Open(ctx, WithStorage(storagePath), WithTableName("sessions"), WithMigrationPolicy(MigrateFail))
+	assert.NoError(t, err)
+	assert.NoError(t, sessions.Put(ctx, "namespace", "key2"))
+	assert.NoError(t, sessions.Close())
+}
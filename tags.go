@@ -0,0 +1,75 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+)
+
+// PutOption configures a single Put
+type PutOption struct {
+	key   string
+	value any
+}
+
+type putOptions struct {
+	tags []string
+}
+
+// Tag the key, with tags such as "source=api", so it can be found with
+// MatchKeyByTag while it has active entries
+func WithTags(tags ...string) PutOption {
+	return PutOption{
+		key:   "tags",
+		value: tags,
+	}
+}
+
+func parsePutOptions(opts ...PutOption) putOptions {
+	put := putOptions{}
+	for _, opt := range opts {
+		switch opt.key {
+		case "tags":
+			put.tags = append(put.tags, opt.value.([]string)...)
+		}
+	}
+	return put
+}
+
+// MatchKeyByTag collects the keys of a namespace with active entries that
+// were put with the tag
+func (k *Keybase) MatchKeyByTag(ctx context.Context, namespace, tag string) ([]string, error) {
+	timestamp := k.clock.Now().UnixMilli()
+	var keys []string
+	err := k.read(ctx, OpMatchKeyByTag, []string{namespace}, func(ctx context.Context) (err error) {
+		keys, err = newMatchKeyByTagQuery(k.params(QueryParams{Namespace: namespace, Tag: tag, Timestamp: timestamp})).queryValues(ctx, k.conn)
+		if err != nil {
+			return err
+		}
+		keys, err = k.decodeAll(keys)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.MatchKeyByTag: failed to query database: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTags(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{{}, {WithChecksums(), WithOverflow(8)}, {WithEncryption(make([]byte, 32))}} {
+		buffer := bytes.Buffer{}
+		clock := &fixedClock{now: time.Now()}
+		keybase, err := Open(ctx, append(opts, WithClock(clock), WithTTL(time.Minute), WithJournal(&buffer))...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		assert.NoError(t, keybase.Put(ctx, "namespace", "key0", WithTags("source=api", "region=eu")))
+		assert.NoError(t, keybase.Put(ctx, "namespace", "overflowing1", WithTags("source=api")))
+		batch, flush := keybase.WithBatch(ctx)
+		assert.NoError(t, keybase.Put(batch, "namespace", "key2", WithTags("region=eu")))
+		assert.NoError(t, keybase.Put(batch, "namespace", "key3"))
+		assert.NoError(t, flush())
+		assert.NoError(t, keybase.Put(ctx, "other", "key4", WithTags("source=api")))
+		assert.ErrorIs(t, keybase.Put(ctx, "namespace", "key5", WithTags("")), ErrInvalidArgument)
+
+		keys, err := keybase.MatchKeyByTag(ctx, "namespace", "source=api")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"key0", "overflowing1"}, keys)
+		keys, err = keybase.MatchKeyByTag(ctx, "namespace", "region=eu")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"key0", "key2"}, keys)
+		keys, err = keybase.MatchKeyByTag(ctx, "namespace", "missing")
+		assert.NoError(t, err)
+		assert.Empty(t, keys)
+
+		replayed, err := Open(ctx, opts...)
+		assert.NoError(t, err)
+		defer replayed.Close()
+		assert.NoError(t, ReplayJournal(ctx, replayed, &buffer))
+		keys, err = replayed.MatchKeyByTag(ctx, "namespace", "region=eu")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"key0", "key2"}, keys)
+
+		assert.NoError(t, keybase.Tx(ctx, func(tx *KeybaseTx) error {
+			return tx.Delete(ctx, "namespace", "key2")
+		}))
+		assert.NoError(t, keybase.ClearNamespace(ctx, "other"))
+		clock.now = clock.now.Add(time.Minute)
+		keys, err = keybase.MatchKeyByTag(ctx, "namespace", "source=api")
+		assert.NoError(t, err)
+		assert.Empty(t, keys)
+		assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+		keys, err = keybase.MatchKeyByTag(ctx, "namespace", "source=api")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key0"}, keys)
+
+		assert.NoError(t, keybase.PruneEntries(ctx))
+		count, err := keybase.CountEntries(ctx, false, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		var tags int
+		assert.NoError(t, keybase.db.QueryRow("SELECT COUNT(*) FROM keybase_tags").Scan(&tags))
+		assert.Equal(t, 2, tags)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type coldTierOption struct {
+	threshold time.Duration
+	interval  time.Duration
+}
+
+// Periodically move entries that expired more than threshold ago into an
+// unindexed cold table, which is only read by queries that include inactive entries
+func WithColdTier(threshold, interval time.Duration) Option {
+	return Option{
+		key: "coldtier",
+		value: coldTierOption{
+			threshold: threshold,
+			interval:  interval,
+		},
+	}
+}
+
+// MoveToColdTier moves entries that expired longer ago than the cold tier
+// threshold out of the hot table, returning the number of entries moved
+func (k *Keybase) MoveToColdTier(ctx context.Context) (int, error) {
+	timestamp := k.clock.Now().UnixMilli()
+	moved := 0
+	err := k.write(ctx, OpMoveToColdTier, nil, func(ctx context.Context) error {
+		params := k.params(QueryParams{Timestamp: timestamp, Threshold: k.threshold.Milliseconds()})
+		return k.transaction(ctx, func(db querier) error {
+			err := newCopyToColdTierQuery(params).queryExec(ctx, db)
+			if err != nil {
+				return err
+			}
+			rows, err := newMoveToColdTierQuery(params).queryRowsAffected(ctx, db)
+			moved = int(rows)
+			return err
+		})
+	})
+	k.record(ctx, JournalEntry{Op: OpMoveToColdTier}, err)
+	if err != nil {
+		return 0, fmt.Errorf("keybase.MoveToColdTier: failed to move entries: %w", err)
+	}
+	return moved, nil
+}
+
+// ColdTiering handle for the background tiering feature, which should only be
+// started if cold entries were enabled with WithColdTier
+func (k *Keybase) ColdTiering() *Feature {
+	return k.tiering
+}
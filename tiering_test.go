@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestColdTier(t *testing.T) {
+	keybase, err := Open(context.Background(), WithTTL(time.Millisecond), WithColdTier(time.Millisecond*20, time.Hour))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.True(t, keybase.ColdTiering().Status().Running)
+
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "key0"))
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "key0"))
+	time.Sleep(time.Millisecond * 25)
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "key1"))
+	time.Sleep(time.Millisecond * 5)
+	assert.NoError(t, keybase.Reconfigure(context.Background(), WithTTL(time.Minute)))
+	assert.NoError(t, keybase.Put(context.Background(), "namespace", "key2"))
+
+	moved, err := keybase.MoveToColdTier(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, moved)
+
+	keys, err := keybase.GetKeys(context.Background(), "namespace", false, false)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"key0", "key0", "key1", "key2"}, keys)
+	keys, err = keybase.GetKeys(context.Background(), "namespace", true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key2"}, keys)
+	count, err := keybase.CountEntries(context.Background(), false, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	count, err = keybase.CountKey(context.Background(), "namespace", "key0", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	assert.NoError(t, keybase.PruneEntries(context.Background()))
+	count, err = keybase.CountEntries(context.Background(), false, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(0))
+	defer cancel()
+	_, err = keybase.MoveToColdTier(ctx)
+	assert.Error(t, err)
+}
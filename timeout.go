@@ -0,0 +1,56 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"time"
+)
+
+type queryTimeoutKey struct{}
+
+// Bound every query with a timeout, even when the caller's context has no
+// deadline, so that a runaway scan cannot hold a connection indefinitely. A
+// zero timeout disables it.
+func WithQueryTimeout(timeout time.Duration) Option {
+	return Option{
+		key:   "querytimeout",
+		value: timeout,
+	}
+}
+
+// withQueryTimeout sets the timeout applied to each query issued with ctx
+func withQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	if timeout <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, queryTimeoutKey{}, timeout)
+}
+
+// queryContext derives the context of a single query, bounded by the query
+// timeout if one was set
+func queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout, ok := ctx.Value(queryTimeoutKey{}).(time.Duration)
+	if !ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryContext(t *testing.T) {
+	ctx, cancel := queryContext(context.Background())
+	defer cancel()
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+
+	assert.Equal(t, context.Background(), withQueryTimeout(context.Background(), 0))
+	ctx, cancel = queryContext(withQueryTimeout(context.Background(), time.Minute))
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+}
+
+func TestWithQueryTimeout(t *testing.T) {
+	ctx := context.Background()
+	_, err := Open(ctx, WithQueryTimeout(-time.Second))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	keybase, err := Open(ctx, WithQueryTimeout(time.Minute))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+	count, err := keybase.CountKey(ctx, "namespace", "key", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	keybase, err = Open(ctx, WithStorage(filepath.Join(t.TempDir(), "keybase.db")), WithQueryTimeout(time.Nanosecond))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.ErrorIs(t, keybase.Put(ctx, "namespace", "key"), context.DeadlineExceeded)
+	_, err = keybase.GetKeys(ctx, "namespace", true, true)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
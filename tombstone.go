@@ -0,0 +1,73 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Keep the entries removed by deleting a key as tombstones instead of
+// removing them, so that deletions can be audited
+func WithTombstones() Option {
+	return Option{
+		key: "tombstones",
+	}
+}
+
+// GetDeletedKeys collects the keys of a namespace that were deleted while
+// tombstones were kept
+func (k *Keybase) GetDeletedKeys(ctx context.Context, namespace string) ([]string, error) {
+	var keys []string
+	err := k.read(ctx, OpGetDeletedKeys, []string{namespace}, func(ctx context.Context) (err error) {
+		keys, err = newGetDeletedKeysQuery(k.params(QueryParams{Namespace: namespace})).queryValues(ctx, k.conn)
+		if err != nil {
+			return err
+		}
+		keys, err = k.decodeAll(keys)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.GetDeletedKeys: failed to query database: %w", err)
+	}
+	return keys, nil
+}
+
+// PurgeTombstones removes tombstones of entries that were deleted longer ago
+// than olderThan, returning the number of tombstones removed
+func (k *Keybase) PurgeTombstones(ctx context.Context, olderThan time.Duration) (int, error) {
+	timestamp := k.clock.Now().UnixMilli()
+	purged := 0
+	err := k.write(ctx, OpPurgeTombstones, nil, func(ctx context.Context) error {
+		rows, err := newPurgeTombstonesQuery(QueryParams{Timestamp: timestamp, Threshold: olderThan.Milliseconds()}).queryRowsAffected(ctx, k.conn)
+		purged = int(rows)
+		if err != nil {
+			return err
+		}
+		return newPruneOverflowQuery(QueryParams{}).queryExec(withOperation(ctx, OpPruneOverflow), k.conn)
+	})
+	k.record(ctx, JournalEntry{Op: OpPurgeTombstones, Interval: olderThan}, err)
+	if err != nil {
+		return 0, fmt.Errorf("keybase.PurgeTombstones: failed to purge tombstones: %w", err)
+	}
+	return purged, nil
+}
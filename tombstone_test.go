@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTombstones(t *testing.T) {
+	ctx := context.Background()
+	long := strings.Repeat("k", 64)
+	clock := &fixedClock{now: time.UnixMilli(1700000000000)}
+	keybase, err := Open(ctx, WithClock(clock), WithTombstones(), WithOverflow(32), WithEncryption(make([]byte, 32)))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+	assert.NoError(t, keybase.Put(ctx, "namespace", long))
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key1"))
+	assert.NoError(t, keybase.Tx(ctx, func(tx *KeybaseTx) error {
+		err := tx.Delete(ctx, "namespace", "key0")
+		if err != nil {
+			return err
+		}
+		return tx.Delete(ctx, "namespace", long)
+	}))
+
+	keys, err := keybase.GetKeys(ctx, "namespace", false, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key1"}, keys)
+	keys, err = keybase.GetDeletedKeys(ctx, "namespace")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"key0", long}, keys)
+	keys, err = keybase.GetDeletedKeys(ctx, "other")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	clock.now = clock.now.Add(time.Minute)
+	purged, err := keybase.PurgeTombstones(ctx, time.Hour)
+	assert.NoError(t, err)
+	assert.Zero(t, purged)
+	purged, err = keybase.PurgeTombstones(ctx, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, purged)
+	keys, err = keybase.GetDeletedKeys(ctx, "namespace")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	keybase, err = Open(ctx)
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key"))
+	assert.NoError(t, keybase.Tx(ctx, func(tx *KeybaseTx) error {
+		return tx.Delete(ctx, "namespace", "key")
+	}))
+	keys, err = keybase.GetDeletedKeys(ctx, "namespace")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	assert.NoError(t, keybase.Close())
+	_, err = keybase.GetDeletedKeys(ctx, "namespace")
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = keybase.PurgeTombstones(ctx, 0)
+	assert.ErrorIs(t, err, ErrClosed)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// KeyCount number of entries inserted for a key
+type KeyCount struct {
+	Key   string
+	Count int
+}
+
+// TopKeys collects the n keys of a namespace with the most entries, most
+// frequent first, which helps spot hot keys and abusive clients. When keys
+// are encrypted, keys tied at the cutoff are picked by their encrypted form.
+func (k *Keybase) TopKeys(ctx context.Context, namespace string, n int, active bool) ([]KeyCount, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("keybase.TopKeys: %w: n must be positive", ErrInvalidArgument)
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	top := []KeyCount{}
+	err := k.read(ctx, OpTopKeys, []string{namespace}, func(ctx context.Context) error {
+		err := newTopKeysQuery(k.params(QueryParams{Namespace: namespace, Active: active, Limit: n, Timestamp: timestamp})).queryRows(ctx, k.conn, func(rows *sql.Rows) error {
+			var count KeyCount
+			err := rows.Scan(&count.Key, &count.Count)
+			if err == nil {
+				count.Key, err = k.decode(count.Key)
+			}
+			top = append(top, count)
+			return err
+		})
+		if err != nil || k.cipher == nil {
+			return err
+		}
+		// ties are broken by the encrypted key, so the selected keys are
+		// sorted again by their plain text
+		slices.SortStableFunc(top, func(a, b KeyCount) int {
+			if a.Count != b.Count {
+				return b.Count - a.Count
+			}
+			return strings.Compare(a.Key, b.Key)
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.TopKeys: failed to query database: %w", err)
+	}
+	return top, nil
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopKeys(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{nil, {WithEncryption(make([]byte, 32))}} {
+		clock := &fixedClock{now: time.Now()}
+		keybase, err := Open(ctx, append(opts, WithClock(clock), WithTTL(time.Minute))...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		_, err = keybase.TopKeys(ctx, "namespace", 0, true)
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+
+		top, err := keybase.TopKeys(ctx, "namespace", 2, true)
+		assert.NoError(t, err)
+		assert.Empty(t, top)
+
+		for _, key := range []string{"cold", "cold", "cold", "hot", "warm", "hot"} {
+			assert.NoError(t, keybase.Put(ctx, "namespace", key))
+		}
+		assert.NoError(t, keybase.Put(ctx, "other", "cold"))
+		clock.now = clock.now.Add(2 * time.Minute)
+		for _, key := range []string{"hot", "warm", "hot"} {
+			assert.NoError(t, keybase.Put(ctx, "namespace", key))
+		}
+
+		top, err = keybase.TopKeys(ctx, "namespace", 2, false)
+		assert.NoError(t, err)
+		assert.Equal(t, []KeyCount{{"hot", 4}, {"cold", 3}}, top)
+		top, err = keybase.TopKeys(ctx, "namespace", 5, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []KeyCount{{"hot", 2}, {"warm", 1}}, top)
+	}
+}
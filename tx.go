@@ -0,0 +1,140 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// KeybaseTx operations that run within the transaction started by Tx
+type KeybaseTx struct {
+	keybase *Keybase
+	db      querier
+	journal []JournalEntry
+}
+
+// Tx runs fn in a single transaction, committing if fn returns nil and
+// rolling back if it returns an error. The keybase itself must not be used
+// from fn, since it may be waiting on the same lock or connection.
+func (k *Keybase) Tx(ctx context.Context, fn func(tx *KeybaseTx) error) error {
+	tx := &KeybaseTx{keybase: k}
+	err := k.write(ctx, OpTx, nil, func(ctx context.Context) error {
+		return k.transaction(ctx, func(db querier) error {
+			tx.db = db
+			return fn(tx)
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("keybase.Tx: transaction failed: %w", err)
+	}
+	// only committed operations are journaled, since a rolled back
+	// operation replayed on its own would succeed
+	for _, entry := range tx.journal {
+		k.record(ctx, entry, nil)
+	}
+	return nil
+}
+
+// Put inserts new value within the transaction
+func (tx *KeybaseTx) Put(ctx context.Context, namespace, key string) error {
+	err := tx.put(ctx, namespace, key, time.Time{})
+	if err != nil {
+		return fmt.Errorf("keybase.KeybaseTx.Put: failed to insert key: %w", err)
+	}
+	return nil
+}
+
+// PutUntil inserts new value that expires at the given time within the
+// transaction
+func (tx *KeybaseTx) PutUntil(ctx context.Context, namespace, key string, until time.Time) error {
+	err := tx.put(ctx, namespace, key, until)
+	if err != nil {
+		return fmt.Errorf("keybase.KeybaseTx.PutUntil: failed to insert key: %w", err)
+	}
+	return nil
+}
+
+func (tx *KeybaseTx) put(ctx context.Context, namespace, key string, until time.Time) error {
+	k := tx.keybase
+	err := k.authorize(ctx, OpPut, []string{namespace})
+	if err != nil {
+		return err
+	}
+	ctx = withOperation(ctx, OpPut)
+	now := k.clock.Now()
+	expiration := k.expiration(now)
+	if !until.IsZero() {
+		expiration = until.UnixMilli()
+	}
+	params := k.params(QueryParams{Namespace: namespace, Key: key, Expiration: expiration, Timestamp: now.UnixMilli()})
+	err = k.insertWith(ctx, tx.db, key, func(db querier) error {
+		err := newPutQuery(params).queryExec(ctx, db)
+		if err != nil {
+			return err
+		}
+		return k.versioned(ctx, db, params)
+	})
+	if err != nil {
+		return err
+	}
+	tx.journal = append(tx.journal, putEntry(namespace, key, now, until))
+	return nil
+}
+
+// Delete removes every entry of a key within the transaction, including
+// entries in the cold tier. With WithTombstones, the entries are kept as
+// tombstones instead.
+func (tx *KeybaseTx) Delete(ctx context.Context, namespace, key string) error {
+	k := tx.keybase
+	err := k.authorize(ctx, OpDeleteKey, []string{namespace})
+	if err != nil {
+		return fmt.Errorf("keybase.KeybaseTx.Delete: %w", err)
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	err = newDeleteKeyQuery(k.params(QueryParams{Namespace: namespace, Key: key, Timestamp: timestamp})).queryExec(withOperation(ctx, OpDeleteKey), tx.db)
+	if err != nil {
+		return fmt.Errorf("keybase.KeybaseTx.Delete: failed to delete key: %w", err)
+	}
+	tx.journal = append(tx.journal, JournalEntry{Op: OpDeleteKey, Namespace: namespace, Key: key})
+	return nil
+}
+
+// Match searches the namespace for keys matching the pattern within the
+// transaction, observing its uncommitted changes
+func (tx *KeybaseTx) Match(ctx context.Context, namespace, pattern string, active, unique bool) ([]string, error) {
+	k := tx.keybase
+	err := ValidatePattern(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("keybase.KeybaseTx.Match: %w", err)
+	}
+	err = k.authorize(ctx, OpMatchKey, []string{namespace})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.KeybaseTx.Match: %w", err)
+	}
+	timestamp := k.clock.Now().UnixMilli()
+	keys, err := k.match(withOperation(ctx, OpMatchKey), tx.db, k.params(QueryParams{Namespace: namespace, Pattern: pattern, Active: active, Unique: unique, Timestamp: timestamp}))
+	if err != nil {
+		return nil, fmt.Errorf("keybase.KeybaseTx.Match: failed to query database: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTx(t *testing.T) {
+	ctx := context.Background()
+	buffer := bytes.Buffer{}
+	keybase, err := Open(ctx, WithJournal(&buffer))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	assert.NoError(t, keybase.Put(ctx, "namespace", "key0"))
+
+	err = keybase.Tx(ctx, func(tx *KeybaseTx) error {
+		assert.NoError(t, tx.Put(ctx, "namespace", "key1"))
+		assert.NoError(t, tx.Delete(ctx, "namespace", "key0"))
+		keys, err := tx.Match(ctx, "namespace", "key*", true, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key1"}, keys)
+		_, err = tx.Match(ctx, "namespace", "", true, true)
+		assert.ErrorIs(t, err, ErrInvalidPattern)
+		return nil
+	})
+	assert.NoError(t, err)
+	keys, err := keybase.MatchKey(ctx, "namespace", "key*", true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key1"}, keys)
+
+	failure := errors.New("some error")
+	err = keybase.Tx(ctx, func(tx *KeybaseTx) error {
+		assert.NoError(t, tx.Put(ctx, "namespace", "key2"))
+		assert.NoError(t, tx.Delete(ctx, "namespace", "key1"))
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+	keys, err = keybase.MatchKey(ctx, "namespace", "key*", true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key1"}, keys)
+
+	replayed, err := Open(ctx)
+	assert.NoError(t, err)
+	defer replayed.Close()
+	assert.NoError(t, ReplayJournal(ctx, replayed, &buffer))
+	keys, err = replayed.MatchKey(ctx, "namespace", "key*", true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key1"}, keys)
+
+	assert.NoError(t, keybase.Close())
+	assert.ErrorIs(t, keybase.Tx(ctx, func(*KeybaseTx) error { return nil }), ErrClosed)
+}
+
+func TestTxEncryption(t *testing.T) {
+	ctx := context.Background()
+	keybase, err := Open(ctx, WithEncryption(make([]byte, 32)), WithMaxEntries(1, RejectWrites))
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	err = keybase.Tx(ctx, func(tx *KeybaseTx) error {
+		assert.NoError(t, tx.Put(ctx, "namespace", "key0"))
+		keys, err := tx.Match(ctx, "namespace", "key?", true, false)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"key0"}, keys)
+		return tx.Put(ctx, "namespace", "key1")
+	})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	count, err := keybase.CountEntries(ctx, false, false)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
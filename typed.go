@@ -0,0 +1,119 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+)
+
+// valueField reserved field holding the encoded value of a TypedKeybase,
+// hidden from GetFields
+const valueField = "\x1fvalue"
+
+// Codec encodes and decodes the values stored by a TypedKeybase
+type Codec interface {
+	Encode(v any) ([]byte, error)
+	Decode(data []byte, v any) error
+}
+
+// JSONCodec encodes values as JSON
+type JSONCodec struct{}
+
+// GobCodec encodes values with encoding/gob
+type GobCodec struct{}
+
+// TypedKeybase stores values of type T alongside keys, sharing the expiration
+// of the key's fields
+type TypedKeybase[T any] struct {
+	keybase *Keybase
+	codec   Codec
+}
+
+// Encode encodes v as JSON
+func (JSONCodec) Encode(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Decode decodes JSON data into v
+func (JSONCodec) Decode(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// Encode encodes v with gob
+func (GobCodec) Encode(v any) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	err := gob.NewEncoder(&buffer).Encode(v)
+	return buffer.Bytes(), err
+}
+
+// Decode decodes gob data into v
+func (GobCodec) Decode(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// NewTyped wraps a keybase to store values of type T with the given codec,
+// using JSON if codec is nil
+func NewTyped[T any](keybase *Keybase, codec Codec) *TypedKeybase[T] {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	return &TypedKeybase[T]{
+		keybase: keybase,
+		codec:   codec,
+	}
+}
+
+// Keybase underlying keybase
+func (t *TypedKeybase[T]) Keybase() *Keybase {
+	return t.keybase
+}
+
+// PutValue encodes and stores the value of a key
+func (t *TypedKeybase[T]) PutValue(ctx context.Context, namespace, key string, v T) error {
+	data, err := t.codec.Encode(v)
+	if err != nil {
+		return fmt.Errorf("keybase.PutValue: failed to encode value: %w", err)
+	}
+	err = t.keybase.PutField(ctx, namespace, key, valueField, string(data))
+	if err != nil {
+		return fmt.Errorf("keybase.PutValue: %w", err)
+	}
+	return nil
+}
+
+// GetValue loads and decodes the value of a key, returning ErrNotFound if the
+// key has no active value
+func (t *TypedKeybase[T]) GetValue(ctx context.Context, namespace, key string) (T, error) {
+	var v T
+	data, err := t.keybase.GetField(ctx, namespace, key, valueField)
+	if err != nil {
+		return v, fmt.Errorf("keybase.GetValue: %w", err)
+	}
+	err = t.codec.Decode([]byte(data), &v)
+	if err != nil {
+		return v, fmt.Errorf("keybase.GetValue: failed to decode value: %w", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type typedValue struct {
+	Name  string
+	Count int
+}
+
+type failingCodec struct{}
+
+func (failingCodec) Encode(any) ([]byte, error) {
+	return nil, errors.New("encode failed")
+}
+
+func (failingCodec) Decode([]byte, any) error {
+	return errors.New("decode failed")
+}
+
+func TestTypedKeybase(t *testing.T) {
+	keybase, err := Open(context.Background())
+	assert.NoError(t, err)
+	defer keybase.Close()
+
+	for _, codec := range []Codec{nil, JSONCodec{}, GobCodec{}} {
+		typed := NewTyped[typedValue](keybase, codec)
+		assert.Equal(t, keybase, typed.Keybase())
+		_, err = typed.GetValue(context.Background(), "namespace", "missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		assert.NoError(t, typed.PutValue(context.Background(), "namespace", "key", typedValue{Name: "name", Count: 3}))
+		value, err := typed.GetValue(context.Background(), "namespace", "key")
+		assert.NoError(t, err)
+		assert.Equal(t, typedValue{Name: "name", Count: 3}, value)
+	}
+
+	assert.NoError(t, keybase.PutField(context.Background(), "namespace", "key", "field", "value"))
+	fields, err := keybase.GetFields(context.Background(), "namespace", "key")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"field": "value"}, fields)
+
+	failing := NewTyped[typedValue](keybase, failingCodec{})
+	assert.Error(t, failing.PutValue(context.Background(), "namespace", "key", typedValue{}))
+	_, err = failing.GetValue(context.Background(), "namespace", "key")
+	assert.Error(t, err)
+
+	assert.NoError(t, keybase.Close())
+	assert.ErrorIs(t, NewTyped[int](keybase, nil).PutValue(context.Background(), "namespace", "key", 1), ErrClosed)
+}
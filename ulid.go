@@ -0,0 +1,134 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulid 128-bit identifier made of a 48-bit millisecond timestamp followed by
+// 80 random bits, held as two halves
+type ulid struct {
+	hi uint64
+	lo uint64
+}
+
+// ulidGenerator issues ULIDs that sort in the order they were generated, by
+// incrementing the previous ULID when the clock has not moved forward
+type ulidGenerator struct {
+	mu   *sync.Mutex
+	last ulid
+}
+
+var ulids = &ulidGenerator{mu: new(sync.Mutex)}
+
+func ulidBound(t time.Time) ulid {
+	return ulid{hi: uint64(t.UnixMilli()) << 16}
+}
+
+func (u ulid) String() string {
+	encoded := make([]byte, 26)
+	for index := len(encoded) - 1; index >= 0; index-- {
+		encoded[index] = crockford[u.lo&31]
+		u.lo = u.lo>>5 | u.hi<<59
+		u.hi >>= 5
+	}
+	return string(encoded)
+}
+
+func (g *ulidGenerator) next(now time.Time) (string, error) {
+	random := make([]byte, 10)
+	_, err := rand.Read(random)
+	if err != nil {
+		return "", err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id := ulidBound(now)
+	id.hi |= uint64(binary.BigEndian.Uint16(random))
+	id.lo = binary.BigEndian.Uint64(random[2:])
+	if id.hi>>16 <= g.last.hi>>16 {
+		id = g.last
+		id.lo++
+		if id.lo == 0 {
+			id.hi++
+		}
+	}
+	g.last = id
+	return id.String(), nil
+}
+
+// PutNew inserts a newly generated ULID key and returns it. Keys generated by
+// the same process sort in the order they were issued, so they can be scanned
+// by time with ScanULIDs.
+func (k *Keybase) PutNew(ctx context.Context, namespace string) (string, error) {
+	key, err := ulids.next(k.clock.Now())
+	if err != nil {
+		return "", fmt.Errorf("keybase.PutNew: failed to generate key: %w", err)
+	}
+	err = k.put(ctx, namespace, key, time.Time{}, nil)
+	if err != nil {
+		return "", fmt.Errorf("keybase.PutNew: failed to insert key: %w", err)
+	}
+	return key, nil
+}
+
+// ScanULIDs collects the active ULID keys of a namespace that were generated
+// at or after from and before to, in ascending order
+func (k *Keybase) ScanULIDs(ctx context.Context, namespace string, from, to time.Time) ([]string, error) {
+	timestamp := k.clock.Now().UnixMilli()
+	lower, upper := ulidBound(from).String(), ulidBound(to).String()
+	var keys []string
+	err := k.read(ctx, OpScanULIDs, []string{namespace}, func(ctx context.Context) (err error) {
+		params := k.params(QueryParams{Namespace: namespace, Lower: lower, Upper: upper, Active: true, Unique: true, Timestamp: timestamp})
+		if k.cipher == nil {
+			keys, err = newScanRangeQuery(params).queryValues(ctx, k.conn)
+			return err
+		}
+		// encrypted keys do not sort like their plain text, so they are
+		// filtered and sorted here
+		keys, err = newGetKeysQuery(params).queryValues(ctx, k.conn)
+		if err != nil {
+			return err
+		}
+		keys, err = k.decodeAll(keys)
+		if err != nil {
+			return err
+		}
+		keys = slices.DeleteFunc(keys, func(key string) bool {
+			return key < lower || key >= upper
+		})
+		slices.Sort(keys)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("keybase.ScanULIDs: failed to query database: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestULID(t *testing.T) {
+	assert.Equal(t, "00000000000000000000000000", ulid{}.String())
+	assert.Equal(t, "7ZZZZZZZZZZZZZZZZZZZZZZZZZ", ulid{hi: ^uint64(0), lo: ^uint64(0)}.String())
+	assert.Equal(t, "01ARYZ6S41", ulidBound(time.UnixMilli(1469918176385)).String()[:10])
+
+	generator := &ulidGenerator{mu: new(sync.Mutex)}
+	now := time.Now()
+	previous := ""
+	for i := 0; i < 100; i++ {
+		id, err := generator.next(now)
+		assert.NoError(t, err)
+		assert.Len(t, id, 26)
+		assert.Greater(t, id, previous)
+		previous = id
+	}
+	id, err := generator.next(now.Add(-time.Second))
+	assert.NoError(t, err)
+	assert.Greater(t, id, previous)
+}
+
+func TestPutNew(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]Option{nil, {WithEncryption(make([]byte, 32))}} {
+		keybase, err := Open(ctx, opts...)
+		assert.NoError(t, err)
+		defer keybase.Close()
+
+		start := time.Now()
+		keys := []string{}
+		for i := 0; i < 5; i++ {
+			key, err := keybase.PutNew(ctx, "tokens")
+			assert.NoError(t, err)
+			keys = append(keys, key)
+		}
+		_, err = keybase.PutNew(ctx, "other")
+		assert.NoError(t, err)
+		assert.NoError(t, keybase.Put(ctx, "tokens", "not-a-ulid"))
+
+		count, err := keybase.CountKey(ctx, "tokens", keys[0], true)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		scanned, err := keybase.ScanULIDs(ctx, "tokens", start.Add(-time.Millisecond), time.Now().Add(time.Millisecond))
+		assert.NoError(t, err)
+		assert.Equal(t, keys, scanned)
+		scanned, err = keybase.ScanULIDs(ctx, "tokens", start.Add(-time.Hour), start.Add(-time.Minute))
+		assert.NoError(t, err)
+		assert.Empty(t, scanned)
+	}
+
+	keybase, err := Open(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, keybase.Close())
+	_, err = keybase.PutNew(ctx, "tokens")
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = keybase.ScanULIDs(ctx, "tokens", time.Time{}, time.Now())
+	assert.ErrorIs(t, err, ErrClosed)
+}
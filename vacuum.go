@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// auto_vacuum mode in which free pages are only reclaimed on request
+const incrementalVacuum = 2
+
+// Compact the database after a prune once the fraction of its pages that are
+// free exceeds threshold, between 0 and 1
+func WithAutoCompact(threshold float64) Option {
+	return Option{
+		key:   "autocompact",
+		value: threshold,
+	}
+}
+
+// Compact returns the free pages left by removed entries to the filesystem,
+// using an incremental vacuum when the database has auto_vacuum set to
+// incremental and a full VACUUM otherwise
+func (k *Keybase) Compact(ctx context.Context) error {
+	err := k.write(ctx, OpCompact, nil, func(ctx context.Context) error {
+		_, _, mode, err := k.freePages(ctx)
+		if err != nil {
+			return err
+		}
+		return k.compactWith(ctx, mode)
+	})
+	if err != nil {
+		return fmt.Errorf("keybase.Compact: failed to vacuum database: %w", err)
+	}
+	return nil
+}
+
+// autoCompact compacts the database when the fraction of free pages exceeds
+// the threshold set by WithAutoCompact
+func (k *Keybase) autoCompact(ctx context.Context) error {
+	if k.vacuum == 0 {
+		return nil
+	}
+	err := k.write(ctx, OpCompact, nil, func(ctx context.Context) error {
+		free, pages, mode, err := k.freePages(ctx)
+		if err != nil || pages == 0 || float64(free)/float64(pages) <= k.vacuum {
+			return err
+		}
+		return k.compactWith(ctx, mode)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to compact database: %w", err)
+	}
+	return nil
+}
+
+func (k *Keybase) freePages(ctx context.Context) (free, pages, mode int, err error) {
+	err = newFreePagesQuery().queryRows(withOperation(ctx, OpFreePages), k.conn, func(rows *sql.Rows) error {
+		return rows.Scan(&free, &pages, &mode)
+	})
+	return free, pages, mode, err
+}
+
+func (k *Keybase) compactWith(ctx context.Context, mode int) error {
+	if mode == incrementalVacuum {
+		return newIncrementalVacuumQuery().queryExec(withOperation(ctx, OpIncrementalVacuum), k.conn)
+	}
+	return newVacuumQuery().queryExec(withOperation(ctx, OpVacuum), k.conn)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Maxtek Consulting
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package keybase
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompact(t *testing.T) {
+	ctx := context.Background()
+	fill := func(keybase *Keybase, until time.Time) {
+		err := keybase.Tx(ctx, func(tx *KeybaseTx) error {
+			for index := range 500 {
+				err := tx.PutUntil(ctx, "namespace", fmt.Sprint(index, strings.Repeat("x", 200)), until)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+	}
+	size := func(keybase *Keybase) int64 {
+		stats, err := keybase.Stats(ctx)
+		assert.NoError(t, err)
+		return stats.StorageSize
+	}
+
+	keybase, err := Open(ctx, WithStorage(filepath.Join(t.TempDir(), "keybase.db")))
+	assert.NoError(t, err)
+	fill(keybase, time.Now().Add(time.Minute))
+	assert.NoError(t, keybase.ClearEntries(ctx))
+	before := size(keybase)
+	assert.NoError(t, keybase.Compact(ctx))
+	assert.Less(t, size(keybase), before)
+	assert.Equal(t, int64(1), keybase.QueryStats()[OpVacuum].Queries)
+	assert.NoError(t, keybase.Close())
+	assert.ErrorIs(t, keybase.Compact(ctx), ErrClosed)
+
+	clock := &fixedClock{now: time.Now()}
+	keybase, err = Open(ctx, WithStorage(filepath.Join(t.TempDir(), "keybase.db")), WithClock(clock), WithAutoCompact(0.5),
+		WithPragmas(map[string]string{"auto_vacuum": "incremental"}))
+	assert.NoError(t, err)
+	defer keybase.Close()
+	fill(keybase, clock.now.Add(time.Minute))
+	assert.NoError(t, keybase.PruneEntries(ctx))
+	assert.Zero(t, keybase.QueryStats()[OpIncrementalVacuum].Queries)
+	before = size(keybase)
+	clock.now = clock.now.Add(time.Minute)
+	assert.NoError(t, keybase.PruneEntries(ctx))
+	assert.Equal(t, int64(1), keybase.QueryStats()[OpIncrementalVacuum].Queries)
+	assert.Less(t, size(keybase), before)
+
+	_, err = Open(ctx, WithAutoCompact(1.5))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+}